@@ -0,0 +1,200 @@
+package fmp4parser
+
+// AacFramer wraps the raw AAC frames, i.e. Packet.Data of an MPEG-4 audio track, in ADTS
+// headers or in LOAS/LATM, so the frames can be saved as ".aac" or muxed into MPEG-2 TS.
+type AacFramer struct {
+	// the core decoder. SBR and PS of HE-AAC are signalled implicitly in ADTS, so the
+	// object type and the sampling frequency of the core are used even if they are
+	// signalled explicitly in AudioSpecificConfig
+	ObjectType           int
+	SamplingFrequency    uint32
+	ChannelConfiguration uint8  // 0 if the channels are described by program_config_element()
+	MPEG2                bool   // MPEG-2 AAC, ID of ADTS is 1
+	AudioSpecificConfig  []byte // carried by StreamMuxConfig of LATM
+}
+
+// NewAacFramer returns the framer of the AAC track. The AudioSpecificConfig is used if
+// present, otherwise the object type, the sample rate and the channel count of esds.
+func NewAacFramer(esds *EsDescriptor) (*AacFramer, error) {
+	if esds == nil || esds.AudioCodec != AudioCodecAAC {
+		return nil, ErrInvalidParam
+	}
+	p := &AacFramer{MPEG2: esds.ObjectTypeIndication >= 0x66 && esds.ObjectTypeIndication <= 0x68}
+	if asc := esds.AudioSpecificConfig; asc != nil {
+		p.ObjectType = asc.AudioObjectType
+		p.SamplingFrequency = asc.SamplingFrequency
+		p.ChannelConfiguration = asc.ChannelConfiguration
+		p.AudioSpecificConfig = esds.DecoderSpecificInfo
+		return p, nil
+	}
+
+	// no DecoderSpecificInfo, e.g. MPEG-2 AAC whose profile is the object type indication
+	p.ObjectType = esds.AudioObjectType
+	p.SamplingFrequency = esds.SampleRate
+	if p.MPEG2 {
+		p.ObjectType = int(esds.ObjectTypeIndication) - 0x65
+	}
+	if p.ObjectType == aotSBR || p.ObjectType == aotPS {
+		p.ObjectType = aotAACLC
+		p.SamplingFrequency /= 2
+	}
+	if p.ObjectType == 0 {
+		p.ObjectType = aotAACLC
+	}
+	switch {
+	case esds.ChannelCount >= 1 && esds.ChannelCount <= 6:
+		p.ChannelConfiguration = uint8(esds.ChannelCount)
+	case esds.ChannelCount == 8:
+		p.ChannelConfiguration = 7
+	}
+	w := new(bitWriter)
+	if p.ObjectType >= 31 {
+		w.write(31, 5)
+		w.write(uint64(p.ObjectType-32), 6)
+	} else {
+		w.write(uint64(p.ObjectType), 5)
+	}
+	if index := samplingFrequencyIndex(p.SamplingFrequency); index < len(aacSamplingFrequencies) {
+		w.write(uint64(index), 4)
+	} else {
+		w.write(0x0F, 4)
+		w.write(uint64(p.SamplingFrequency), 24)
+	}
+	w.write(uint64(p.ChannelConfiguration), 4)
+	w.write(0, 3) // GASpecificConfig: frameLengthFlag, dependsOnCoreCoder, extensionFlag
+	p.AudioSpecificConfig = w.b
+	return p, nil
+}
+
+// AacFramer returns the framer of the sample description. ErrInvalidParam if it isn't AAC.
+func (p *SampleEntry) AacFramer() (*AacFramer, error) {
+	if p.audioEntry == nil {
+		return nil, ErrInvalidParam
+	}
+	esds, _ := p.audioEntry.decoderDescriptors[AudioCodecAAC].(*EsDescriptor)
+	return NewAacFramer(esds)
+}
+
+// samplingFrequencyIndex returns samplingFrequencyIndex of the frequency,
+// len(aacSamplingFrequencies) if the frequency isn't in the table.
+func samplingFrequencyIndex(frequency uint32) int {
+	for i, f := range aacSamplingFrequencies {
+		if f == frequency {
+			return i
+		}
+	}
+	return len(aacSamplingFrequencies)
+}
+
+// ADTS returns the frame with the ADTS header without CRC, refer to ISO/IEC 14496-3 1.A.2.
+// ErrUnsupportedFraming if ADTS can't describe the audio, i.e. the object type isn't one of
+// AAC Main, LC, SSR and LTP, the frequency isn't in the table or there is a program config.
+//
+//	syncword                          12 bits, 0xFFF
+//	ID                                1 bit
+//	layer                             2 bits
+//	protection_absent                 1 bit
+//	profile_ObjectType                2 bits
+//	sampling_frequency_index          4 bits
+//	private_bit                       1 bit
+//	channel_configuration             3 bits
+//	original_copy, home               2 bits
+//	copyright_identification_bit      1 bit
+//	copyright_identification_start    1 bit
+//	aac_frame_length                  13 bits
+//	adts_buffer_fullness              11 bits
+//	number_of_raw_data_blocks_in_frame 2 bits
+func (p *AacFramer) ADTS(frame []byte) ([]byte, error) {
+	index := samplingFrequencyIndex(p.SamplingFrequency)
+	if p.ObjectType < 1 || p.ObjectType > 4 || index == len(aacSamplingFrequencies) ||
+		p.ChannelConfiguration == 0 || p.ChannelConfiguration > 7 {
+		return nil, ErrUnsupportedFraming
+	}
+	const headerSize = 7
+	frameLength := headerSize + len(frame)
+	if frameLength >= 1<<13 {
+		return nil, ErrOutOfRange
+	}
+	w := &bitWriter{b: make([]byte, 0, frameLength)}
+	w.write(0xFFF, 12)
+	if p.MPEG2 {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+	w.write(0, 2)
+	w.write(1, 1) // no CRC
+	w.write(uint64(p.ObjectType-1), 2)
+	w.write(uint64(index), 4)
+	w.write(0, 1)
+	w.write(uint64(p.ChannelConfiguration), 3)
+	w.write(0, 4)
+	w.write(uint64(frameLength), 13)
+	w.write(0x7FF, 11) // variable bit rate
+	w.write(0, 2)
+	return append(w.b, frame...), nil
+}
+
+// LOAS returns the frame in AudioSyncStream() of LOAS, refer to ISO/IEC 14496-3 1.7.2.
+// StreamMuxConfig() carrying the AudioSpecificConfig as is, so including the explicit
+// signalling of SBR and PS, is written if muxConfig is true. It's required by the first frame
+// and should be repeated for the random access of the stream. audioMuxVersion 1 is used so
+// that the AudioSpecificConfig is carried with its length.
+func (p *AacFramer) LOAS(frame []byte, muxConfig bool) ([]byte, error) {
+	if len(p.AudioSpecificConfig) == 0 {
+		return nil, ErrUnsupportedFraming
+	}
+	w := &bitWriter{b: make([]byte, 0, len(frame)+len(p.AudioSpecificConfig)+16)}
+	w.write(0x2B7, 11)
+	w.write(0, 13) // audioMuxLengthBytes, set at last
+
+	// AudioMuxElement(1)
+	if muxConfig {
+		w.write(0, 1) // useSameStreamMux
+		p.writeStreamMuxConfig(w)
+	} else {
+		w.write(1, 1)
+	}
+	// PayloadLengthInfo() of frameLengthType 0
+	size := len(frame)
+	for ; size >= 255; size -= 255 {
+		w.write(255, 8)
+	}
+	w.write(uint64(size), 8)
+	w.writeBytes(frame) // PayloadMux()
+
+	length := len(w.b) - 3
+	if length >= 1<<13 {
+		return nil, ErrOutOfRange
+	}
+	w.b[1] |= byte(length >> 8)
+	w.b[2] = byte(length)
+	return w.b, nil
+}
+
+// writeStreamMuxConfig writes StreamMuxConfig() of audioMuxVersion 1, one program of one layer.
+func (p *AacFramer) writeStreamMuxConfig(w *bitWriter) {
+	w.write(1, 1)           // audioMuxVersion
+	w.write(0, 1)           // audioMuxVersionA
+	writeLatmValue(w, 0xFF) // taraBufferFullness
+	w.write(1, 1)           // allStreamsSameTimeFraming
+	w.write(0, 6)           // numSubFrames
+	w.write(0, 4)           // numProgram
+	w.write(0, 3)           // numLayer
+	writeLatmValue(w, uint32(len(p.AudioSpecificConfig)*8))
+	w.writeBytes(p.AudioSpecificConfig)
+	w.write(0, 3)    // frameLengthType
+	w.write(0xFF, 8) // latmBufferFullness
+	w.write(0, 1)    // otherDataPresent
+	w.write(0, 1)    // crcCheckPresent
+}
+
+// writeLatmValue writes LatmGetValue().
+func writeLatmValue(w *bitWriter, v uint32) {
+	bytesForValue := 0
+	for v>>(8*uint(bytesForValue+1)) != 0 {
+		bytesForValue++
+	}
+	w.write(uint64(bytesForValue), 2)
+	w.write(uint64(v), 8*(bytesForValue+1))
+}
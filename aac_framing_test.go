@@ -0,0 +1,72 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAacFramer_ADTS(t *testing.T) {
+	// HE-AAC of explicit signalling, 24000 Hz core and 48000 Hz SBR
+	dsi := new(bitWriter).write(5, 5).write(6, 4).write(2, 4).write(3, 4).write(2, 5).write(0, 3).align().b
+	asc, err := parseAudioSpecificConfig(dsi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAacFramer(&EsDescriptor{AudioCodec: AudioCodecAAC, ObjectTypeIndication: 0x40, AudioSpecificConfig: asc, DecoderSpecificInfo: dsi})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.ADTS([]byte{0xAA, 0xBB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AAC LC, 24000 Hz, 2 channels, 9 bytes
+	if want := []byte{0xFF, 0xF1, 0x58, 0x80, 0x01, 0x3F, 0xFC, 0xAA, 0xBB}; !bytes.Equal(got, want) {
+		t.Errorf("ADTS() = % X, want % X", got, want)
+	}
+
+	// MPEG-2 AAC LC without DecoderSpecificInfo
+	p, err = NewAacFramer(&EsDescriptor{AudioCodec: AudioCodecAAC, ObjectTypeIndication: 0x67, SampleRate: 44100, ChannelCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = p.ADTS(nil); !bytes.Equal(got[:4], []byte{0xFF, 0xF9, 0x50, 0x40}) {
+		t.Errorf("ADTS() = % X", got)
+	}
+	if want := []byte{0x12, 0x08}; !bytes.Equal(p.AudioSpecificConfig, want) {
+		t.Errorf("AudioSpecificConfig = % X, want % X", p.AudioSpecificConfig, want)
+	}
+
+	p.ChannelConfiguration = 0
+	if _, err = p.ADTS(nil); err != ErrUnsupportedFraming {
+		t.Errorf("got error %v, want ErrUnsupportedFraming", err)
+	}
+}
+
+func TestAacFramer_LOAS(t *testing.T) {
+	p := &AacFramer{ObjectType: aotAACLC, SamplingFrequency: 48000, ChannelConfiguration: 2, AudioSpecificConfig: []byte{0x11, 0x90}}
+	got, err := p.LOAS([]byte{0xAA, 0xBB}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x56, 0xE0, 0x04, 0x81, 0x55, 0x5D, 0x80}; !bytes.Equal(got, want) {
+		t.Errorf("LOAS() = % X, want % X", got, want)
+	}
+
+	got, err = p.LOAS(bytes.Repeat([]byte{0xAA}, 300), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := new(bitWriter).write(0x2B7, 11).write(uint64(len(got)-3), 13).
+		write(0, 1).write(1, 1).write(0, 1).write(0, 2).write(0xFF, 8). // audioMuxVersion 1, taraBufferFullness
+		write(1, 1).write(0, 6).write(0, 4).write(0, 3).
+		write(0, 2).write(16, 8).write(0x1190, 16). // ascLen and AudioSpecificConfig
+		write(0, 3).write(0xFF, 8).write(0, 1).write(0, 1).
+		write(255, 8).write(45, 8)
+	for i := 0; i < 300; i++ {
+		want.write(0xAA, 8)
+	}
+	if !bytes.Equal(got, want.align().b) {
+		t.Errorf("LOAS() = % X, want % X", got, want.b)
+	}
+}
@@ -0,0 +1,253 @@
+package fmp4parser
+
+import "bytes"
+
+// nal unit types of the pictures and the access unit delimiters
+const (
+	avcNalIDR = 5
+	avcNalAUD = 9
+
+	hevcNalBLAWLP  = 16 // the first IRAP picture
+	hevcNalRSVIRAP = 23 // the last IRAP picture (reserved)
+	hevcNalAUD     = 35
+)
+
+var startCode = []byte{0, 0, 0, 1}
+
+// NalConverter converts the samples of an AVC, HEVC or VVC sample description between the
+// length-prefixed form of ISO/IEC 14496-15 and the start code form of ITU-T H.264 Annex B.
+type NalConverter struct {
+	Codec         CodecType // VideoCodecH264, VideoCodecHEVC or VideoCodecVVC
+	LengthSize    int       // size of the NALUnitLength field of the samples: 1, 2 or 4
+	ParameterSets [][]byte  // VPS, SPS and PPS (and OPI, DCI and APS of VVC) of the decoder configuration record
+	InBand        bool      // parameter sets may be in the samples, i.e. "avc3", "avc4", "hev1", "vvi1", "dvav" or "dvhe"
+}
+
+// NewNalConverter returns the converter of the decoder configuration record, *AvcConfig,
+// *HevcConfig or *VvcConfig. inBand tells whether the sample entry allows parameter sets in
+// the samples.
+func NewNalConverter(config interface{}, inBand bool) (*NalConverter, error) {
+	switch c := config.(type) {
+	case *AvcConfig:
+		p := &NalConverter{Codec: VideoCodecH264, LengthSize: int(c.LengthSize), InBand: inBand}
+		p.ParameterSets = append(append(p.ParameterSets, c.ListSPS...), c.ListPPS...)
+		return p, nil
+	case *HevcConfig:
+		p := &NalConverter{Codec: VideoCodecHEVC, LengthSize: int(c.LengthSizeMinusOne) + 1, InBand: inBand}
+		// VPS, SPS and PPS must be in this order, whatever the order of the arrays is
+		for _, nalType := range []uint8{hevcNalVPS, hevcNalSPS, hevcNalPPS} {
+			for _, array := range c.NalUnitArrays {
+				if array.NALUnitType == nalType {
+					p.ParameterSets = append(p.ParameterSets, array.NalUnit...)
+				}
+			}
+		}
+		return p, nil
+	case *VvcConfig:
+		p := &NalConverter{Codec: VideoCodecVVC, LengthSize: int(c.LengthSizeMinusOne) + 1, InBand: inBand}
+		for _, nalType := range []uint8{vvcNalOPI, vvcNalDCI, vvcNalVPS, vvcNalSPS, vvcNalPPS, vvcNalPrefixAPS} {
+			for _, array := range c.NalUnitArrays {
+				if array.NALUnitType == nalType {
+					p.ParameterSets = append(p.ParameterSets, array.NalUnit...)
+				}
+			}
+		}
+		return p, nil
+	}
+	return nil, ErrInvalidParam
+}
+
+// nalCodec returns the codec of the NAL units of the samples, i.e. the codec of the base
+// layer of Dolby Vision.
+func (p *videoSampleEntry) nalCodec() CodecType {
+	if p.codec != VideoCodecDolbyVision {
+		return p.codec
+	}
+	for _, codec := range []CodecType{VideoCodecHEVC, VideoCodecH264} {
+		if _, ok := p.decoderConfigurationRecords[codec]; ok {
+			return codec
+		}
+	}
+	return p.codec
+}
+
+// NalConverter returns the converter of the sample description, the converter of the base
+// layer for Dolby Vision. ErrInvalidParam if it's none of AVC, HEVC and VVC.
+func (p *SampleEntry) NalConverter() (*NalConverter, error) {
+	if p.videoEntry == nil {
+		return nil, ErrInvalidParam
+	}
+	inBand := false
+	switch p.codingName {
+	case avc3SampleEntry, avc4SampleEntry, hev1SampleEntry, vvi1SampleEntry, dvavSampleEntry, dvheSampleEntry:
+		inBand = true
+	}
+	return NewNalConverter(p.videoEntry.decoderConfigurationRecords[p.videoEntry.nalCodec()], inBand)
+}
+
+// nalType returns nal_unit_type of the NAL unit.
+func (p *NalConverter) nalType(nal []byte) uint8 {
+	if len(nal) == 0 {
+		return 0xFF
+	}
+	switch p.Codec {
+	case VideoCodecHEVC:
+		return nal[0] >> 1 & 0x3F
+	case VideoCodecVVC:
+		if len(nal) < 2 {
+			return 0xFF
+		}
+		return nal[1] >> 3
+	}
+	return nal[0] & 0x1F
+}
+
+func (p *NalConverter) isParameterSet(nalType uint8) bool {
+	switch p.Codec {
+	case VideoCodecHEVC:
+		return nalType >= hevcNalVPS && nalType <= hevcNalPPS
+	case VideoCodecVVC:
+		return nalType >= vvcNalOPI && nalType <= vvcNalPrefixAPS
+	}
+	return nalType == avcNalSPS || nalType == avcNalPPS
+}
+
+func (p *NalConverter) isRandomAccess(nalType uint8) bool {
+	switch p.Codec {
+	case VideoCodecHEVC:
+		return nalType >= hevcNalBLAWLP && nalType <= hevcNalRSVIRAP
+	case VideoCodecVVC:
+		return nalType >= vvcNalIDRWRADL && nalType <= vvcNalGDR
+	}
+	return nalType == avcNalIDR
+}
+
+// isLeadingNonVCL returns whether the NAL unit must precede the parameter sets inserted, i.e.
+// the access unit delimiter.
+func (p *NalConverter) isLeadingNonVCL(nalType uint8) bool {
+	switch p.Codec {
+	case VideoCodecHEVC:
+		return nalType == hevcNalAUD
+	case VideoCodecVVC:
+		return nalType == vvcNalAUD
+	}
+	return nalType == avcNalAUD
+}
+
+// SplitLengthPrefixed splits the sample into NAL units by the NALUnitLength field of
+// lengthSize bytes. The NAL units refer to sample.
+func SplitLengthPrefixed(sample []byte, lengthSize int) ([][]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, ErrInvalidParam
+	}
+	var nals [][]byte
+	for len(sample) > 0 {
+		if len(sample) < lengthSize {
+			return nil, ErrNoEnoughData
+		}
+		size := 0
+		for _, b := range sample[:lengthSize] {
+			size = size<<8 | int(b)
+		}
+		sample = sample[lengthSize:]
+		if size > len(sample) {
+			return nil, ErrNoEnoughData
+		}
+		nals = append(nals, sample[:size])
+		sample = sample[size:]
+	}
+	return nals, nil
+}
+
+// SplitAnnexB splits the byte stream of Annex B into NAL units by the start codes. The
+// trailing zero bytes of the NAL units are dropped. The NAL units refer to data.
+func SplitAnnexB(data []byte) [][]byte {
+	var nals [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			i++
+			continue
+		}
+		if start >= 0 {
+			nals = appendNal(nals, data[start:i])
+		}
+		i += 3
+		start = i
+	}
+	if start >= 0 {
+		nals = appendNal(nals, data[start:])
+	}
+	return nals
+}
+
+func appendNal(nals [][]byte, nal []byte) [][]byte {
+	nal = bytes.TrimRight(nal, "\x00")
+	if len(nal) == 0 {
+		return nals
+	}
+	return append(nals, nal)
+}
+
+// ToAnnexB converts the length-prefixed sample to Annex B with 4-byte start codes. The
+// parameter sets of the decoder configuration record are inserted before the first VCL NAL
+// unit of the IDR/IRAP samples unless the sample carries parameter sets itself, which is
+// the case of the in-band sample entries.
+func (p *NalConverter) ToAnnexB(sample []byte) ([]byte, error) {
+	nals, err := SplitLengthPrefixed(sample, p.LengthSize)
+	if err != nil {
+		return nil, err
+	}
+	randomAccess, hasParameterSets := false, false
+	for _, nal := range nals {
+		nalType := p.nalType(nal)
+		randomAccess = randomAccess || p.isRandomAccess(nalType)
+		hasParameterSets = hasParameterSets || p.isParameterSet(nalType)
+	}
+	insert := randomAccess && !hasParameterSets
+
+	out := make([]byte, 0, len(sample)+len(nals)*(len(startCode)-p.LengthSize)+len(p.ParameterSets)*8)
+	for _, nal := range nals {
+		if insert && !p.isLeadingNonVCL(p.nalType(nal)) {
+			for _, ps := range p.ParameterSets {
+				out = append(append(out, startCode...), ps...)
+			}
+			insert = false
+		}
+		out = append(append(out, startCode...), nal...)
+	}
+	return out, nil
+}
+
+// ToLengthPrefixed converts the Annex B access unit to a length-prefixed sample for muxing.
+// The parameter sets that are the same as the ones of the decoder configuration record are
+// dropped unless the sample entry is in-band.
+func (p *NalConverter) ToLengthPrefixed(data []byte) ([]byte, error) {
+	if p.LengthSize != 1 && p.LengthSize != 2 && p.LengthSize != 4 {
+		return nil, ErrInvalidParam
+	}
+	maxSize := uint64(1)<<(8*uint(p.LengthSize)) - 1
+	out := make([]byte, 0, len(data))
+	for _, nal := range SplitAnnexB(data) {
+		if !p.InBand && p.isParameterSet(p.nalType(nal)) && p.hasParameterSet(nal) {
+			continue
+		}
+		if uint64(len(nal)) > maxSize {
+			return nil, ErrOutOfRange
+		}
+		for i := p.LengthSize - 1; i >= 0; i-- {
+			out = append(out, byte(len(nal)>>(8*uint(i))))
+		}
+		out = append(out, nal...)
+	}
+	return out, nil
+}
+
+func (p *NalConverter) hasParameterSet(nal []byte) bool {
+	for _, ps := range p.ParameterSets {
+		if bytes.Equal(ps, nal) {
+			return true
+		}
+	}
+	return false
+}
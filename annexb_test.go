@@ -0,0 +1,102 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNalConverter_AVC(t *testing.T) {
+	sps, pps := []byte{0x67, 0x64, 0x00, 0x1F}, []byte{0x68, 0xEE}
+	p, err := NewNalConverter(&AvcConfig{LengthSize: 4, ListSPS: [][]byte{sps}, ListPPS: [][]byte{pps}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aud, idr := []byte{0x09, 0xF0}, []byte{0x65, 0x88, 0x84}
+	sample := append(append(u32(2), aud...), append(u32(3), idr...)...)
+	got, err := p.ToAnnexB(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the parameter sets follow the access unit delimiter
+	want := bytes.Join([][]byte{nil, aud, sps, pps, idr}, startCode)
+	if !bytes.Equal(got, want) {
+		t.Errorf("ToAnnexB() = % X, want % X", got, want)
+	}
+	back, err := p.ToLengthPrefixed(append(got, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back, sample) {
+		t.Errorf("ToLengthPrefixed() = % X, want % X", back, sample)
+	}
+
+	// non-IDR samples are kept
+	slice := append(u32(2), 0x41, 0x9A)
+	if got, _ := p.ToAnnexB(slice); !bytes.Equal(got, []byte{0, 0, 0, 1, 0x41, 0x9A}) {
+		t.Errorf("ToAnnexB() = % X", got)
+	}
+	if _, err := p.ToAnnexB(append(u32(5), 0x65)); err != ErrNoEnoughData {
+		t.Errorf("got error %v, want ErrNoEnoughData", err)
+	}
+}
+
+func TestNalConverter_HEVCInBand(t *testing.T) {
+	vps, sps, pps := []byte{0x40, 0x01}, []byte{0x42, 0x01}, []byte{0x44, 0x01}
+	config := &HevcConfig{LengthSizeMinusOne: 1, NalUnitArrays: []NalUnitInfo{
+		{NALUnitType: hevcNalPPS, NalUnit: [][]byte{pps}},
+		{NALUnitType: hevcNalSPS, NalUnit: [][]byte{sps}},
+		{NALUnitType: hevcNalVPS, NalUnit: [][]byte{vps}},
+	}}
+	p, err := NewNalConverter(config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cra := []byte{0x2A, 0x01, 0xAF}
+	got, err := p.ToAnnexB(append(u16(3), cra...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Join([][]byte{nil, vps, sps, pps, cra}, startCode); !bytes.Equal(got, want) {
+		t.Errorf("ToAnnexB() = % X, want % X", got, want)
+	}
+
+	// the parameter sets in the sample are used as is
+	inBand := []byte{0x42, 0x01, 0x02}
+	sample := append(append(u16(3), inBand...), append(u16(3), cra...)...)
+	if got, _ = p.ToAnnexB(sample); !bytes.Equal(got, bytes.Join([][]byte{nil, inBand, cra}, startCode)) {
+		t.Errorf("ToAnnexB() = % X", got)
+	}
+	// and kept when muxing to an in-band sample entry, 3-byte start codes are accepted
+	back, err := p.ToLengthPrefixed(append(append([]byte{0, 0, 1}, vps...), append([]byte{0, 0, 1}, cra...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append(u16(2), vps...), append(u16(3), cra...)...); !bytes.Equal(back, want) {
+		t.Errorf("ToLengthPrefixed() = % X, want % X", back, want)
+	}
+}
+
+func TestSampleEntry_NalConverterDolbyVision(t *testing.T) {
+	vps := []byte{0x40, 0x01}
+	hvcC := mkBox("hvcC", []byte{1, 0x02}, u32(0x20000000), make([]byte, 6), []byte{120, 0xF0, 0, 0xFC, 0xFD, 0xFA, 0xFA},
+		u16(0), []byte{0x0F, 1}, []byte{hevcNalVPS}, u16(1), u16(uint16(len(vps))), vps)
+	dvvC := mkBox("dvvC", []byte{1, 0, 8<<1 | 0, 6<<3 | 0x05, 4 << 4}, make([]byte, 19))
+	p := newTestParser(t, mkVideoInit(mkVideoEntry("dvh1", 3840, 2160, hvcC, dvvC), mkVideoEntry("dvhe", 3840, 2160, hvcC, dvvC)))
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "h265" {
+		t.Errorf("ElementaryStreamFormat() = %q, want h265", format)
+	}
+	entries := p.GetTracks()[0].SampleEntries
+	if len(entries) != 2 || entries[0].Codec != VideoCodecDolbyVision {
+		t.Fatalf("unexpected sample entries %+v", entries)
+	}
+	for i, inBand := range []bool{false, true} {
+		c, err := entries[i].NalConverter()
+		if err != nil {
+			t.Fatalf("%s: %v", entries[i].Format, err)
+		}
+		if c.Codec != VideoCodecHEVC || c.LengthSize != 4 || c.InBand != inBand || len(c.ParameterSets) != 1 ||
+			!bytes.Equal(c.ParameterSets[0], vps) {
+			t.Errorf("%s: NalConverter() = %+v", entries[i].Format, c)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"bytes"
@@ -175,7 +175,8 @@ func (p *atomReader) FindSubAtom(atomType uint32) (ar *atomReader, err error) {
 }
 
 func (p *atomReader) GetSubAtom() (*atomReader, error) {
-	if p.r.Len() == 0 {
+	// less than an atom header, e.g. the 32-bit zero terminator of the QuickTime atom list
+	if p.r.Len() < 8 {
 		return nil, ErrNoMoreAtom
 	}
 	start, _ := p.r.Seek(0, io.SeekCurrent)
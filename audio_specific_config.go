@@ -0,0 +1,595 @@
+package fmp4parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+AudioSpecificConfig refer to ISO/IEC 14496-3:2019 1.6.2.1
+
+	AudioSpecificConfig() {
+	    audioObjectType = GetAudioObjectType();
+	    samplingFrequencyIndex;                     4 bits
+	    if (samplingFrequencyIndex == 0xf)
+	        samplingFrequency;                      24 bits
+	    channelConfiguration;                       4 bits
+	    sbrPresentFlag = -1;
+	    psPresentFlag = -1;
+	    if (audioObjectType == 5 || audioObjectType == 29) {
+	        extensionAudioObjectType = 5;
+	        ...
+	    }
+	    switch (audioObjectType) { ... GASpecificConfig(), ELDSpecificConfig(), UsacConfig(), ALSSpecificConfig() ... }
+	    ...
+	    if (extensionAudioObjectType != 5 && bits_to_decode() >= 16) {
+	        syncExtensionType;                      11 bits, 0x2b7
+	        ...
+	    }
+	}
+*/
+
+// AudioSpecificConfig is the decoder specific information of MPEG-4 audio.
+type AudioSpecificConfig struct {
+	// the first audio object type in the config. It's 5 (SBR) or 29 (PS) if
+	// HE-AAC is signalled explicitly (hierarchical signalling)
+	SignalledObjectType        int
+	AudioObjectType            int    // object type of the core decoder, e.g. 2 (AAC LC), 39 (ER AAC ELD), 42 (USAC)
+	ExtensionAudioObjectType   int    // 5 (SBR) or 22 (BSAC) if an extension is signalled, otherwise 0
+	SBRPresent                 bool   // SBR is signalled, explicitly or backward compatibly
+	PSPresent                  bool   // parametric stereo is signalled
+	SamplingFrequency          uint32 // sampling frequency of the core decoder
+	ExtensionSamplingFrequency uint32 // sampling frequency of SBR, 0 if there is no SBR
+	ChannelConfiguration       uint8  // 0 means the channels are described by ProgramConfig (or UsacChannelConfig)
+	FrameLength                int    // samples per frame of the core decoder
+	BitsPerSample              uint8  // for ALS only
+
+	ProgramConfig *ProgramConfigElement // if ChannelConfiguration == 0 for GA object types
+
+	// the output of the decoder
+	SampleRate    uint32
+	ChannelCount  uint16
+	ChannelLayout ChannelLayout
+}
+
+// ProgramConfigElement is the program_config_element() of AAC, refer to ISO/IEC 14496-3 4.4.1.1.
+// It describes the channel layout if channelConfiguration is 0.
+type ProgramConfigElement struct {
+	ElementInstanceTag     uint8
+	ObjectType             uint8
+	SamplingFrequencyIndex uint8
+	FrontElements          []ChannelElement
+	SideElements           []ChannelElement
+	BackElements           []ChannelElement
+	LFEElements            []uint8 // element tags of the LFE channels
+	AssocDataElements      []uint8
+	ValidCCElements        []uint8
+	MonoMixdownElement     *uint8
+	StereoMixdownElement   *uint8
+	MatrixMixdownIdx       *uint8
+	PseudoSurroundEnable   bool
+	Comment                []byte
+}
+
+// ChannelElement is a single channel element or a channel pair element of program_config_element().
+type ChannelElement struct {
+	IsCPE     bool // channel pair element, i.e. 2 channels
+	TagSelect uint8
+}
+
+// ChannelLayout is the number of channels at each position.
+type ChannelLayout struct {
+	Front  uint8
+	Side   uint8
+	Back   uint8
+	LFE    uint8
+	Top    uint8 // height channels
+	Bottom uint8
+}
+
+// Channels returns the number of all channels.
+func (p ChannelLayout) Channels() uint16 {
+	return uint16(p.Front) + uint16(p.Side) + uint16(p.Back) + uint16(p.LFE) + uint16(p.Top) + uint16(p.Bottom)
+}
+
+// String returns the layout in the form of "front/side/back.lfe", the height channels are appended as ".top".
+func (p ChannelLayout) String() string {
+	s := fmt.Sprintf("%d/%d/%d.%d", p.Front, p.Side, p.Back, p.LFE)
+	if p.Top != 0 || p.Bottom != 0 {
+		s += fmt.Sprintf(".%d", p.Top)
+	}
+	if p.Bottom != 0 {
+		s += fmt.Sprintf(".%d", p.Bottom)
+	}
+	return s
+}
+
+// channel layouts of ChannelConfiguration defined in ISO/IEC 23001-8 (CICP), the value
+// 1~7 and 11~14 are the same as the channelConfiguration of ISO/IEC 14496-3.
+var cicpChannelLayouts = map[uint8]ChannelLayout{
+	1:  {Front: 1},
+	2:  {Front: 2},
+	3:  {Front: 3},
+	4:  {Front: 3, Back: 1},
+	5:  {Front: 3, Back: 2},
+	6:  {Front: 3, Back: 2, LFE: 1},
+	7:  {Front: 5, Back: 2, LFE: 1},
+	8:  {Front: 2}, // 1+1, two independent mono channels
+	9:  {Front: 2, Back: 1},
+	10: {Front: 2, Back: 2},
+	11: {Front: 3, Side: 2, Back: 1, LFE: 1},
+	12: {Front: 3, Side: 2, Back: 2, LFE: 1},
+	13: {Front: 5, Side: 2, Back: 3, LFE: 2, Top: 9, Bottom: 3},
+	14: {Front: 3, Back: 2, LFE: 1, Top: 2},
+	15: {Front: 3, Side: 2, Back: 2, LFE: 2, Top: 3},
+	16: {Front: 3, Back: 2, LFE: 1, Top: 4},
+	17: {Front: 3, Back: 2, LFE: 1, Top: 6},
+	18: {Front: 3, Side: 2, Back: 2, LFE: 1, Top: 6},
+	19: {Front: 3, Side: 2, Back: 2, LFE: 1, Top: 4},
+	20: {Front: 5, Side: 2, Back: 2, LFE: 1, Top: 4},
+}
+
+// sampling frequencies of samplingFrequencyIndex, ISO/IEC 14496-3 Table 1.18
+var aacSamplingFrequencies = [13]uint32{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// sampling frequencies of usacSamplingFrequencyIndex, ISO/IEC 23003-3 Table 72. 0 is reserved
+var usacSamplingFrequencies = [31]uint32{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350,
+	0, 0, 57600, 51200, 40000, 38400, 34150, 28800, 25600, 20000, 19200, 17075, 14400, 12800, 9600}
+
+const (
+	aotAACLC    = 2
+	aotSBR      = 5
+	aotAACLD    = 23
+	aotPS       = 29
+	aotERBSAC   = 22
+	aotALS      = 36
+	aotERAACELD = 39
+	aotUSAC     = 42
+
+	syncExtensionTypeSBR = 0x2b7
+	syncExtensionTypePS  = 0x548
+)
+
+var errInvalidAudioSpecificConfig = errors.New("invalid AudioSpecificConfig")
+
+// ascReader is a bit reader which knows the number of the remaining bits.
+type ascReader struct {
+	br   bitReader
+	pos  int // in bits
+	size int // in bits
+}
+
+func (r *ascReader) read(bits uint) uint32 {
+	r.pos += int(bits)
+	return r.br.ReadBitsLE32(bits)
+}
+
+func (r *ascReader) readBool() bool {
+	return r.read(1) != 0
+}
+
+func (r *ascReader) left() int {
+	return r.size - r.pos
+}
+
+func (r *ascReader) skip(bits int) {
+	for ; bits > 32; bits -= 32 {
+		_ = r.read(32)
+	}
+	_ = r.read(uint(bits))
+}
+
+func (r *ascReader) byteAlign() {
+	if n := r.pos % 8; n != 0 {
+		_ = r.read(uint(8 - n))
+	}
+}
+
+func (r *ascReader) audioObjectType() int {
+	audioObjectType := int(r.read(5))
+	if audioObjectType == 31 {
+		audioObjectType = 32 + int(r.read(6))
+	}
+	return audioObjectType
+}
+
+func (r *ascReader) samplingFrequency() uint32 {
+	index := r.read(4)
+	if index == 0x0F {
+		return r.read(24)
+	}
+	if index < uint32(len(aacSamplingFrequencies)) {
+		return aacSamplingFrequencies[index]
+	}
+	return 0
+}
+
+// escapedValue refer to ISO/IEC 23003-3 Table 16.
+func (r *ascReader) escapedValue(nBits1, nBits2, nBits3 uint) uint32 {
+	value := r.read(nBits1)
+	if value == 1<<nBits1-1 {
+		valueAdd := r.read(nBits2)
+		value += valueAdd
+		if valueAdd == 1<<nBits2-1 {
+			value += r.read(nBits3)
+		}
+	}
+	return value
+}
+
+// parseAudioSpecificConfig parses the AudioSpecificConfig and computes the output
+// sample rate, the number of channels and the channel layout.
+func parseAudioSpecificConfig(data []byte) (*AudioSpecificConfig, error) {
+	if len(data) < 2 {
+		return nil, errInvalidAudioSpecificConfig
+	}
+	r := &ascReader{br: newBitReaderFromSlice(data), size: len(data) * 8}
+	p := new(AudioSpecificConfig)
+	p.AudioObjectType = r.audioObjectType()
+	p.SignalledObjectType = p.AudioObjectType
+	p.SamplingFrequency = r.samplingFrequency()
+	p.ChannelConfiguration = uint8(r.read(4))
+	extensionChannelConfiguration := p.ChannelConfiguration
+	if p.AudioObjectType == aotSBR || p.AudioObjectType == aotPS {
+		p.ExtensionAudioObjectType = aotSBR
+		p.SBRPresent = true
+		p.PSPresent = p.AudioObjectType == aotPS
+		p.ExtensionSamplingFrequency = r.samplingFrequency()
+		p.AudioObjectType = r.audioObjectType()
+		if p.AudioObjectType == aotERBSAC {
+			extensionChannelConfiguration = uint8(r.read(4))
+		}
+	}
+
+	var err error
+	switch p.AudioObjectType {
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23:
+		err = p.parseGASpecificConfig(r)
+	case aotERAACELD:
+		err = p.parseELDSpecificConfig(r)
+	case aotUSAC:
+		err = p.parseUsacConfig(r)
+	case aotALS:
+		r.byteAlign() // fillBits
+		err = p.parseALSSpecificConfig(r)
+	case 32, 33, 34: // MPEG-1/2 Layer 1/2/3
+		_ = r.read(1) // extension
+	default:
+		// CELP, HVXC, TTSI, structured audio, SSC, DST, SLS ... only the common fields are used
+		logD.Printf("AudioSpecificConfig of audio object type %d isn't parsed", p.AudioObjectType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch p.AudioObjectType {
+	case 17, 19, 20, 21, 22, 23, 24, 25, 26, 27, aotERAACELD:
+		epConfig := r.read(2)
+		if epConfig == 2 || epConfig == 3 {
+			// ErrorProtectionSpecificConfig isn't parsed, so the backward compatible extension can't be found
+			r.pos = r.size
+		}
+	}
+
+	// backward compatible signalling of SBR and PS
+	if p.ExtensionAudioObjectType != aotSBR && p.AudioObjectType != aotUSAC && p.AudioObjectType != aotALS && r.left() >= 16 {
+		if r.read(11) == syncExtensionTypeSBR {
+			extensionAudioObjectType := r.audioObjectType()
+			if extensionAudioObjectType == aotSBR {
+				p.SBRPresent = r.readBool()
+				if p.SBRPresent {
+					p.ExtensionAudioObjectType = aotSBR
+					p.ExtensionSamplingFrequency = r.samplingFrequency()
+					if r.left() >= 12 && r.read(11) == syncExtensionTypePS {
+						p.PSPresent = r.readBool()
+					}
+				}
+			} else if extensionAudioObjectType == aotERBSAC {
+				p.SBRPresent = r.readBool()
+				p.ExtensionAudioObjectType = aotERBSAC
+				if p.SBRPresent {
+					p.ExtensionSamplingFrequency = r.samplingFrequency()
+				}
+				extensionChannelConfiguration = uint8(r.read(4))
+			}
+		}
+	}
+	if r.br.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidAudioSpecificConfig, r.br.Err())
+	}
+	p.computeOutput(extensionChannelConfiguration)
+	return p, nil
+}
+
+// computeOutput sets the output sample rate and channels.
+func (p *AudioSpecificConfig) computeOutput(extensionChannelConfiguration uint8) {
+	if p.SampleRate == 0 {
+		p.SampleRate = p.SamplingFrequency
+		if p.SBRPresent && p.ExtensionSamplingFrequency != 0 {
+			p.SampleRate = p.ExtensionSamplingFrequency
+		}
+	}
+	if p.ChannelCount != 0 {
+		return
+	}
+	channelConfiguration := p.ChannelConfiguration
+	if channelConfiguration == 0 && extensionChannelConfiguration != 0 {
+		channelConfiguration = extensionChannelConfiguration
+	}
+	if p.ProgramConfig != nil && channelConfiguration == 0 {
+		p.ChannelLayout = p.ProgramConfig.channelLayout()
+	} else if layout, ok := cicpChannelLayouts[channelConfiguration]; ok && channelConfiguration <= 14 {
+		p.ChannelLayout = layout
+	}
+	// parametric stereo outputs 2 channels from 1 channel
+	if p.PSPresent && p.ChannelLayout == (ChannelLayout{Front: 1}) {
+		p.ChannelLayout = ChannelLayout{Front: 2}
+	}
+	p.ChannelCount = p.ChannelLayout.Channels()
+}
+
+// GASpecificConfig refer to ISO/IEC 14496-3 4.4.1
+func (p *AudioSpecificConfig) parseGASpecificConfig(r *ascReader) error {
+	p.FrameLength = 1024
+	if r.readBool() { // frameLengthFlag
+		p.FrameLength = 960
+	}
+	if p.AudioObjectType == aotAACLD {
+		p.FrameLength = p.FrameLength / 2 // 512 or 480
+	}
+	if r.readBool() { // dependsOnCoreCoder
+		_ = r.read(14) // coreCoderDelay
+	}
+	extensionFlag := r.readBool()
+	if p.ChannelConfiguration == 0 {
+		pce, err := parseProgramConfigElement(r)
+		if err != nil {
+			return err
+		}
+		p.ProgramConfig = pce
+	}
+	if p.AudioObjectType == 6 || p.AudioObjectType == 20 {
+		_ = r.read(3) // layerNr
+	}
+	if extensionFlag {
+		if p.AudioObjectType == aotERBSAC {
+			_ = r.read(5)  // numOfSubFrame
+			_ = r.read(11) // layer_length
+		}
+		if p.AudioObjectType == 17 || p.AudioObjectType == 19 || p.AudioObjectType == 20 || p.AudioObjectType == 23 {
+			_ = r.read(3) // aacSectionDataResilienceFlag, aacScalefactorDataResilienceFlag, aacSpectralDataResilienceFlag
+		}
+		_ = r.read(1) // extensionFlag3
+	}
+	return nil
+}
+
+// program_config_element refer to ISO/IEC 14496-3 4.4.1.1
+func parseProgramConfigElement(r *ascReader) (*ProgramConfigElement, error) {
+	p := new(ProgramConfigElement)
+	p.ElementInstanceTag = uint8(r.read(4))
+	p.ObjectType = uint8(r.read(2))
+	p.SamplingFrequencyIndex = uint8(r.read(4))
+	numFront := int(r.read(4))
+	numSide := int(r.read(4))
+	numBack := int(r.read(4))
+	numLFE := int(r.read(2))
+	numAssocData := int(r.read(3))
+	numValidCC := int(r.read(4))
+	if r.readBool() {
+		v := uint8(r.read(4))
+		p.MonoMixdownElement = &v
+	}
+	if r.readBool() {
+		v := uint8(r.read(4))
+		p.StereoMixdownElement = &v
+	}
+	if r.readBool() {
+		v := uint8(r.read(2))
+		p.MatrixMixdownIdx = &v
+		p.PseudoSurroundEnable = r.readBool()
+	}
+	readElements := func(n int) []ChannelElement {
+		elements := make([]ChannelElement, n)
+		for i := range elements {
+			elements[i].IsCPE = r.readBool()
+			elements[i].TagSelect = uint8(r.read(4))
+		}
+		return elements
+	}
+	p.FrontElements = readElements(numFront)
+	p.SideElements = readElements(numSide)
+	p.BackElements = readElements(numBack)
+	for i := 0; i < numLFE; i++ {
+		p.LFEElements = append(p.LFEElements, uint8(r.read(4)))
+	}
+	for i := 0; i < numAssocData; i++ {
+		p.AssocDataElements = append(p.AssocDataElements, uint8(r.read(4)))
+	}
+	for i := 0; i < numValidCC; i++ {
+		_ = r.read(1) // cc_element_is_ind_sw
+		p.ValidCCElements = append(p.ValidCCElements, uint8(r.read(4)))
+	}
+	r.byteAlign()
+	commentBytes := int(r.read(8))
+	if commentBytes*8 > r.left() {
+		return nil, fmt.Errorf("%w: comment of program_config_element is too long", errInvalidAudioSpecificConfig)
+	}
+	p.Comment = make([]byte, commentBytes)
+	for i := range p.Comment {
+		p.Comment[i] = uint8(r.read(8))
+	}
+	return p, nil
+}
+
+func (p *ProgramConfigElement) channelLayout() ChannelLayout {
+	count := func(elements []ChannelElement) uint8 {
+		n := uint8(0)
+		for _, e := range elements {
+			if e.IsCPE {
+				n += 2
+			} else {
+				n++
+			}
+		}
+		return n
+	}
+	return ChannelLayout{
+		Front: count(p.FrontElements),
+		Side:  count(p.SideElements),
+		Back:  count(p.BackElements),
+		LFE:   uint8(len(p.LFEElements)),
+	}
+}
+
+// ELDSpecificConfig refer to ISO/IEC 14496-3 4.4.1.2
+func (p *AudioSpecificConfig) parseELDSpecificConfig(r *ascReader) error {
+	p.FrameLength = 512
+	if r.readBool() { // frameLengthFlag
+		p.FrameLength = 480
+	}
+	_ = r.read(3) // aacSectionDataResilienceFlag, aacScalefactorDataResilienceFlag, aacSpectralDataResilienceFlag
+	ldSbrPresent := r.readBool()
+	if ldSbrPresent {
+		p.SBRPresent = true
+		ldSbrSamplingRate := r.readBool()
+		_ = r.read(1) // ldSbrCrcFlag
+		// dual rate SBR doubles the sampling frequency
+		p.ExtensionSamplingFrequency = p.SamplingFrequency
+		if ldSbrSamplingRate {
+			p.ExtensionSamplingFrequency = p.SamplingFrequency * 2
+		}
+		numSbrHeader := 0
+		switch p.ChannelConfiguration {
+		case 1, 2:
+			numSbrHeader = 1
+		case 3:
+			numSbrHeader = 2
+		case 4, 5, 6:
+			numSbrHeader = 3
+		case 7:
+			numSbrHeader = 4
+		}
+		for i := 0; i < numSbrHeader; i++ {
+			parseSbrHeader(r)
+		}
+	}
+	const eldExtTerm = 0
+	for r.left() >= 4 {
+		eldExtType := r.read(4)
+		if eldExtType == eldExtTerm {
+			break
+		}
+		eldExtLen := int(r.read(4))
+		if eldExtLen == 15 {
+			eldExtLen += int(r.read(8))
+			if eldExtLen == 15+255 {
+				eldExtLen += int(r.read(16))
+			}
+		}
+		if eldExtLen*8 > r.left() {
+			return fmt.Errorf("%w: ELD extension is too long", errInvalidAudioSpecificConfig)
+		}
+		r.skip(eldExtLen * 8)
+	}
+	return nil
+}
+
+// sbr_header refer to ISO/IEC 14496-3 4.4.2.8
+func parseSbrHeader(r *ascReader) {
+	_ = r.read(1) // bs_amp_res
+	_ = r.read(4) // bs_start_freq
+	_ = r.read(4) // bs_stop_freq
+	_ = r.read(3) // bs_xover_band
+	_ = r.read(2) // bs_reserved
+	extra1 := r.readBool()
+	extra2 := r.readBool()
+	if extra1 {
+		_ = r.read(5) // bs_freq_scale, bs_alter_scale, bs_noise_bands
+	}
+	if extra2 {
+		_ = r.read(6) // bs_limiter_bands, bs_limiter_gains, bs_interpol_freq, bs_smoothing_mode
+	}
+}
+
+// UsacConfig refer to ISO/IEC 23003-3 5.2. Only the fields before UsacDecoderConfig are parsed.
+func (p *AudioSpecificConfig) parseUsacConfig(r *ascReader) error {
+	index := r.read(5)
+	if index == 0x1F {
+		p.SamplingFrequency = r.read(24)
+	} else if index < uint32(len(usacSamplingFrequencies)) && usacSamplingFrequencies[index] != 0 {
+		p.SamplingFrequency = usacSamplingFrequencies[index]
+	} else {
+		return fmt.Errorf("%w: usacSamplingFrequencyIndex %d is reserved", errInvalidAudioSpecificConfig, index)
+	}
+	coreSbrFrameLengthIndex := r.read(3)
+	switch coreSbrFrameLengthIndex {
+	case 0:
+		p.FrameLength = 768
+	case 1:
+		p.FrameLength = 1024
+	case 2, 3:
+		// 8:3 and 2:1 SBR, the output frame is 2048 samples
+		p.FrameLength = 2048
+		p.SBRPresent = true
+	case 4:
+		// 4:1 SBR
+		p.FrameLength = 4096
+		p.SBRPresent = true
+	}
+	// the sampling frequency of USAC is the output sampling frequency
+	p.SampleRate = p.SamplingFrequency
+	p.ChannelConfiguration = uint8(r.read(5))
+	if p.ChannelConfiguration == 0 {
+		// UsacChannelConfig
+		numOutChannels := r.escapedValue(5, 8, 16)
+		if int(numOutChannels)*5 > r.left() {
+			return fmt.Errorf("%w: too many channels in UsacChannelConfig", errInvalidAudioSpecificConfig)
+		}
+		for i := uint32(0); i < numOutChannels; i++ {
+			p.ChannelLayout.add(uint8(r.read(5)))
+		}
+	} else if layout, ok := cicpChannelLayouts[p.ChannelConfiguration]; ok {
+		p.ChannelLayout = layout
+	}
+	p.ChannelCount = p.ChannelLayout.Channels()
+	return nil
+}
+
+// add adds a loudspeaker of bsOutputChannelPos, refer to ISO/IEC 23001-8 Table 8 (CICP SpeakerLayout).
+func (p *ChannelLayout) add(position uint8) {
+	switch position {
+	case 0, 1, 2, 6, 7, 13, 14: // L, R, C, Lc, Rc, Lw, Rw
+		p.Front++
+	case 4, 5, 11, 12: // Ls, Rs, Lsd, Rsd
+		p.Side++
+	case 8, 9, 10: // Lsr, Rsr, Cs
+		p.Back++
+	case 3, 24: // LFE, LFE2
+		p.LFE++
+	case 25, 26, 27: // Lb, Rb, Cb
+		p.Bottom++
+	default: // Lv, Rv, Cv, Lvr, Rvr, Cvr, Lvss, Rvss, Ts ...
+		p.Top++
+	}
+}
+
+// ALSSpecificConfig refer to ISO/IEC 14496-3 11.2.1
+func (p *AudioSpecificConfig) parseALSSpecificConfig(r *ascReader) error {
+	if r.left() < 32*3+16+6 {
+		return fmt.Errorf("%w: ALSSpecificConfig is too short", errInvalidAudioSpecificConfig)
+	}
+	if alsID := r.read(32); alsID != 0x414C5300 { // "ALS\0"
+		return fmt.Errorf("%w: invalid als_id %x", errInvalidAudioSpecificConfig, alsID)
+	}
+	p.SampleRate = r.read(32)
+	p.SamplingFrequency = p.SampleRate
+	_ = r.read(32) // samples
+	p.ChannelCount = uint16(r.read(16)) + 1
+	_ = r.read(3) // file_type
+	p.BitsPerSample = uint8(r.read(3)+1) * 8
+	p.FrameLength = 0
+	if p.ChannelCount <= 2 {
+		p.ChannelLayout = ChannelLayout{Front: uint8(p.ChannelCount)}
+	}
+	return nil
+}
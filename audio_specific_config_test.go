@@ -0,0 +1,79 @@
+package fmp4parser
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseAudioSpecificConfig(t *testing.T) {
+	newLog(ioutil.Discard)
+	tests := []struct {
+		name            string
+		data            []byte
+		signalled, core int
+		sbr, ps         bool
+		sampleRate      uint32
+		channels        uint16
+		layout          string
+		frameLength     int
+	}{
+		{"aac lc", []byte{0x12, 0x10}, 2, 2, false, false, 44100, 2, "2/0/0.0", 1024},
+		{"he-aac explicit", new(bitWriter).write(5, 5).write(6, 4).write(2, 4).write(3, 4).write(2, 5).write(0, 3).align().b,
+			5, 2, true, false, 48000, 2, "2/0/0.0", 1024},
+		{"he-aac v2 backward compatible",
+			new(bitWriter).write(2, 5).write(6, 4).write(1, 4).write(0, 3).
+				write(0x2b7, 11).write(5, 5).write(1, 1).write(3, 4).write(0x548, 11).write(1, 1).align().b,
+			2, 2, true, true, 48000, 2, "2/0/0.0", 1024},
+		{"pce 5.1",
+			new(bitWriter).write(2, 5).write(3, 4).write(0, 4).write(0, 3).
+				write(0, 4).write(1, 2).write(3, 4). // element_instance_tag, object_type, sampling_frequency_index
+				write(2, 4).write(0, 4).write(1, 4).write(1, 2).write(0, 3).write(0, 4).
+				write(0, 3).                                     // no mixdown
+				write(0, 1).write(0, 4).write(1, 1).write(0, 4). // front: SCE, CPE
+				write(1, 1).write(1, 4).                         // back: CPE
+				write(0, 4).                                     // lfe
+				align().write(0, 8).b,
+			2, 2, false, false, 48000, 6, "3/0/2.1", 1024},
+		{"xhe-aac",
+			new(bitWriter).write(31, 5).write(42-32, 6).write(3, 4).write(2, 4).
+				write(3, 5).write(1, 3).write(2, 5).write(0, 16).align().b,
+			42, 42, false, false, 48000, 2, "2/0/0.0", 1024},
+		{"xhe-aac channel config",
+			new(bitWriter).write(31, 5).write(42-32, 6).write(3, 4).write(0, 4).
+				write(3, 5).write(3, 3).write(0, 5).write(3, 5).write(0, 5).write(1, 5).write(3, 5).write(0, 16).align().b,
+			42, 42, true, false, 48000, 3, "2/0/0.1", 2048},
+		{"aac eld with sbr",
+			new(bitWriter).write(31, 5).write(39-32, 6).write(3, 4).write(1, 4).
+				write(0, 4).write(1, 1).write(1, 1).write(0, 1). // ldSbrPresentFlag, ldSbrSamplingRate, ldSbrCrcFlag
+				write(0, 16).                                    // sbr_header
+				write(0, 4).write(0, 2).align().b,               // ELDEXT_TERM, epConfig
+			39, 39, true, false, 96000, 1, "1/0/0.0", 512},
+		{"als",
+			new(bitWriter).write(31, 5).write(36-32, 6).write(4, 4).write(2, 4).align().
+				write(0x414C5300, 32).write(44100, 32).write(1000, 32).write(1, 16).write(0, 3).write(1, 3).write(0, 26).b,
+			36, 36, false, false, 44100, 2, "2/0/0.0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asc, err := parseAudioSpecificConfig(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asc.SignalledObjectType != tt.signalled || asc.AudioObjectType != tt.core {
+				t.Errorf("object type = %d/%d, want %d/%d", asc.SignalledObjectType, asc.AudioObjectType, tt.signalled, tt.core)
+			}
+			if asc.SBRPresent != tt.sbr || asc.PSPresent != tt.ps {
+				t.Errorf("sbr/ps = %v/%v, want %v/%v", asc.SBRPresent, asc.PSPresent, tt.sbr, tt.ps)
+			}
+			if asc.SampleRate != tt.sampleRate || asc.ChannelCount != tt.channels {
+				t.Errorf("sample rate/channels = %d/%d, want %d/%d", asc.SampleRate, asc.ChannelCount, tt.sampleRate, tt.channels)
+			}
+			if tt.layout != "" && asc.ChannelLayout.String() != tt.layout {
+				t.Errorf("layout = %s, want %s", asc.ChannelLayout, tt.layout)
+			}
+			if asc.FrameLength != tt.frameLength {
+				t.Errorf("frame length = %d, want %d", asc.FrameLength, tt.frameLength)
+			}
+		})
+	}
+}
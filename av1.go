@@ -0,0 +1,408 @@
+package fmp4parser
+
+import (
+	"errors"
+)
+
+/*
+AV1 bitstream is a sequence of OBUs (Open Bitstream Unit). refer to
+	AV1 Bitstream & Decoding Process Specification 5.3 ~ 5.9
+	https://aomediacodec.github.io/av1-spec/
+
+The configOBUs of "av1C" and the samples of "av01" are in the Low Overhead Bitstream
+Format, i.e. each OBU has obu_size field.
+*/
+
+// obu_type of the OBU header
+const (
+	Av1OBUSequenceHeader       = 1
+	Av1OBUTemporalDelimiter    = 2
+	Av1OBUFrameHeader          = 3
+	Av1OBUTileGroup            = 4
+	Av1OBUMetadata             = 5
+	Av1OBUFrame                = 6
+	Av1OBURedundantFrameHeader = 7
+	Av1OBUTileList             = 8
+	Av1OBUPadding              = 15
+)
+
+// metadata_type of the metadata OBU
+const (
+	Av1MetadataHdrCll      = 1
+	Av1MetadataHdrMdcv     = 2
+	Av1MetadataScalability = 3
+	Av1MetadataItutT35     = 4
+	Av1MetadataTimecode    = 5
+)
+
+const av1KeyFrame = 0
+
+var errInvalidOBU = errors.New("invalid AV1 OBU")
+
+// Av1OBU is an OBU of AV1 bitstream.
+type Av1OBU struct {
+	Type       uint8
+	TemporalID uint8 // 0 if obu_extension_flag is 0
+	SpatialID  uint8
+	Payload    []byte
+}
+
+// Av1OperatingPoint is an operating point of the sequence header.
+type Av1OperatingPoint struct {
+	Idc         uint16 // operating_point_idc, the temporal and spatial layers of the operating point
+	SeqLevelIdx uint8
+	SeqTier     uint8
+}
+
+// Av1SequenceHeader is the parsed sequence header OBU.
+type Av1SequenceHeader struct {
+	SeqProfile                uint8
+	StillPicture              bool
+	ReducedStillPictureHeader bool
+	OperatingPoints           []Av1OperatingPoint
+
+	// timing info, TimeScale is 0 if not present
+	NumUnitsInDisplayTick uint32
+	TimeScale             uint32
+	EqualPictureInterval  bool
+	NumTicksPerPicture    uint32
+
+	MaxFrameWidth  uint32
+	MaxFrameHeight uint32
+
+	// color config
+	BitDepth                uint8
+	MonoChrome              bool
+	ColorDescriptionPresent bool
+	ColorPrimaries          uint8
+	TransferCharacteristics uint8
+	MatrixCoefficients      uint8
+	FullRange               bool
+	SubsamplingX            uint8
+	SubsamplingY            uint8
+	ChromaSamplePosition    uint8
+
+	FilmGrainParamsPresent bool
+}
+
+// Av1Metadata is the parsed metadata OBU.
+type Av1Metadata struct {
+	Type             uint64
+	ContentLight     *ContentLightLevel            // Av1MetadataHdrCll
+	MasteringDisplay *MasteringDisplayColourVolume // Av1MetadataHdrMdcv
+	// Av1MetadataItutT35
+	CountryCode          uint8
+	CountryCodeExtension uint8
+	Payload              []byte // the payload of ITU-T T.35 or the other metadata types
+}
+
+// readLeb128 reads leb128() and returns the value and the number of bytes read.
+func readLeb128(data []byte) (uint64, int, error) {
+	value := uint64(0)
+	for i := 0; i < 8; i++ {
+		if i >= len(data) {
+			return 0, 0, errInvalidOBU
+		}
+		value |= uint64(data[i]&0x7F) << (uint(i) * 7)
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, errInvalidOBU
+}
+
+// ParseAv1OBUs splits the data of the Low Overhead Bitstream Format into OBUs, e.g. the
+// configOBUs of "av1C" or a sample of "av01". The payloads refer to data.
+func ParseAv1OBUs(data []byte) ([]Av1OBU, error) {
+	var obus []Av1OBU
+	for len(data) > 0 {
+		header := data[0]
+		if header&0x80 != 0 { // obu_forbidden_bit
+			return obus, errInvalidOBU
+		}
+		obu := Av1OBU{Type: header >> 3 & 0xF}
+		pos := 1
+		if header&0x04 != 0 { // obu_extension_flag
+			if len(data) < 2 {
+				return obus, errInvalidOBU
+			}
+			obu.TemporalID = data[1] >> 5
+			obu.SpatialID = data[1] >> 3 & 0x3
+			pos++
+		}
+		size := uint64(len(data) - pos)
+		if header&0x02 != 0 { // obu_has_size_field
+			n := 0
+			var err error
+			if size, n, err = readLeb128(data[pos:]); err != nil {
+				return obus, err
+			}
+			pos += n
+		}
+		if size > uint64(len(data)-pos) {
+			return obus, errInvalidOBU
+		}
+		obu.Payload = data[pos : pos+int(size)]
+		obus = append(obus, obu)
+		data = data[pos+int(size):]
+	}
+	return obus, nil
+}
+
+// uvlc reads uvlc(), the variable length unsigned number.
+func (r *rbspReader) uvlc() uint32 {
+	leadingZeros := uint(0)
+	for !r.readBool() {
+		if r.err() != nil {
+			return 0
+		}
+		leadingZeros++
+	}
+	if leadingZeros >= 32 {
+		return 1<<32 - 1
+	}
+	return r.read(leadingZeros) + 1<<leadingZeros - 1
+}
+
+// SequenceHeader parses the payload of the sequence header OBU, refer to AV1 spec 5.5.
+// AV1 has no emulation prevention, the payload is read by rbspReader directly.
+func (p *Av1OBU) SequenceHeader() (*Av1SequenceHeader, error) {
+	if p.Type != Av1OBUSequenceHeader {
+		return nil, errInvalidOBU
+	}
+	r := &rbspReader{br: newBitReaderFromSlice(p.Payload)}
+	seq := new(Av1SequenceHeader)
+	seq.SeqProfile = uint8(r.read(3))
+	seq.StillPicture = r.readBool()
+	seq.ReducedStillPictureHeader = r.readBool()
+	if seq.ReducedStillPictureHeader {
+		seq.OperatingPoints = []Av1OperatingPoint{{SeqLevelIdx: uint8(r.read(5))}}
+	} else {
+		decoderModelInfoPresent := false
+		bufferDelayLength := uint(0)
+		if r.readBool() { // timing_info_present_flag
+			seq.NumUnitsInDisplayTick = r.read(32)
+			seq.TimeScale = r.read(32)
+			seq.EqualPictureInterval = r.readBool()
+			if seq.EqualPictureInterval {
+				seq.NumTicksPerPicture = r.uvlc() + 1
+			}
+			decoderModelInfoPresent = r.readBool()
+			if decoderModelInfoPresent {
+				bufferDelayLength = uint(r.read(5)) + 1
+				_ = r.read(32) // num_units_in_decoding_tick
+				_ = r.read(10) // buffer_removal_time_length_minus_1, frame_presentation_time_length_minus_1
+			}
+		}
+		initialDisplayDelayPresent := r.readBool()
+		operatingPointsCnt := int(r.read(5)) + 1
+		for i := 0; i < operatingPointsCnt; i++ {
+			op := Av1OperatingPoint{Idc: uint16(r.read(12)), SeqLevelIdx: uint8(r.read(5))}
+			if op.SeqLevelIdx > 7 {
+				op.SeqTier = uint8(r.read(1))
+			}
+			if decoderModelInfoPresent && r.readBool() { // decoder_model_present_for_this_op
+				// decoder_buffer_delay, encoder_buffer_delay, low_delay_mode_flag
+				r.skip(int(2*bufferDelayLength + 1))
+			}
+			if initialDisplayDelayPresent && r.readBool() {
+				_ = r.read(4) // initial_display_delay_minus_1
+			}
+			seq.OperatingPoints = append(seq.OperatingPoints, op)
+		}
+	}
+	frameWidthBits := uint(r.read(4)) + 1
+	frameHeightBits := uint(r.read(4)) + 1
+	seq.MaxFrameWidth = r.read(frameWidthBits) + 1
+	seq.MaxFrameHeight = r.read(frameHeightBits) + 1
+	if !seq.ReducedStillPictureHeader && r.readBool() { // frame_id_numbers_present_flag
+		_ = r.read(7) // delta_frame_id_length_minus_2, additional_frame_id_length_minus_1
+	}
+	_ = r.read(3) // use_128x128_superblock, enable_filter_intra, enable_intra_edge_filter
+	if !seq.ReducedStillPictureHeader {
+		// enable_interintra_compound, enable_masked_compound, enable_warped_motion, enable_dual_filter
+		_ = r.read(4)
+		enableOrderHint := r.readBool()
+		if enableOrderHint {
+			_ = r.read(2) // enable_jnt_comp, enable_ref_frame_mvs
+		}
+		// seq_force_screen_content_tools is SELECT_SCREEN_CONTENT_TOOLS if seq_choose_screen_content_tools is set
+		forceScreenContentTools := true
+		if !r.readBool() {
+			forceScreenContentTools = r.readBool()
+		}
+		if forceScreenContentTools && !r.readBool() { // seq_choose_integer_mv
+			_ = r.readBool() // seq_force_integer_mv
+		}
+		if enableOrderHint {
+			_ = r.read(3) // order_hint_bits_minus_1
+		}
+	}
+	_ = r.read(3) // enable_superres, enable_cdef, enable_restoration
+	seq.parseColorConfig(r)
+	seq.FilmGrainParamsPresent = r.readBool()
+	if err := r.err(); err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// parseColorConfig parses color_config(), refer to AV1 spec 5.5.2.
+func (p *Av1SequenceHeader) parseColorConfig(r *rbspReader) {
+	p.BitDepth = 8
+	if r.readBool() { // high_bitdepth
+		p.BitDepth = 10
+		if p.SeqProfile == 2 && r.readBool() { // twelve_bit
+			p.BitDepth = 12
+		}
+	}
+	if p.SeqProfile != 1 {
+		p.MonoChrome = r.readBool()
+	}
+	// CP_UNSPECIFIED, TC_UNSPECIFIED and MC_UNSPECIFIED
+	p.ColorPrimaries, p.TransferCharacteristics, p.MatrixCoefficients = 2, 2, 2
+	p.ColorDescriptionPresent = r.readBool()
+	if p.ColorDescriptionPresent {
+		p.ColorPrimaries = uint8(r.read(8))
+		p.TransferCharacteristics = uint8(r.read(8))
+		p.MatrixCoefficients = uint8(r.read(8))
+	}
+	if p.MonoChrome {
+		p.FullRange = r.readBool()
+		p.SubsamplingX, p.SubsamplingY = 1, 1
+		return
+	}
+	// CP_BT_709, TC_SRGB and MC_IDENTITY
+	if p.ColorPrimaries == 1 && p.TransferCharacteristics == 13 && p.MatrixCoefficients == 0 {
+		p.FullRange = true
+	} else {
+		p.FullRange = r.readBool()
+		switch p.SeqProfile {
+		case 0:
+			p.SubsamplingX, p.SubsamplingY = 1, 1
+		case 1:
+		default:
+			p.SubsamplingX, p.SubsamplingY = 1, 0
+			if p.BitDepth == 12 {
+				p.SubsamplingX, p.SubsamplingY = uint8(r.read(1)), 0
+				if p.SubsamplingX == 1 {
+					p.SubsamplingY = uint8(r.read(1))
+				}
+			}
+		}
+		if p.SubsamplingX == 1 && p.SubsamplingY == 1 {
+			p.ChromaSamplePosition = uint8(r.read(2))
+		}
+	}
+	_ = r.readBool() // separate_uv_delta_q
+}
+
+// Metadata parses the payload of the metadata OBU, refer to AV1 spec 5.8.
+func (p *Av1OBU) Metadata() (*Av1Metadata, error) {
+	if p.Type != Av1OBUMetadata {
+		return nil, errInvalidOBU
+	}
+	metadataType, n, err := readLeb128(p.Payload)
+	if err != nil {
+		return nil, err
+	}
+	m := &Av1Metadata{Type: metadataType, Payload: p.Payload[n:]}
+	r := &rbspReader{br: newBitReaderFromSlice(m.Payload)}
+	switch metadataType {
+	case Av1MetadataHdrCll:
+		m.ContentLight = &ContentLightLevel{MaxCLL: uint16(r.read(16)), MaxFALL: uint16(r.read(16))}
+	case Av1MetadataHdrMdcv:
+		// the chromaticity coordinates are 0.16 fixed-point, luminance_max is 24.8 and luminance_min is 18.14
+		mdcv := new(MasteringDisplayColourVolume)
+		for i := range mdcv.Primaries {
+			mdcv.Primaries[i].X = float64(r.read(16)) / (1 << 16)
+			mdcv.Primaries[i].Y = float64(r.read(16)) / (1 << 16)
+		}
+		mdcv.WhitePoint.X = float64(r.read(16)) / (1 << 16)
+		mdcv.WhitePoint.Y = float64(r.read(16)) / (1 << 16)
+		mdcv.MaxLuminance = float64(r.read(32)) / (1 << 8)
+		mdcv.MinLuminance = float64(r.read(32)) / (1 << 14)
+		m.MasteringDisplay = mdcv
+	case Av1MetadataItutT35:
+		if len(m.Payload) < 1 {
+			return nil, errInvalidOBU
+		}
+		m.CountryCode = m.Payload[0]
+		m.Payload = m.Payload[1:]
+		if m.CountryCode == 0xFF {
+			if len(m.Payload) < 1 {
+				return nil, errInvalidOBU
+			}
+			m.CountryCodeExtension = m.Payload[0]
+			m.Payload = m.Payload[1:]
+		}
+	}
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseConfigOBUs parses the sequence header and the metadata of the configOBUs.
+func (p *Av1cConfig) parseConfigOBUs(data []byte) error {
+	obus, err := ParseAv1OBUs(data)
+	p.ConfigOBUs = obus
+	for i := range obus {
+		switch obus[i].Type {
+		case Av1OBUSequenceHeader:
+			seq, err := obus[i].SequenceHeader()
+			if err != nil {
+				return err
+			}
+			p.SequenceHeader = seq
+		case Av1OBUMetadata:
+			m, err := obus[i].Metadata()
+			if err != nil {
+				return err
+			}
+			p.Metadata = append(p.Metadata, m)
+		}
+	}
+	return err
+}
+
+// av1Config returns "av1C" of the "av01" track, nil if it's not AV1.
+func (p *boxTrak) av1Config() *Av1cConfig {
+	if p.videoEntry == nil || p.videoEntry.codec != VideoCodecAV1 {
+		return nil
+	}
+	av1c, _ := p.videoEntry.decoderConfigurationRecords[VideoCodecAV1].(*Av1cConfig)
+	return av1c
+}
+
+// IsKeyFrame returns whether the "av01" sample starts with a shown key frame, i.e. it's
+// a sync sample. The sequence header of the sample is used if present, otherwise the one
+// of the configOBUs.
+func (p *Av1cConfig) IsKeyFrame(sample []byte) bool {
+	obus, err := ParseAv1OBUs(sample)
+	if err != nil {
+		return false
+	}
+	seq := p.SequenceHeader
+	for i := range obus {
+		switch obus[i].Type {
+		case Av1OBUSequenceHeader:
+			if s, err := obus[i].SequenceHeader(); err == nil {
+				seq = s
+			}
+		case Av1OBUFrameHeader, Av1OBUFrame:
+			if seq != nil && seq.ReducedStillPictureHeader {
+				return true
+			}
+			// uncompressed_header(): show_existing_frame, frame_type and show_frame
+			r := &rbspReader{br: newBitReaderFromSlice(obus[i].Payload)}
+			if r.readBool() {
+				return false
+			}
+			frameType := r.read(2)
+			showFrame := r.readBool()
+			return r.err() == nil && frameType == av1KeyFrame && showFrame
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func mkOBU(obuType uint8, payload []byte) []byte {
+	return append([]byte{obuType<<3 | 0x02, byte(len(payload))}, payload...)
+}
+
+func mkAv1SequenceHeader() []byte {
+	return new(bitWriter).write(0, 3).write(0, 1).write(0, 1).
+		write(1, 1).write(1, 32).write(30, 32).write(1, 1).write(1, 1).write(0, 1). // timing info, no decoder model
+		write(0, 1).write(0, 5).write(0, 12).write(8, 5).write(0, 1).               // one operating point of level 4.0
+		write(10, 4).write(10, 4).write(1919, 11).write(1079, 11).write(0, 1).
+		write(0, 3).write(0, 4).write(1, 1).write(0, 2).write(1, 1).write(1, 1).write(6, 3).write(0, 3).
+		write(1, 1).write(0, 1).write(1, 1).write(9, 8).write(16, 8).write(9, 8). // 10 bits, BT.2020 PQ
+		write(0, 1).write(1, 2).write(0, 1).                                      // color_range, chroma_sample_position, separate_uv_delta_q
+		write(1, 1).write(1, 1).align().b
+}
+
+func TestAv1cConfig_parseConfigOBUs(t *testing.T) {
+	cll := mkOBU(Av1OBUMetadata, []byte{Av1MetadataHdrCll, 0x03, 0xE8, 0x01, 0x90})
+	mdcv := new(bitWriter).write(Av1MetadataHdrMdcv, 8)
+	for _, v := range []uint64{46399, 19137, 11141, 52298, 9830, 3014, 20493, 21561} {
+		mdcv.write(v, 16)
+	}
+	mdcv.write(1000<<8, 32).write(82, 32) // 1000 and 0.005 cd/m2
+	t35 := mkOBU(Av1OBUMetadata, []byte{Av1MetadataItutT35, 0xB5, 0x00, 0x3C})
+	config := append(append(append(mkOBU(Av1OBUSequenceHeader, mkAv1SequenceHeader()), cll...),
+		mkOBU(Av1OBUMetadata, mdcv.b)...), t35...)
+
+	p := new(Av1cConfig)
+	if err := p.parseConfigOBUs(config); err != nil {
+		t.Fatal(err)
+	}
+	seq := p.SequenceHeader
+	if seq == nil || len(p.ConfigOBUs) != 4 || len(p.Metadata) != 3 {
+		t.Fatalf("got %d OBUs, %d metadata, sequence header %v", len(p.ConfigOBUs), len(p.Metadata), seq)
+	}
+	if seq.MaxFrameWidth != 1920 || seq.MaxFrameHeight != 1080 || seq.BitDepth != 10 || seq.SubsamplingX != 1 ||
+		seq.SubsamplingY != 1 || seq.ChromaSamplePosition != 1 || !seq.FilmGrainParamsPresent {
+		t.Errorf("unexpected sequence header %+v", seq)
+	}
+	if seq.TimeScale != 30 || seq.NumUnitsInDisplayTick != 1 || seq.NumTicksPerPicture != 1 {
+		t.Errorf("unexpected timing info %+v", seq)
+	}
+	if len(seq.OperatingPoints) != 1 || seq.OperatingPoints[0].SeqLevelIdx != 8 {
+		t.Errorf("operating points = %+v", seq.OperatingPoints)
+	}
+	if seq.ColorPrimaries != 9 || seq.TransferCharacteristics != 16 || seq.MatrixCoefficients != 9 || seq.FullRange {
+		t.Errorf("unexpected color config %+v", seq)
+	}
+	if c := p.Metadata[0].ContentLight; c == nil || c.MaxCLL != 1000 || c.MaxFALL != 400 {
+		t.Errorf("content light level = %+v", c)
+	}
+	m := p.Metadata[1].MasteringDisplay
+	if m == nil || m.MaxLuminance != 1000 || m.MinLuminance < 0.0049 || m.MinLuminance > 0.0051 ||
+		m.Primaries[0].X < 0.707 || m.Primaries[0].X > 0.709 || m.WhitePoint.Y < 0.328 || m.WhitePoint.Y > 0.33 {
+		t.Errorf("mastering display = %+v", m)
+	}
+	if t35 := p.Metadata[2]; t35.CountryCode != 0xB5 || len(t35.Payload) != 2 {
+		t.Errorf("itu-t t.35 = %+v", t35)
+	}
+}
+
+func TestAv1cConfig_IsKeyFrame(t *testing.T) {
+	p := new(Av1cConfig)
+	td := mkOBU(Av1OBUTemporalDelimiter, nil)
+	tests := []struct {
+		name   string
+		sample []byte
+		want   bool
+	}{
+		{"key frame", append(append(append([]byte{}, td...), mkOBU(Av1OBUSequenceHeader, mkAv1SequenceHeader())...),
+			mkOBU(Av1OBUFrame, []byte{0x10, 0})...), true},
+		{"inter frame", append(append([]byte{}, td...), mkOBU(Av1OBUFrame, []byte{0x30, 0})...), false},
+		{"hidden key frame", append(append([]byte{}, td...), mkOBU(Av1OBUFrameHeader, []byte{0x00})...), false},
+		{"show existing frame", append(append([]byte{}, td...), mkOBU(Av1OBUFrameHeader, []byte{0x80})...), false},
+		{"truncated", []byte{Av1OBUFrame<<3 | 0x02, 10, 0x10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.IsKeyFrame(tt.sample); got != tt.want {
+				t.Errorf("IsKeyFrame() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ReadPacketAv1Sync(t *testing.T) {
+	// a fragment without "stss" or sample flags: the sync samples are detected from the data
+	init := mkVideoInit(mkVideoEntry("av01", 1920, 1080, mkBox("av1C", []byte{0x81, 0x08, 0x0C, 0x00})))
+	td := mkOBU(Av1OBUTemporalDelimiter, nil)
+	key := append(append([]byte{}, td...), mkOBU(Av1OBUFrame, []byte{0x10, 0})...)
+	inter := append(append([]byte{}, td...), mkOBU(Av1OBUFrame, []byte{0x30, 0})...)
+	traf := mkBox("traf", mkFullBox("tfhd", 0, 0x020000, u32(1)),
+		mkFullBox("trun", 0, 0x000201, u32(2), u32(0), u32(uint32(len(key))), u32(uint32(len(inter)))))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf)
+	// data_offset of "trun" points to the data of "mdat"
+	binary.BigEndian.PutUint32(moof[len(moof)-12:], uint32(len(moof)+8))
+	file := append(append(init, moof...), mkBox("mdat", key, inter)...)
+
+	p := newTestParser(t, file)
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil || len(packets) != 2 {
+		t.Fatalf("Packets() = %d packets, %v", len(packets), err)
+	}
+	for i, want := range []bool{false, true} {
+		if err = p.ReadPacket(&packets[i]); err != nil {
+			t.Fatal(err)
+		}
+		if packets[i].SampleFlags.IsNonSync != want {
+			t.Errorf("IsNonSync of packet %d = %v, want %v", i, packets[i].SampleFlags.IsNonSync, want)
+		}
+	}
+}
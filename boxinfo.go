@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"fmt"
@@ -11,6 +11,7 @@ const (
 	AudioTrack
 	VideoTrack
 	SubtitleTrack
+	TimecodeTrack
 )
 
 // encryption scheme type
@@ -54,6 +55,11 @@ var (
 	fourCCmvex uint32 = 0x6d766578 // "mvex"
 	fourCCmehd uint32 = 0x6d656864 // "mehd"
 	fourCCmeta uint32 = 0x6d657461 // "meta"
+	fourCCilst uint32 = 0x696c7374 // "ilst"
+	fourCCmean uint32 = 0x6d65616e // "mean"
+	fourCCname uint32 = 0x6e616d65 // "name"
+	fourCCdata uint32 = 0x64617461 // "data"
+	fourCC____ uint32 = 0x2d2d2d2d // "----", freeform tag of iTunes
 	fourCCtrep uint32 = 0x74726570 // "trep"
 	fourCCtrex uint32 = 0x74726578 // "trex"
 	fourCCleva uint32 = 0x6c657661 // "leva"
@@ -65,6 +71,8 @@ var (
 	fourCCtrun uint32 = 0x7472756E // "trun"
 	fourCCsbgp uint32 = 0x73626770 // "sbgp"
 	fourCCsgpd uint32 = 0x73677064 // "sgpd"
+	fourCCroll uint32 = 0x726f6c6c // "roll"
+	fourCCprol uint32 = 0x70726f6c // "prol"
 	fourCCsenc uint32 = 0x73656e63 // "senc"
 	fourCCsubs uint32 = 0x73756273 // "subs"
 	fourCCsaiz uint32 = 0x7361697A // "saiz"
@@ -83,6 +91,10 @@ var (
 	fourCCtrak uint32 = 0x7472616b // "trak"
 	fourCCtkhd uint32 = 0x746b6864 // "tkhd"
 	fourCCedts uint32 = 0x65647473 // "edts"
+	fourCCtref uint32 = 0x74726566 // "tref"
+	fourCCtrgr uint32 = 0x74726772 // "trgr"
+	fourCCster uint32 = 0x73746572 // "ster"
+	fourCCelst uint32 = 0x656c7374 // "elst"
 	fourCCmdia uint32 = 0x6d646961 // "mdia"
 	fourCCmdhd uint32 = 0x6d646864 // "mdhd"
 	fourCChdlr uint32 = 0x68646c72 // "hdlr"
@@ -111,6 +123,33 @@ var (
 	fourCCcolr uint32 = 0x636f6c72 // "colr"
 	fourCCclap uint32 = 0x636c6170 // "clap"
 	fourCCpasp uint32 = 0x70617370 // "pasp"
+	fourCCmdcv uint32 = 0x6d646376 // "mdcv"
+	fourCCclli uint32 = 0x636c6c69 // "clli"
+	fourCCSmDm uint32 = 0x536d446d // "SmDm"
+	fourCCCoLL uint32 = 0x436f4c4c // "CoLL"
+	fourCCst3d uint32 = 0x73743364 // "st3d"
+	fourCCsv3d uint32 = 0x73763364 // "sv3d"
+	fourCCsvhd uint32 = 0x73766864 // "svhd"
+	fourCCproj uint32 = 0x70726f6a // "proj"
+	fourCCprhd uint32 = 0x70726864 // "prhd"
+	fourCCequi uint32 = 0x65717569 // "equi"
+	fourCCcbmp uint32 = 0x63626d70 // "cbmp"
+	fourCCmshp uint32 = 0x6d736870 // "mshp"
+	fourCCdref uint32 = 0x64726566 // "dref"
+	fourCCurl  uint32 = 0x75726c20 // "url "
+	fourCCurn  uint32 = 0x75726e20 // "urn "
+
+	// QuickTime File Format atoms
+	fourCCclip uint32 = 0x636c6970 // "clip"
+	fourCCcrgn uint32 = 0x6372676e // "crgn"
+	fourCCmatt uint32 = 0x6d617474 // "matt"
+	fourCCkmat uint32 = 0x6b6d6174 // "kmat"
+	fourCCgmhd uint32 = 0x676d6864 // "gmhd"
+	fourCCgmin uint32 = 0x676d696e // "gmin"
+	fourCCtmcd uint32 = 0x746d6364 // "tmcd"
+	fourCCtcmi uint32 = 0x74636d69 // "tcmi"
+	fourCCalis uint32 = 0x616c6973 // "alis"
+	fourCCchan uint32 = 0x6368616e // "chan"
 
 	avc1SampleEntry uint32 = 0x61766331 // "avc1"   video sample entry ->
 	avc2SampleEntry uint32 = 0x61766332 // "avc2"
@@ -127,6 +166,11 @@ var (
 	vp08SampleEntry uint32 = 0x76703038 // "vp08"
 	vp09SampleEntry uint32 = 0x76703039 // "vp09"
 	av01SampleEntry uint32 = 0x61763031 // "av01"
+	dav1SampleEntry uint32 = 0x64617631 // "dav1"
+	vvc1SampleEntry uint32 = 0x76766331 // "vvc1"
+	vvi1SampleEntry uint32 = 0x76766931 // "vvi1"
+	vvs1SampleEntry uint32 = 0x76767331 // "vvs1" VVC subpicture track
+	vvcNSampleEntry uint32 = 0x7676634e // "vvcN" VVC non-VCL track
 	s263SampleEntry uint32 = 0x73323633 // "s263"
 	h263SampleEntry uint32 = 0x48323633 // "H263"
 	s264SampleEntry uint32 = 0x73323634 // "s264"
@@ -134,14 +178,22 @@ var (
 	jpegSampleEntry uint32 = 0x6a706567 // "jpeg"
 	jPEGSampleEntry uint32 = 0x4a504547 // "JPEG"
 	div3SampleEntry uint32 = 0x64697633 // "div3"
-	dIV3SampleEntry uint32 = 0x44495633 // "DIV3"	<- video sample entry
+	dIV3SampleEntry uint32 = 0x44495633 // "DIV3"
+	apchSampleEntry uint32 = 0x61706368 // "apch" Apple ProRes 422 HQ
+	apcnSampleEntry uint32 = 0x6170636e // "apcn" Apple ProRes 422
+	apcsSampleEntry uint32 = 0x61706373 // "apcs" Apple ProRes 422 LT
+	apcoSampleEntry uint32 = 0x6170636f // "apco" Apple ProRes 422 Proxy
+	ap4hSampleEntry uint32 = 0x61703468 // "ap4h" Apple ProRes 4444
+	ap4xSampleEntry uint32 = 0x61703478 // "ap4x" Apple ProRes 4444 XQ	<- video sample entry
 
 	fourCCav1c uint32 = 0x61763143 // "av1C"  -> video codec configuration record
 	fourCCavcC uint32 = 0x61766343 // "avcC"
 	fourCCdvcC uint32 = 0x64766343 // "dvcC"
 	fourCCdvvC uint32 = 0x64767643 // "dvvC"
+	fourCCdvwC uint32 = 0x64767743 // "dvwC"
 	fourCCvpcC uint32 = 0x76706343 // "vpcC"
-	fourCChvcC uint32 = 0x68766343 // "hvcC"  <- video codec configuration record
+	fourCChvcC uint32 = 0x68766343 // "hvcC"
+	fourCCvvcC uint32 = 0x76766343 // "vvcC"  <- video codec configuration record
 
 	flaCSampleEntry uint32 = 0x664c6143 // "fLaC"	audio sample entry ->
 	opusSampleEntry uint32 = 0x4f707573 // "Opus"
@@ -158,12 +210,19 @@ var (
 	dtseSampleEntry uint32 = 0x64747365 // "dtse"
 	dtshSampleEntry uint32 = 0x64747368 // "dtsh"
 	dtslSampleEntry uint32 = 0x6474736c // "dtsl"
+	dtsxSampleEntry uint32 = 0x64747378 // "dtsx" DTS-UHD profile 2, i.e. DTS:X
+	dtsySampleEntry uint32 = 0x64747379 // "dtsy" DTS-UHD profile 3
 	samrSampleEntry uint32 = 0x73616d72 // "samr"
 	sawbSampleEntry uint32 = 0x73617762 // "sawb"
 	sowtSampleEntry uint32 = 0x736f7774 // "sowt"
 	twosSampleEntry uint32 = 0x74776f73 // "twos"
 	alawSampleEntry uint32 = 0x616c6177 // "alaw"
 	ulawSampleEntry uint32 = 0x756c6177 // "ulaw"
+	mha1SampleEntry uint32 = 0x6d686131 // "mha1" MPEG-H 3D Audio
+	mha2SampleEntry uint32 = 0x6d686132 // "mha2" MPEG-H 3D Audio, multi-stream
+	mhm1SampleEntry uint32 = 0x6d686d31 // "mhm1" MPEG-H 3D Audio in MHAS packets
+	mhm2SampleEntry uint32 = 0x6d686d32 // "mhm2" MPEG-H 3D Audio in MHAS packets, multi-stream
+	iamfSampleEntry uint32 = 0x69616d66 // "iamf" Immersive Audio Model and Formats
 	sounSampleEntry uint32 = 0x736f756e // "soun"	<- audio sample entry
 
 	tx3gSampleEntry uint32 = 0x74783367 // "tx3g"	subtitle sample entry ->
@@ -172,14 +231,20 @@ var (
 	TTMLSampleEntry uint32 = 0x54544d4c // "TTML"
 	c608SampleEntry uint32 = 0x63363038 // "c608"	<- subtitle sample entry
 
+	tmcdSampleEntry uint32 = 0x746d6364 // "tmcd"	timecode sample entry of QuickTime
+
 	fourCCesds uint32 = 0x65736473 // "esds" audio sample descriptors ->
 	fourCCdfla uint32 = 0x64664c61 // "dfLa"
 	fourCCdops uint32 = 0x644f7073 // "dOps"
 	fourCCalac uint32 = 0x616C6163 // "alac" - Also used by ALACSampleEntry
 	fourCCddts uint32 = 0x64647473 // "ddts"
+	fourCCudts uint32 = 0x75647473 // "udts"
 	fourCCdac3 uint32 = 0x64616333 // "dac3"
 	fourCCdec3 uint32 = 0x64656333 // "dec3"
 	fourCCdac4 uint32 = 0x64616334 // "dac4"
+	fourCCmhaC uint32 = 0x6d686143 // "mhaC"
+	fourCCmhaP uint32 = 0x6d686150 // "mhaP"
+	fourCCiacb uint32 = 0x69616362 // "iacb"
 	fourCCwave uint32 = 0x77617665 // "wave" - quicktime atom
 	fourCCdmlp uint32 = 0x646D6C70 // "dmlp"  <- audio sample descriptors
 
@@ -195,9 +260,6 @@ var (
 	// fourCCuuid uint32 = 0x75756964 // "uuid"
 	// fourCCmhdr uint32 = 0x6d686472 // "mhdr"
 	// fourCCkeys uint32 = 0x6b657973 // "keys"
-	// fourCCilst uint32 = 0x696c7374 // "ilst"
-	// fourCCdata uint32 = 0x64617461 // "Data"
-	// fourCCname uint32 = 0x6e616d65 // "name"
 	// fourCCitif uint32 = 0x69746966 // "itif"
 	// fourCCudta uint32 = 0x75647461 // "udta"
 
@@ -255,6 +317,8 @@ type boxFtyp struct {
 }
 
 type boxSidx struct {
+	offset                  int64 // absolute position of the "sidx" box in the file
+	size                    int64 // full size of the "sidx" box, header included
 	referenceID             uint32
 	timeScale               uint32
 	earlistPresentationTime uint64
@@ -285,7 +349,8 @@ func (p *boxSidx) String() string {
 }
 
 type boxSsix struct {
-	subSegmentCount uint32 // is ranges' len
+	sidx            *boxSidx // the "sidx" this box pertains to
+	subSegmentCount uint32   // is ranges' len
 	ranges          []struct {
 		rangeCount uint32 // is rangeSize's len
 		rangeSize  []struct {
@@ -360,7 +425,6 @@ type boxTrak struct {
 	// the sum of the durations of all the track’s edits.
 	duration     uint64
 	sampleNumber uint64
-	timeOffset   int64
 
 	timeScale   uint32
 	language    uint16 // ISO-639-2/T language code
@@ -378,10 +442,18 @@ type boxTrak struct {
 	protection []*ProtectedInformation
 
 	edts *boxEdts
+	tref []TrackReference
+	trgr []TrackGroup
+	dref []DataReference
+	// the atoms of QuickTime, nil if none of them exists
+	quickTime *QuickTime
 	// mdia *boxMdia
 
-	audioEntry *audioSampleEntry
-	videoEntry *videoSampleEntry
+	audioEntry    *audioSampleEntry    // the first audio sample entry
+	videoEntry    *videoSampleEntry    // the first video sample entry
+	sampleEntries []*sampleEntry       // all the entries of "stsd" in order
+	spatial       *Spatial             // spherical video V1 of "uuid" box
+	timecodeEntry *TimecodeSampleEntry // the first timecode sample entry
 
 	stts             *boxStts
 	ctts             *boxCtts
@@ -395,10 +467,10 @@ type boxTrak struct {
 	samplePriority   []uint16 // degradation priority of each sample. If existed, len(samplePriority) == sample_count of stsz box
 	sampleDependency *boxSdtp
 
-	subs *boxSubs
+	subs []*boxSubs // one for each flags
 
-	sbgp *boxSbgp
-	sgpd *boxSgpd
+	sbgp []*boxSbgp // one for each grouping type
+	sgpd []*boxSgpd // one for each grouping type
 
 	saio *boxSaio
 	saiz *boxSaiz
@@ -517,6 +589,8 @@ type audioSampleEntry struct {
 	qttfSamplesPerPacket uint32
 	qttfBytesPerPacket   uint32
 	qttfBytesPerFrame    uint32
+	lpcmCodec            lpcmCodecId         // PCM format of "lpcm", "sowt", "twos", "alaw" and "ulaw"
+	channelLayout        *AudioChannelLayout // "chan" of QuickTime, if has
 
 	quickTimeVersion int
 	codec            CodecType
@@ -524,8 +598,8 @@ type audioSampleEntry struct {
 	sampleRate       uint32
 	sampleSize       uint16
 	originalFormat   uint32
-	protectedInfo    ProtectedInformation
-	format           uint32 // need to be specific, now it represent the entryType
+	protectedInfo    *ProtectedInformation // information of enca
+	format           uint32                // need to be specific, now it represent the entryType
 
 	descriptorsRawData map[CodecType][]byte      // raw Data of descriptor
 	decoderDescriptors map[CodecType]interface{} // store the descriptor in specific struct
@@ -558,6 +632,11 @@ type videoSampleEntry struct {
 	horizOffD            uint32
 	vertOffN             uint32
 	vertOffD             uint32
+	// MasteringDisplayColourVolumeBox/SMPTE2086MasteringDisplayMetadataBox and ContentLightLevelBox, if has
+	masteringDisplay *MasteringDisplayColourVolume
+	contentLight     *ContentLightLevel
+	// Stereoscopic3D and SphericalVideoBox, if has
+	spatial *Spatial
 
 	protectedInfo               *ProtectedInformation     // information of encv
 	configurationRecordsRawData map[CodecType][]byte      // raw Data of decoderConfigurationRecord
@@ -572,6 +651,14 @@ func (v *videoSampleEntry) String() string {
 		v.width, v.height, v.hSpacing, v.vSpacing)
 }
 
+// sampleEntry is an entry of "stsd". Only one of audio and video is set, both
+// are nil if the type of the entry isn't supported yet.
+type sampleEntry struct {
+	format uint32 // fourCC of the entry
+	audio  *audioSampleEntry
+	video  *videoSampleEntry
+}
+
 type ProtectedInformation struct {
 	DataFormat             uint32 // coding name fourcc
 	SchemeType             uint32 // 4CC identifying the scheme
@@ -675,12 +762,11 @@ type cencSampleEncryptionInformationGroupEntry struct {
 
 // SampleGroupDescription
 type boxSgpd struct {
-	groupingType                  uint32  // only support "seig" currently
-	defaultLength                 *uint32 // if version == 1
+	groupingType                  uint32
+	defaultLength                 *uint32 // if version == 1, 0 means that the length of each entry is present
 	defaultSampleDescriptionIndex *uint32 // if version >= 2
 	entryCount                    uint32
-	descriptionLength             *uint32                                      // if version ==1 && defaultLength == 0
-	cencGroupEntries              []*cencSampleEncryptionInformationGroupEntry // len(cencGroupEntries) == entryCount
+	entries                       []interface{} // decoded by sampleGroupEntryParsers, see SampleGroup.Entry
 }
 
 type subSampleEncryption struct {
@@ -740,7 +826,7 @@ type boxEdts struct {
 	entryCount   uint32
 	editDuration []uint64 // if Version == 0, uint32
 	mediaTime    []int64  // if Version == 0, int32
-	mediaRate    []float32
+	mediaRate    []float64
 }
 
 type boxStss struct {
@@ -778,10 +864,3 @@ type boxSdtp struct {
 	sampleIsDependedOn  []uint8
 	sampleHasRedundancy []uint8
 }
-
-//
-// type sphatial struct {
-// 	Spherical bool
-// 	Stitched bool
-// 	StitchingSoftware bool
-// }
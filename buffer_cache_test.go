@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"fmt"
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fmp4parser "github.com/garden4hu/fmp4parser-go"
+)
+
+// The command extracts the tracks of an MP4 file to the elementary streams, each track is
+// written to "<name>.track<id>.<format>" of Track.ElementaryStreamFormat:
+//
+//	fmp4extract [-o dir] [-track id] file.mp4
+func main() {
+	output := flag.String("o", ".", "the directory to write the elementary streams")
+	trackID := flag.Uint("track", 0, "the track to extract, 0 means all the tracks")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-o dir] [-track id] file.mp4\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := extract(flag.Arg(0), *output, uint32(*trackID)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// extract writes the tracks of the file to the directory. The tracks which can't be
+// extracted are skipped, unless it's the only track requested.
+func extract(name, dir string, trackID uint32) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	parser := fmp4parser.NewFmp4Parser(f)
+	fmp4parser.SetLogOutput(io.Discard)
+	if err = parser.Parse(); err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	found := false
+	for _, track := range parser.GetTracks() {
+		if trackID != 0 && track.TrackID != trackID {
+			continue
+		}
+		found = true
+		format := track.ElementaryStreamFormat()
+		if format == "" {
+			if trackID != 0 {
+				return fmt.Errorf("track %d: %w", track.TrackID, fmp4parser.ErrUnsupportedExtraction)
+			}
+			fmt.Fprintf(os.Stderr, "track %d (%s) is skipped: %v\n", track.TrackID, track.Codec, fmp4parser.ErrUnsupportedExtraction)
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s.track%d.%s", base, track.TrackID, format))
+		if err = extractTrack(parser, track.TrackID, path); err != nil {
+			return fmt.Errorf("track %d: %w", track.TrackID, err)
+		}
+		fmt.Println(path)
+	}
+	if !found {
+		return fmp4parser.ErrNotFoundTrack
+	}
+	return nil
+}
+
+func extractTrack(parser *fmp4parser.Parser, trackID uint32, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err = parser.ExtractTrack(trackID, w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
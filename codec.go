@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 func getTrackType(box uint32) TrackType {
 	if box == avc1SampleEntry ||
@@ -16,6 +16,11 @@ func getTrackType(box uint32) TrackType {
 		box == vp08SampleEntry ||
 		box == vp09SampleEntry ||
 		box == av01SampleEntry ||
+		box == dav1SampleEntry ||
+		box == vvc1SampleEntry ||
+		box == vvi1SampleEntry ||
+		box == vvs1SampleEntry ||
+		box == vvcNSampleEntry ||
 		box == s263SampleEntry ||
 		box == h263SampleEntry ||
 		box == s264SampleEntry ||
@@ -23,7 +28,13 @@ func getTrackType(box uint32) TrackType {
 		box == jpegSampleEntry ||
 		box == jPEGSampleEntry ||
 		box == div3SampleEntry ||
-		box == dIV3SampleEntry {
+		box == dIV3SampleEntry ||
+		box == apchSampleEntry ||
+		box == apcnSampleEntry ||
+		box == apcsSampleEntry ||
+		box == apcoSampleEntry ||
+		box == ap4hSampleEntry ||
+		box == ap4xSampleEntry {
 		return VideoTrack
 	} else if box == flaCSampleEntry ||
 		box == opusSampleEntry ||
@@ -40,11 +51,19 @@ func getTrackType(box uint32) TrackType {
 		box == dtseSampleEntry ||
 		box == dtshSampleEntry ||
 		box == dtslSampleEntry ||
+		box == dtsxSampleEntry ||
+		box == dtsySampleEntry ||
 		box == samrSampleEntry ||
 		box == sawbSampleEntry ||
 		box == sowtSampleEntry ||
+		box == twosSampleEntry ||
 		box == alawSampleEntry ||
 		box == ulawSampleEntry ||
+		box == mha1SampleEntry ||
+		box == mha2SampleEntry ||
+		box == mhm1SampleEntry ||
+		box == mhm2SampleEntry ||
+		box == iamfSampleEntry ||
 		box == sounSampleEntry {
 		return AudioTrack
 	} else if box == tx3gSampleEntry ||
@@ -53,6 +72,8 @@ func getTrackType(box uint32) TrackType {
 		box == TTMLSampleEntry ||
 		box == c608SampleEntry {
 		return SubtitleTrack
+	} else if box == tmcdSampleEntry {
+		return TimecodeTrack
 	} else {
 		return UnknownTrack
 	}
@@ -119,6 +140,8 @@ const (
 	VideoCodecPNG
 	VideoCodecJPG2000
 	VideoCodecDIRAC
+	VideoCodecVVC
+	VideoCodecProRes
 
 	AudioCodecAAC CodecType = iota + 200
 	AudioCodecMP3
@@ -137,6 +160,9 @@ const (
 	AudioCodecAMRWB
 	AudioCodecFLAC
 	AudioCodecALAC
+	AudioCodecMPEGH // MPEG-H 3D Audio
+	AudioCodecIAMF  // Immersive Audio Model and Formats
+	AudioCodecDTSUHD
 
 	// subtitleCodecVTT
 	// subtitleCodecSSA
@@ -159,6 +185,8 @@ var codecString map[CodecType]string = map[CodecType]string{
 	VideoCodecPNG:         "png",
 	VideoCodecJPG2000:     "jpg2000",
 	VideoCodecDIRAC:       "dirac",
+	VideoCodecVVC:         "vvc",
+	VideoCodecProRes:      "prores",
 
 	AudioCodecAAC:        "aac",
 	AudioCodecMP3:        "mp3",
@@ -177,6 +205,17 @@ var codecString map[CodecType]string = map[CodecType]string{
 	AudioCodecAMRWB:      "amr-wb",
 	AudioCodecFLAC:       "flac",
 	AudioCodecALAC:       "alac",
+	AudioCodecMPEGH:      "mpeg-h 3d audio",
+	AudioCodecIAMF:       "iamf",
+	AudioCodecDTSUHD:     "dts-uhd",
+}
+
+// String returns the human-readable name of the codec.
+func (c CodecType) String() string {
+	if s, ok := codecString[c]; ok {
+		return s
+	}
+	return codecString[CodecUNKNOW]
 }
 
 func getMediaTypeFromObjectType(objectType uint8) CodecType {
@@ -0,0 +1,221 @@
+package fmp4parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CodecString returns the codecs parameter of the track defined by RFC 6381, e.g.
+// "avc1.64001f", "hvc1.2.4.L123.B0", "mp4a.40.2" or "ec-3". It's used by the
+// "codecs" attribute of DASH and the CODECS attribute of HLS.
+// If the codec configuration is unknown, the coding name of the sample entry is returned.
+func (p *Track) CodecString() string {
+	return rfc6381CodecString(p.codingName, p.audioEntry, p.videoEntry)
+}
+
+// rfc6381CodecString returns the RFC 6381 codecs parameter of the sample entry. codingName
+// is the original format of the sample entry, i.e. the data_format of "frma" if the
+// sample entry is encrypted.
+func rfc6381CodecString(codingName uint32, audio *audioSampleEntry, video *videoSampleEntry) string {
+	if codingName == 0 || codingName == encvSampleEntry || codingName == encaSampleEntry {
+		switch {
+		case video != nil:
+			codingName = video.format
+		case audio != nil:
+			codingName = audio.format
+		}
+	}
+	if codingName == 0 {
+		return ""
+	}
+	name := int2String(codingName)
+	switch {
+	case video != nil:
+		if s := video.codecString(codingName); s != "" {
+			return s
+		}
+	case audio != nil:
+		if s := audio.codecString(codingName); s != "" {
+			return s
+		}
+	}
+	return name
+}
+
+func (p *videoSampleEntry) codecString(codingName uint32) string {
+	name := int2String(codingName)
+	switch codingName {
+	case dvavSampleEntry, dva1SampleEntry, dvheSampleEntry, dvh1SampleEntry, dav1SampleEntry:
+		if dvc, ok := p.decoderConfigurationRecords[VideoCodecDolbyVision].(*DvcConfig); ok {
+			return dvc.codecString(name)
+		}
+	}
+	// the configuration records are checked one by one, because the Dolby Vision
+	// configuration and the configuration of the base layer are both present.
+	if avc, ok := p.decoderConfigurationRecords[VideoCodecH264].(*AvcConfig); ok {
+		return avc.codecString(name)
+	}
+	if hevc, ok := p.decoderConfigurationRecords[VideoCodecHEVC].(*HevcConfig); ok {
+		return hevc.codecString(name)
+	}
+	if vvc, ok := p.decoderConfigurationRecords[VideoCodecVVC].(*VvcConfig); ok {
+		return vvc.codecString(name)
+	}
+	if av1c, ok := p.decoderConfigurationRecords[VideoCodecAV1].(*Av1cConfig); ok {
+		return av1c.codecString(name, p)
+	}
+	for _, codec := range []CodecType{VideoCodecVP9, VideoCodecVP8} {
+		if vpc, ok := p.decoderConfigurationRecords[codec].(*VpcConfig); ok {
+			return vpc.codecString(name, p)
+		}
+	}
+	return ""
+}
+
+func (p *audioSampleEntry) codecString(codingName uint32) string {
+	switch p.codec {
+	case AudioCodecAAC, AudioCodecMP3:
+		if esds, ok := p.decoderDescriptors[p.codec].(*EsDescriptor); ok {
+			return esds.codecString()
+		}
+	case AudioCodecOPUS:
+		return "Opus"
+	case AudioCodecFLAC:
+		return "fLaC"
+	case AudioCodecALAC:
+		return "alac"
+	case AudioCodecAC3:
+		return "ac-3"
+	case AudioCodecEAC3:
+		return "ec-3"
+	case AudioCodecAC4:
+		return "ac-4"
+	case AudioCodecMLP:
+		return "mlpa"
+	case AudioCodecMPEGH:
+		// refer to ISO/IEC 23008-3 20.6, e.g. "mhm1.0x0D"
+		name := int2String(codingName)
+		if mha, ok := p.decoderDescriptors[p.codec].(*MhaDescriptor); ok && mha.DecoderSpecificInfo != nil {
+			return fmt.Sprintf("%s.0x%02X", name, mha.ProfileLevelIndication)
+		}
+		return name
+	case AudioCodecIAMF:
+		if iamf, ok := p.decoderDescriptors[p.codec].(*IamfDescriptor); ok {
+			return iamf.codecString()
+		}
+		return "iamf"
+	}
+	if codingName == mp3SampleEntry {
+		return "mp4a.6b"
+	}
+	return ""
+}
+
+// codecString returns "avc1.PPCCLL": profile_idc, constraint flags and level_idc in hex.
+func (p *AvcConfig) codecString(name string) string {
+	return fmt.Sprintf("%s.%02x%02x%02x", name, p.ProfileIndication, p.ProfileCompatibility, p.AvcLevel)
+}
+
+// codecString returns the codecs parameter of HEVC, refer to ISO/IEC 14496-15 Annex E.3:
+// the profile space and profile_idc, the compatibility flags in reverse bit order,
+// the tier and level, and the constraint bytes without the trailing zero bytes.
+func (p *HevcConfig) codecString(name string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('.')
+	if p.GeneralProfileSpace > 0 {
+		b.WriteByte('A' + p.GeneralProfileSpace - 1)
+	}
+	fmt.Fprintf(&b, "%d", p.GeneralProfileIdc)
+	compatibility := uint32(0)
+	for i := 0; i < 32; i++ {
+		compatibility |= (p.GeneralProfileCompatibilityFlags >> i & 1) << (31 - i)
+	}
+	fmt.Fprintf(&b, ".%X", compatibility)
+	if p.GeneralTierFlag == 1 {
+		b.WriteString(".H")
+	} else {
+		b.WriteString(".L")
+	}
+	fmt.Fprintf(&b, "%d", p.GeneralLevelIdc)
+	constraints := make([]byte, 6)
+	for i := range constraints {
+		constraints[i] = byte(p.GeneralConstraintIndicatorFlags >> (40 - 8*i))
+	}
+	n := len(constraints)
+	for n > 0 && constraints[n-1] == 0 {
+		n--
+	}
+	for _, c := range constraints[:n] {
+		fmt.Fprintf(&b, ".%X", c)
+	}
+	return b.String()
+}
+
+// codecString returns the codecs parameter of AV1, refer to https://aomediacodec.github.io/av1-isobmff/#codecsparam.
+// The optional colour fields are present only if the sample entry has "colr" box.
+func (p *Av1cConfig) codecString(name string, v *videoSampleEntry) string {
+	tier := 'M'
+	if p.SeqTier0 == 1 {
+		tier = 'H'
+	}
+	bitDepth := 8
+	if p.HighBitdepth == 1 {
+		bitDepth = 10
+		if p.SeqProfile == 2 && p.TwelveBit == 1 {
+			bitDepth = 12
+		}
+	}
+	s := fmt.Sprintf("%s.%d.%02d%c.%02d", name, p.SeqProfile, p.SeqLevelIdx0, tier, bitDepth)
+	if v == nil || v.colourType != colourTypeNCLX {
+		return s
+	}
+	chromaSamplePosition := uint8(0)
+	if p.ChromaSubsamplingX == 1 && p.ChromaSubsamplingY == 1 {
+		chromaSamplePosition = p.ChromaSamplePosition
+	}
+	fullRange := 0
+	if v.fullRangeFlag {
+		fullRange = 1
+	}
+	return fmt.Sprintf("%s.%d.%d%d%d.%02d.%02d.%02d.%d", s, p.Monochrome,
+		p.ChromaSubsamplingX, p.ChromaSubsamplingY, chromaSamplePosition,
+		v.colorPrimaries, v.transferCharacteristics, v.matrixCoefficients, fullRange)
+}
+
+// codecString returns the codecs parameter of VP8/VP9, refer to https://www.webmproject.org/vp9/mp4/.
+// The colour fields of "colr" are preferred to the ones of "vpcC".
+func (p *VpcConfig) codecString(name string, v *videoSampleEntry) string {
+	primaries, transfer, matrix := uint16(p.ColourPrimaries), uint16(p.TransferCharacteristics), uint16(p.MatrixCoefficients)
+	fullRange := p.VideoFullRangeFlag
+	if v != nil && v.colourType == colourTypeNCLX {
+		primaries, transfer, matrix = v.colorPrimaries, v.transferCharacteristics, v.matrixCoefficients
+		fullRange = 0
+		if v.fullRangeFlag {
+			fullRange = 1
+		}
+	}
+	return fmt.Sprintf("%s.%02d.%02d.%02d.%02d.%02d.%02d.%02d.%02d", name, p.Profile, p.Level, p.BitDepth,
+		p.ChromaSubsampling, primaries, transfer, matrix, fullRange)
+}
+
+// codecString returns "dvh1.PP.LL": Dolby Vision profile and level.
+func (p *DvcConfig) codecString(name string) string {
+	return fmt.Sprintf("%s.%02d.%02d", name, p.DvProfile, p.DvLevel)
+}
+
+// codecString returns "mp4a.OO[.A]": the object type indication in hex and the
+// audio object type for MPEG-4 audio.
+func (p *EsDescriptor) codecString() string {
+	if p.ObjectTypeIndication == 0 {
+		if p.AudioCodec == AudioCodecMP3 {
+			return "mp4a.6b"
+		}
+		return "mp4a.40"
+	}
+	s := fmt.Sprintf("mp4a.%02x", p.ObjectTypeIndication)
+	if p.ObjectTypeIndication == 0x40 && p.AudioObjectType != 0 {
+		s += fmt.Sprintf(".%d", p.AudioObjectType)
+	}
+	return s
+}
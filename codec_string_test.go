@@ -0,0 +1,52 @@
+package fmp4parser
+
+import "testing"
+
+func TestTrack_CodecString(t *testing.T) {
+	video := func(format uint32, records map[CodecType]interface{}) *videoSampleEntry {
+		return &videoSampleEntry{format: format, decoderConfigurationRecords: records}
+	}
+	audio := func(format uint32, codec CodecType, descriptor interface{}) *audioSampleEntry {
+		return &audioSampleEntry{format: format, codec: codec, decoderDescriptors: map[CodecType]interface{}{codec: descriptor}}
+	}
+	av1 := video(av01SampleEntry, map[CodecType]interface{}{
+		VideoCodecAV1: &Av1cConfig{SeqLevelIdx0: 8, HighBitdepth: 1, ChromaSubsamplingX: 1, ChromaSubsamplingY: 1},
+	})
+	av1.colourType = colourTypeNCLX
+	av1.colorPrimaries, av1.transferCharacteristics, av1.matrixCoefficients = 1, 1, 1
+
+	tests := []struct {
+		name  string
+		track Track
+		want  string
+	}{
+		{"avc", Track{videoEntry: video(avc1SampleEntry, map[CodecType]interface{}{
+			VideoCodecH264: &AvcConfig{ProfileIndication: 0x64, AvcLevel: 0x1f}})}, "avc1.64001f"},
+		{"hevc", Track{videoEntry: video(hvc1SampleEntry, map[CodecType]interface{}{
+			VideoCodecHEVC: &HevcConfig{GeneralProfileIdc: 2, GeneralProfileCompatibilityFlags: 0x20000000,
+				GeneralLevelIdc: 123, GeneralConstraintIndicatorFlags: 0xB00000000000}})}, "hvc1.2.4.L123.B0"},
+		{"encrypted hevc", Track{codingName: hev1SampleEntry, videoEntry: video(encvSampleEntry, map[CodecType]interface{}{
+			VideoCodecHEVC: &HevcConfig{GeneralProfileSpace: 1, GeneralTierFlag: 1, GeneralProfileIdc: 1,
+				GeneralProfileCompatibilityFlags: 0x60000000, GeneralLevelIdc: 150, GeneralConstraintIndicatorFlags: 0x900000000000}})}, "hev1.A1.6.H150.90"},
+		{"av1", Track{videoEntry: av1}, "av01.0.08M.10.0.110.01.01.01.0"},
+		{"vp9", Track{videoEntry: video(vp09SampleEntry, map[CodecType]interface{}{
+			VideoCodecVP9: &VpcConfig{Profile: 2, Level: 10, BitDepth: 10, ChromaSubsampling: 1, ColourPrimaries: 1,
+				TransferCharacteristics: 1, MatrixCoefficients: 1}})}, "vp09.02.10.10.01.01.01.01.00"},
+		{"dolby vision", Track{videoEntry: video(dvh1SampleEntry, map[CodecType]interface{}{
+			VideoCodecHEVC:        &HevcConfig{GeneralProfileIdc: 2},
+			VideoCodecDolbyVision: &DvcConfig{DvProfile: 5, DvLevel: 6}})}, "dvh1.05.06"},
+		{"aac", Track{audioEntry: audio(mp4aSampleEntry, AudioCodecAAC, &EsDescriptor{ObjectTypeIndication: 0x40, AudioObjectType: 2})}, "mp4a.40.2"},
+		{"mp3", Track{audioEntry: audio(mp4aSampleEntry, AudioCodecMP3, &EsDescriptor{ObjectTypeIndication: 0x6b})}, "mp4a.6b"},
+		{"eac3", Track{audioEntry: audio(ec3SampleEntry, AudioCodecEAC3, &Ac3Descriptor{})}, "ec-3"},
+		{"opus", Track{audioEntry: audio(opusSampleEntry, AudioCodecOPUS, &OpusDescriptor{})}, "Opus"},
+		{"flac", Track{audioEntry: audio(flaCSampleEntry, AudioCodecFLAC, &FlacDescriptor{})}, "fLaC"},
+		{"unknown", Track{videoEntry: video(mp4vSampleEntry, nil)}, "mp4v"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.track.CodecString(); got != tt.want {
+				t.Errorf("CodecString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import "fmt"
 
@@ -24,6 +24,8 @@ type MovieInfo struct {
 	pssh []*PSSH    // 0 or more
 	mvex *boxMvex
 
+	iTunSMPB *iTunSMPB // gapless information of iTunes
+
 	// For 'moof'
 	movieHeader    *MovieInfo // The pointer of parsed 'moov' if this struct is 'moof'
 	sequenceNumber uint32     // sequence number of fragment
@@ -47,8 +49,8 @@ type trackFragment struct {
 
 	trun []*boxTrun
 
-	sgpd *boxSgpd
-	sbgp *boxSbgp
+	sgpd []*boxSgpd // one for each grouping type
+	sbgp []*boxSbgp // one for each grouping type
 
 	subs []*boxSubs
 
@@ -61,6 +63,9 @@ type trackFragment struct {
 }
 
 type movieFragment struct {
+	offset         int64 // absolute position of "moof"
+	size           int64 // full size of "moof"
+	segmentOffset  int64 // start position of the segment, it's the position of "styp" if exists
 	sequenceNumber uint32
 	fragment       []*trackFragment
 	movie          *MovieInfo // overall profile
@@ -102,10 +107,12 @@ func (p *MovieInfo) String() string {
 // EsDescriptor ElementaryStreamDescriptor
 type EsDescriptor struct {
 	AudioCodec              CodecType
+	ObjectTypeIndication    uint8 // from DecoderConfigDescriptor, e.g. 0x40 for MPEG-4 audio
 	AudioObjectType         int
 	ExtendedAudioObjectType int
 	SampleRate              uint32
 	ChannelCount            uint16
+	AudioSpecificConfig     *AudioSpecificConfig // for MPEG-4 audio
 	DecoderSpecificInfo     []byte
 }
 
@@ -146,18 +153,45 @@ type FlacDescriptor struct {
 	DecoderSpecificInfo []byte
 }
 
-// Ac3Descriptor Dolby AC-3/E-AC-3 Descriptor
+// Ac3Descriptor Dolby AC-3/E-AC-3 Descriptor. The fields of E-AC-3 are the ones of the
+// independent substream 0, i.e. the main program.
 type Ac3Descriptor struct {
 	Fscod        uint8 // sampling frequency code
 	Bsid         uint8 // Bit Stream Information
+	Bsmod        uint8 // bit stream mode
 	Acmod        uint8 // audio coding mode
+	Lfeon        uint8 // 1 if the LFE channel is present
+	BitRateCode  uint8 // only for AC-3
 	SampleRate   uint32
-	ChannelCount uint16
+	ChannelCount uint16 // including the LFE and the channels of the dependent substreams
+
+	// only for E-AC-3
+	DataRate        uint16          // in kbit/s
+	Substreams      []Eac3Substream // the independent substreams
+	Atmos           bool            // flag_ec3_extension_type_a, the Joint Object Coding of Dolby Atmos is present
+	ComplexityIndex uint8           // complexity_index_type_a, the number of the objects of Joint Object Coding
+}
+
+// Eac3Substream is an independent substream of E-AC-3 and its dependent substreams.
+type Eac3Substream struct {
+	Fscod     uint8
+	Bsid      uint8
+	Asvc      uint8 // 1 if it's an associated service
+	Bsmod     uint8
+	Acmod     uint8
+	Lfeon     uint8
+	NumDepSub uint8  // the number of the dependent substreams
+	ChanLoc   uint16 // the channel locations of the dependent substreams in addition to Acmod, 0 if none
 }
 
 // Ac4Descriptor Ac4 Descriptor
 type Ac4Descriptor struct {
+	DsiVersion          uint8 // ac4_dsi_version, the fields below SampleRate are parsed only if it's 1
+	BitstreamVersion    uint8
 	SampleRate          uint32
+	FrameRateIndex      uint8
+	ProgramID           uint16 // short_program_id, 0 if not present
+	Presentations       []Ac4Presentation
 	DecoderSpecificInfo []byte
 }
 
@@ -181,6 +215,33 @@ type DtsDescriptor struct {
 	DecoderSpecificInfo []byte
 }
 
+// DtsUhdDescriptor DTS-UHD Descriptor of "udts"
+type DtsUhdDescriptor struct {
+	DecoderProfile        uint8  // DecoderProfileCode + 2
+	FrameDuration         uint32 // in samples of BaseSamplingFrequency
+	MaxPayload            uint32 // the maximum size of a frame in bytes, 0 if reserved
+	NumPresentations      uint8
+	ChannelMask           uint32 // the loudspeakers of the default presentation, refer to ETSI TS 103 491 Table C-4
+	BaseSamplingFrequency uint32 // 44100 or 48000
+	SampleRate            uint32 // BaseSamplingFrequency * (1 << SampleRateMod)
+	RepresentationType    uint8
+	StreamIndex           uint8
+	PresentationIDTags    [][]byte // the 16-byte PresentationIDTag of each presentation, nil if not present
+	ExpansionBox          []byte   // the box which follows, nil if not present
+	DecoderSpecificInfo   []byte
+}
+
+// MhaDescriptor MPEG-H 3D Audio Descriptor of "mhaC" and "mhaP"
+type MhaDescriptor struct {
+	ConfigurationVersion   uint8
+	ProfileLevelIndication uint8  // mpegh3daProfileLevelIndication
+	ReferenceChannelLayout uint8  // ChannelConfiguration of ISO/IEC 23091-3, 0 if the layout isn't a CICP one
+	ChannelCount           uint16 // from ReferenceChannelLayout, 0 if unknown
+	Mpegh3daConfig         []byte
+	CompatibleSets         []uint8 // compatibleSetIndication of "mhaP", the profiles and levels compatible with
+	DecoderSpecificInfo    []byte
+}
+
 // MlpaDescriptor Dolby TrueHD Mlpa Descriptor
 type MlpaDescriptor struct {
 	FormatInfo          uint32
@@ -201,6 +262,9 @@ type AvcConfig struct {
 	NumPPS               uint8
 	ListPPS              [][]byte
 	DecoderSpecificInfo  []byte // need by decoder
+
+	SequenceParameterSets []*SequenceParameterSet // parsed from ListSPS
+	PictureParameterSets  []*PictureParameterSet  // parsed from ListPPS
 }
 
 type NalUnitInfo struct {
@@ -231,6 +295,50 @@ type HevcConfig struct {
 	NumOfArrays                      uint8
 	NalUnitArrays                    []NalUnitInfo
 	DecoderSpecificInfo              []byte // need by decoder
+
+	VideoParameterSets    []*VideoParameterSet    // parsed from NalUnitArrays
+	SequenceParameterSets []*SequenceParameterSet // parsed from NalUnitArrays
+	PictureParameterSets  []*PictureParameterSet  // parsed from NalUnitArrays
+}
+
+// VvcPTLRecord is the profile, tier and level of VVC in vvcC.
+type VvcPTLRecord struct {
+	NumBytesConstraintInfo uint8 // 6 bits lsb
+	GeneralProfileIdc      uint8 // 7 bits lsb
+	GeneralTierFlag        uint8 // 1 bit lsb
+	GeneralLevelIdc        uint8
+	// NumBytesConstraintInfo bytes of ptl_frame_only_constraint_flag, ptl_multi_layer_enabled_flag
+	// and general_constraint_info()
+	GeneralConstraintInfo []byte
+	SublayerLevelIdc      []uint8 // sublayer_level_idc of the sub-layers 0 ~ NumSublayers-2, 0 if not present
+	SubProfileIdc         []uint32
+}
+
+// PtlFrameOnlyConstraintFlag returns ptl_frame_only_constraint_flag.
+func (p *VvcPTLRecord) PtlFrameOnlyConstraintFlag() bool {
+	return len(p.GeneralConstraintInfo) > 0 && p.GeneralConstraintInfo[0]&0x80 != 0
+}
+
+// PtlMultiLayerEnabledFlag returns ptl_multi_layer_enabled_flag.
+func (p *VvcPTLRecord) PtlMultiLayerEnabledFlag() bool {
+	return len(p.GeneralConstraintInfo) > 0 && p.GeneralConstraintInfo[0]&0x40 != 0
+}
+
+type VvcConfig struct {
+	LengthSizeMinusOne  uint8  // 2 bits lsb
+	PtlPresentFlag      uint8  // 1 bit lsb
+	OlsIdx              uint16 // 9 bits lsb
+	NumSublayers        uint8  // 3 bits lsb
+	ConstantFrameRate   uint8  // 2 bits lsb
+	ChromaFormatIdc     uint8  // 2 bits lsb
+	BitDepthMinus8      uint8  // 3 bits lsb
+	NativePTL           VvcPTLRecord
+	MaxPictureWidth     uint16
+	MaxPictureHeight    uint16
+	AvgFrameRate        uint16
+	NumOfArrays         uint8
+	NalUnitArrays       []NalUnitInfo // OPI, DCI, VPS, SPS, PPS, APS and SEI NAL units
+	DecoderSpecificInfo []byte        // need by decoder
 }
 
 type Av1cConfig struct {
@@ -246,6 +354,10 @@ type Av1cConfig struct {
 	InitialPresentationDelayPresent  uint8  // 1 bit lsb
 	InitialPresentationDelayMinusOne uint8  // 4bits lsb
 	DecoderSpecificInfo              []byte // need by decoder
+
+	ConfigOBUs     []Av1OBU
+	SequenceHeader *Av1SequenceHeader // parsed from ConfigOBUs, nil if not present
+	Metadata       []*Av1Metadata     // parsed from ConfigOBUs
 }
 
 type VpcConfig struct {
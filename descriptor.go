@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"errors"
@@ -51,6 +51,7 @@ func (p *EsDescriptor) findDescriptor(r *atomReader) error {
 	// Start of the DecoderConfigDescriptor (defined in 14496-1)
 	if tag == decoderConfigTag {
 		objectProfile := r.ReadUnsignedByte()
+		p.ObjectTypeIndication = objectProfile
 		p.AudioCodec = getMediaTypeFromObjectType(objectProfile)
 		_ = r.Move(12)
 	}
@@ -58,166 +59,18 @@ func (p *EsDescriptor) findDescriptor(r *atomReader) error {
 	if tag == decoderSpecificTag {
 		p.DecoderSpecificInfo = make([]byte, size)
 		_, _ = r.ReadBytes(p.DecoderSpecificInfo)
-		// For AAC
-		frequencyTable := [13]uint32{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
-		_ = r.Move(-size)
-		b := make([]byte, size)
-		_, _ = r.ReadBytes(b)
-		br := newBitReaderFromSlice(b)
-		getAudioObjectType := func() int {
-			audioObjectType := br.ReadBitsLE32(5)
-			if audioObjectType == 31 {
-				// https://github.com/FFmpeg/FFmpeg/blob/a0ac49e38ee1d1011c394d7be67d0f08b2281526/libavcodec/mpeg4audio.h#L102
-				audioObjectTypeExt := br.ReadBitsLE32(6)
-				audioObjectType = 32 + audioObjectTypeExt
-			}
-			return int(audioObjectType)
-		}
-		audioObjectType := getAudioObjectType()
-		frequencyIndex := br.ReadBitsLE32(4)
-		frequency := func() uint32 {
-			if frequencyIndex == 0x0F {
-				return br.ReadBitsLE32(24)
-			} else {
-				if frequencyIndex < 13 {
-					return frequencyTable[frequencyIndex]
-				}
-				return 0
-			}
-		}()
-		channelConfiguration := br.ReadBitsLE32(4)
-		extendedAudioObjectType := 0
-		// audio object types: https://github.com/FFmpeg/FFmpeg/blob/b559a5882f54f6ab46b2d058b8526fae6b00ad0f/libavcodec/mpeg4audio.h#L87
-		// 5 : Spectral Band Replication ;  29 : Parametric Stereo
-		if audioObjectType == 5 || audioObjectType == 29 {
-			extendedAudioObjectType = 5
-			extendedFrequencyIndex := br.ReadBitsLE32(4)
-			extendedFrequency := func() uint32 {
-				if extendedFrequencyIndex == 0x0F { // FREQUENCY_INDEX_ARBITRARY
-					return br.ReadBitsLE32(24)
-				} else {
-					if extendedFrequencyIndex < 13 {
-						return frequencyTable[extendedFrequencyIndex]
-					}
-					return 0
-				}
-			}()
-			frequency = extendedFrequency // Use the extendedFrequency.
-			audioObjectType := getAudioObjectType()
-			extendedChannelConfiguration := uint32(0)
-			if audioObjectType == 22 {
-				extendedChannelConfiguration = br.ReadBitsLE32(4)
-			} else {
-				extendedChannelConfiguration = channelConfiguration
-			}
-			channelConfiguration = extendedChannelConfiguration // use the extendedChannelConfiguration
-		}
-		gASpecificConfig := map[int]int{
-			1:  0,
-			2:  0,
-			3:  0,
-			4:  0,
-			6:  0,
-			7:  0,
-			17: 0,
-			19: 0,
-			20: 0,
-			21: 0,
-			22: 0,
-			23: 0,
+		if p.AudioCodec != AudioCodecAAC {
+			return nil
 		}
-		if _, ok := gASpecificConfig[audioObjectType]; !ok {
-			return errors.New("unsupported audio object type")
+		asc, err := parseAudioSpecificConfig(p.DecoderSpecificInfo)
+		if err != nil {
+			return err
 		}
-		sampleFrequencyValue := func() uint32 {
-			if frequency > 92016 {
-				return 96000
-			} else if frequency > 75131 {
-				return 88200
-			} else if frequency > 55425 {
-				return 64000
-			} else if frequency > 46008 {
-				return 48000
-			} else if frequency > 37565 {
-				return 44100
-			} else if frequency > 27712 {
-				return 32000
-			} else if frequency > 23003 {
-				return 24000
-			} else if frequency > 18782 {
-				return 22050
-			} else if frequency > 13885 {
-				return 16000
-			} else if frequency > 11501 {
-				return 12000
-			} else if frequency > 9390 {
-				return 11025
-			} else {
-				return 8000
-			}
-		}()
-		_ = br.ReadBitsLE32(1) // frameLengthFlag
-		dependOnCoreOrder := br.ReadBitsLE32(1)
-		if dependOnCoreOrder != 0 {
-			_ = br.ReadBitsLE32(14) // codeCoderDelay
-		}
-		_ = br.ReadBool() // extensionFlag
-		channelCount := func() uint16 {
-			if channelConfiguration == 0 {
-				_ = br.ReadBitsLE32(4) // element_instance_tag
-				_ = br.ReadBitsLE32(2) // object_type
-				_ = br.ReadBitsLE32(4) // sampling_frequency_index
-				numFrontChannel := br.ReadBitsLE32(4)
-				numSideChannel := br.ReadBitsLE32(4)
-				numBackChannel := br.ReadBitsLE32(4)
-				numLfeChannel := br.ReadBitsLE32(2)
-				_ = br.ReadBitsLE32(3) // num_assoc_data
-				_ = br.ReadBitsLE32(4) // num_valid_cc
-				monoMixdownPresent := br.ReadBool()
-				if monoMixdownPresent {
-					_ = br.ReadBitsLE32(4) // mono_mixdown_element_number
-				}
-				stereoMixdownPresent := br.ReadBool()
-				if stereoMixdownPresent {
-					_ = br.ReadBitsLE32(4) // stereo_mixdown_element_number
-				}
-				matrixMixdownIdxPresent := br.ReadBool()
-				if matrixMixdownIdxPresent {
-					_ = br.ReadBitsLE32(2) // matrix_mixdown_idx
-					_ = br.ReadBitsLE32(1) // pseudo_surround_enable
-				}
-				channelCounts := uint16(0)
-				readSurroundChannelCount := func(n uint32) uint16 {
-					count := uint16(0)
-					for i := 0; i < int(n); i++ {
-						if ok := br.ReadBool(); ok {
-							count += 2
-						} else {
-							count += 1
-						}
-						_ = br.ReadBitsLE32(4)
-					}
-					return count
-				}
-				channelCounts += readSurroundChannelCount(numFrontChannel)
-				channelCounts += readSurroundChannelCount(numSideChannel)
-				channelCounts += readSurroundChannelCount(numBackChannel)
-				channelCounts += readSurroundChannelCount(numLfeChannel)
-				return channelCounts
-			} else if channelConfiguration >= 1 && channelConfiguration <= 7 {
-				return uint16(int(channelConfiguration))
-			} else if channelConfiguration == 11 {
-				return 7 // 6.1 Amendment 4 of the AAC standard in 2013
-			} else if channelConfiguration == 12 || channelConfiguration == 14 {
-				return 8 // 7.1 (a/d) of ITU BS.2159
-			} else {
-				return 0xFFFF
-			}
-		}()
-		p.AudioObjectType = audioObjectType
-		p.ExtendedAudioObjectType = extendedAudioObjectType
-		p.SampleRate = sampleFrequencyValue
-		p.ChannelCount = channelCount
+		p.AudioSpecificConfig = asc
+		p.AudioObjectType = asc.SignalledObjectType
+		p.ExtendedAudioObjectType = asc.ExtensionAudioObjectType
+		p.SampleRate = asc.SampleRate
+		p.ChannelCount = asc.ChannelCount
 	}
 	return nil
 }
@@ -270,13 +123,6 @@ func (p *FlacDescriptor) parseDescriptor(r *atomReader) error {
 	if length <= 42 {
 		return fmt.Errorf("%w : FlacDescriptor", ErrInvalidAtomSize)
 	}
-	_ = copy(p.DecoderSpecificInfo, "flaC")
-	var err error = nil
-	flaCData := make([]byte, length)
-	if err = r.Peek(flaCData); err != nil {
-		return fmt.Errorf("%w : FlacDescriptor.DecoderSpecificInfo", err)
-	}
-	_ = copy(p.DecoderSpecificInfo[4:], flaCData)
 	version, flags := r.ReadVersionFlags()
 	if version != 0 {
 		return errors.New("unknown dfLa (FLAC) Version, unsupported")
@@ -285,6 +131,12 @@ func (p *FlacDescriptor) parseDescriptor(r *atomReader) error {
 		return errors.New("no-zero dfLa (FLAC) flags, unsupported")
 	}
 	length -= 4
+	// the "fLaC" stream marker followed by the metadata blocks
+	p.DecoderSpecificInfo = make([]byte, 4+length)
+	_ = copy(p.DecoderSpecificInfo, "fLaC")
+	if err := r.Peek(p.DecoderSpecificInfo[4:]); err != nil {
+		return fmt.Errorf("%w : FlacDescriptor.DecoderSpecificInfo", err)
+	}
 	// refer to https://github.com/xiph/flac/blob/master/doc/isoflac.txt
 	metadataFraming := r.Read4()
 	blockType := metadataFraming >> 24 & 0x7F
@@ -356,37 +208,104 @@ ac-3 bitstream storage in the ISO BMFF :ac3specificBox
 */
 func (p *Ac3Descriptor) parseDescriptor(r *atomReader) error {
 	length := r.a.bodySize
-	if length < 2 {
+	if length < 3 {
 		return errors.New("content is too short for ac3 descriptor")
 	}
-	tmpByte := r.ReadUnsignedByte()
-	p.Fscod = (tmpByte & 0xC0) >> 6
-	sampleRateCodes := [3]uint32{48000, 44100, 32000}
-	p.SampleRate = sampleRateCodes[p.Fscod]
-	p.Bsid = tmpByte >> 1 & 0x1F
-	channelCountsByAcmod := [...]uint16{2, 1, 2, 3, 3, 4, 4, 5}
-	// only get the channel count form the Acmod , omit the bsmod, lfeon, bit_rate_code.
-	p.ChannelCount = channelCountsByAcmod[(r.ReadUnsignedByte()&0x38)>>3]
+	bitsBuff := make([]byte, 3)
+	_, _ = r.ReadBytes(bitsBuff)
+	br := newBitReaderFromSlice(bitsBuff)
+	p.Fscod = br.ReadBitsLE8(2)
+	p.Bsid = br.ReadBitsLE8(5)
+	p.Bsmod = br.ReadBitsLE8(3)
+	p.Acmod = br.ReadBitsLE8(3)
+	p.Lfeon = br.ReadBitsLE8(1)
+	p.BitRateCode = br.ReadBitsLE8(5)
+	p.SampleRate = ac3SampleRate(p.Fscod)
+	p.ChannelCount = ac3ChannelCounts[p.Acmod] + uint16(p.Lfeon)
 	return nil
 }
 
-/* e-ac-3 bitstream storage in the ISO BMFF :e-ac3specificBox
-{
-	unsigned int(13) data_rate;
-	unsigned int(3) num_ind_sub;
-	unsigned int(2) Fscod;
-	unsigned int(5) Bsid;
-	unsigned int(5) bsmod;
-	unsigned int(3) Acmod;
-	unsigned int(1) lfeon;
-	unsigned int(3) reserved = 0;
-	unsigned int(4) num_dep_sub;
-	if (num_dep_sub > 0)
-		unsigned int(9) chan_loc;
-	else
-		unsigned int(1) reserved = 0;
-}
+/*
+e-ac-3 bitstream storage in the ISO BMFF :e-ac3specificBox, refer to ETSI TS 102 366 F.6
+
+	{
+		unsigned int(13) data_rate;
+		unsigned int(3) num_ind_sub;
+		for (i = 0; i < num_ind_sub + 1; i++) {
+			unsigned int(2) fscod;
+			unsigned int(5) bsid;
+			unsigned int(1) reserved = 0;
+			unsigned int(1) asvc;
+			unsigned int(3) bsmod;
+			unsigned int(3) acmod;
+			unsigned int(1) lfeon;
+			unsigned int(3) reserved = 0;
+			unsigned int(4) num_dep_sub;
+			if (num_dep_sub > 0)
+				unsigned int(9) chan_loc;
+			else
+				unsigned int(1) reserved = 0;
+		}
+		// the extension of Dolby Atmos, refer to ETSI TS 103 420 C.3
+		unsigned int(7) reserved = 0;
+		unsigned int(1) flag_ec3_extension_type_a;
+		if (flag_ec3_extension_type_a)
+			unsigned int(8) complexity_index_type_a;
+	}
 */
+func (p *Ac3Descriptor) parseEac3Descriptor(r *atomReader) error {
+	if r.Len() < 5 {
+		return errors.New("content is too short for e-ac3 descriptor")
+	}
+	bitsBuff := make([]byte, r.Len())
+	_, _ = r.ReadBytes(bitsBuff)
+	br := newBitReaderFromSlice(bitsBuff)
+	p.DataRate = br.ReadBitsLE16(13)
+	numIndSub := int(br.ReadBitsLE8(3)) + 1
+	bits := 16
+	for i := 0; i < numIndSub && br.Err() == nil; i++ {
+		var s Eac3Substream
+		s.Fscod = br.ReadBitsLE8(2)
+		s.Bsid = br.ReadBitsLE8(5)
+		_ = br.ReadBitsLE8(1)
+		s.Asvc = br.ReadBitsLE8(1)
+		s.Bsmod = br.ReadBitsLE8(3)
+		s.Acmod = br.ReadBitsLE8(3)
+		s.Lfeon = br.ReadBitsLE8(1)
+		_ = br.ReadBitsLE8(3)
+		s.NumDepSub = br.ReadBitsLE8(4)
+		if s.NumDepSub > 0 {
+			s.ChanLoc = br.ReadBitsLE16(9)
+			bits += 32
+		} else {
+			_ = br.ReadBitsLE8(1)
+			bits += 24
+		}
+		p.Substreams = append(p.Substreams, s)
+	}
+	if br.Err() != nil || len(p.Substreams) == 0 {
+		return fmt.Errorf("%w : Ac3Descriptor of e-ac3", ErrIncompleteBox)
+	}
+	if len(bitsBuff)*8-bits >= 8 {
+		_ = br.ReadBitsLE8(7)
+		if p.Atmos = br.ReadBool(); p.Atmos {
+			p.ComplexityIndex = br.ReadBitsLE8(8)
+		}
+	}
+	main := &p.Substreams[0]
+	p.Fscod, p.Bsid, p.Bsmod, p.Acmod, p.Lfeon = main.Fscod, main.Bsid, main.Bsmod, main.Acmod, main.Lfeon
+	p.SampleRate = ac3SampleRate(p.Fscod)
+	p.ChannelCount = main.ChannelCount()
+	return nil
+}
+
+// ac3SampleRate returns the sample rate of fscod, 0 for the reduced sample rates.
+func ac3SampleRate(fscod uint8) uint32 {
+	if fscod < 3 {
+		return [3]uint32{48000, 44100, 32000}[fscod]
+	}
+	return 0
+}
 
 // parseConfig Dolby AC-4. refer to: ETSI TS 103 190-2 V1.1.1 (2015-09) “Digital Audio Compression (AC‐4) Standard” Annex E
 func (p *Ac4Descriptor) parseDescriptor(r *atomReader) error {
@@ -395,14 +314,10 @@ func (p *Ac4Descriptor) parseDescriptor(r *atomReader) error {
 	if err = r.Peek(p.DecoderSpecificInfo); err != nil {
 		return fmt.Errorf("%w : Ac4Descriptor.DecoderSpecificInfo", err)
 	}
-	_ = r.Move(1)
-	fsIndex := r.ReadUnsignedByte() >> 5 & 0x1
-	if fsIndex == 0 { // ETSI TS 103 190-1 [1], clause 4.3.3.2.5
-		p.SampleRate = 44100
-	} else {
-		p.SampleRate = 48000
+	if len(p.DecoderSpecificInfo) < 2 {
+		return fmt.Errorf("%w : Ac4Descriptor", ErrInvalidAtomSize)
 	}
-	return nil
+	return p.parseDsi(p.DecoderSpecificInfo)
 }
 
 // refer to: IMPLEMENTATION OF DTS AUDIO IN MEDIA FILES BASED ON ISO/IEC 14496 Effective Date: February 2014
@@ -440,6 +355,74 @@ func (p *DtsDescriptor) parseDescriptor(r *atomReader) error {
 	return nil
 }
 
+/*
+parseDescriptor parses udts, refer to ETSI TS 103 491 V1.2.1 Annex B
+
+	class DTSUHDSpecificBox extends Box('udts') {
+		unsigned int(6) DecoderProfileCode;
+		unsigned int(2) FrameDurationCode;
+		unsigned int(3) MaxPayloadCode;
+		unsigned int(5) NumPresentationsCode;
+		unsigned int(32) ChannelMask;
+		unsigned int(1) BaseSamplingFrequencyCode;
+		unsigned int(2) SampleRateMod;
+		unsigned int(3) RepresentationType;
+		unsigned int(3) StreamIndex;
+		unsigned int(1) ExpansionBoxPresent;
+		unsigned int(1) IDTagPresent[NumPresentationsCode + 1];
+		bit(0..7) zero_padding;
+		for (i = 0; i <= NumPresentationsCode; i++)
+			if (IDTagPresent[i])
+				unsigned int(8) PresentationIDTag[16];
+		if (ExpansionBoxPresent)
+			ExpansionBox();
+	}
+*/
+func (p *DtsUhdDescriptor) parseDescriptor(r *atomReader) error {
+	if r.Size() < 8 {
+		return fmt.Errorf("%w : DtsUhdDescriptor", ErrInvalidAtomSize)
+	}
+	p.DecoderSpecificInfo = make([]byte, r.Size())
+	_ = r.Peek(p.DecoderSpecificInfo)
+	data := p.DecoderSpecificInfo
+	br := newBitReaderFromSlice(data)
+	p.DecoderProfile = br.ReadBitsLE8(6) + 2
+	p.FrameDuration = 512 << br.ReadBitsLE8(2)
+	if maxPayloadCode := br.ReadBitsLE8(3); maxPayloadCode < 7 {
+		p.MaxPayload = 2048 << maxPayloadCode
+	}
+	p.NumPresentations = br.ReadBitsLE8(5) + 1
+	p.ChannelMask = br.ReadBitsLE32(32)
+	p.BaseSamplingFrequency = 44100
+	if br.ReadBool() {
+		p.BaseSamplingFrequency = 48000
+	}
+	p.SampleRate = p.BaseSamplingFrequency << br.ReadBitsLE8(2)
+	p.RepresentationType = br.ReadBitsLE8(3)
+	p.StreamIndex = br.ReadBitsLE8(3)
+	expansionBoxPresent := br.ReadBool()
+	idTagPresent := make([]bool, p.NumPresentations)
+	for i := range idTagPresent {
+		idTagPresent[i] = br.ReadBool()
+	}
+	pos := (58 + len(idTagPresent) + 7) / 8 // zero_padding
+	for _, present := range idTagPresent {
+		var tag []byte
+		if present {
+			if pos+16 > len(data) {
+				return fmt.Errorf("%w : DtsUhdDescriptor.PresentationIDTag", ErrIncompleteBox)
+			}
+			tag = data[pos : pos+16]
+			pos += 16
+		}
+		p.PresentationIDTags = append(p.PresentationIDTags, tag)
+	}
+	if expansionBoxPresent && pos < len(data) {
+		p.ExpansionBox = data[pos:]
+	}
+	return nil
+}
+
 /*
 	MLPSpecificBox refer to: Dolby TrueHD (MLP) bitstreams within the ISO base media file format
 
@@ -459,6 +442,62 @@ func (p *MlpaDescriptor) parseDescriptor(r *atomReader) {
 	p.PeakDataRate = r.Read2() >> 1
 }
 
+// the number of channels of ChannelConfiguration of ISO/IEC 23091-3, indexed by the value
+var cicpChannelCounts = [...]uint16{0, 1, 2, 3, 4, 5, 6, 8, 2, 3, 4, 7, 8, 24, 8, 12, 10, 12, 14, 12, 14}
+
+/*
+parseDescriptor parses mhaC, refer to ISO/IEC 23008-3 20.5.2
+
+	class MHADecoderConfigurationRecord() {
+		unsigned int(8) configurationVersion = 1;
+		unsigned int(8) mpegh3daProfileLevelIndication;
+		unsigned int(8) referenceChannelLayout;
+		unsigned int(16) mpegh3daConfigLength;
+		bit(8*mpegh3daConfigLength) mpegh3daConfig();
+	}
+*/
+func (p *MhaDescriptor) parseDescriptor(r *atomReader) error {
+	if r.Size() < 5 {
+		return fmt.Errorf("%w : MhaDescriptor", ErrInvalidAtomSize)
+	}
+	p.DecoderSpecificInfo = make([]byte, r.Size())
+	_ = r.Peek(p.DecoderSpecificInfo)
+	p.ConfigurationVersion = r.ReadUnsignedByte()
+	p.ProfileLevelIndication = r.ReadUnsignedByte()
+	p.ReferenceChannelLayout = r.ReadUnsignedByte()
+	if int(p.ReferenceChannelLayout) < len(cicpChannelCounts) {
+		p.ChannelCount = cicpChannelCounts[p.ReferenceChannelLayout]
+	}
+	length := int(r.Read2())
+	if length > r.Len() {
+		return fmt.Errorf("%w : MhaDescriptor.mpegh3daConfig", ErrIncompleteBox)
+	}
+	p.Mpegh3daConfig = make([]byte, length)
+	_, _ = r.ReadBytes(p.Mpegh3daConfig)
+	return nil
+}
+
+/*
+parseCompatibleSets parses mhaP, refer to ISO/IEC 23008-3 20.5.4
+
+	class MHAProfileAndLevelCompatibilitySetBox() extends Box('mhaP') {
+		unsigned int(8) numCompatibleSets;
+		for (i = 0; i < numCompatibleSets; i++)
+			unsigned int(8) CompatibleSetIndication;
+	}
+*/
+func (p *MhaDescriptor) parseCompatibleSets(r *atomReader) {
+	if r.Len() < 1 {
+		return
+	}
+	num := int(r.ReadUnsignedByte())
+	if num > r.Len() {
+		num = r.Len()
+	}
+	p.CompatibleSets = make([]uint8, num)
+	_, _ = r.ReadBytes(p.CompatibleSets)
+}
+
 /*
  parseConfig AVC file format. refer to: ISO/IEC 14496-15, 5.3.3.1.2
 aligned(8) class AVCDecoderConfigurationRecord {
@@ -519,7 +558,7 @@ func (p *AvcConfig) parseConfig(r *atomReader) error {
 	for i := uint8(0); i < numOfSequenceParameterSets; i++ {
 		sps := make([]byte, r.Read2())
 		_, _ = r.ReadBytes(sps)
-		p.ListSPS = append(p.ListPPS, sps)
+		p.ListSPS = append(p.ListSPS, sps)
 	}
 	numOfPictureParameterSets := r.ReadUnsignedByte()
 	for i := uint8(0); i < numOfPictureParameterSets; i++ {
@@ -527,7 +566,7 @@ func (p *AvcConfig) parseConfig(r *atomReader) error {
 		_, _ = r.ReadBytes(pps)
 		p.ListPPS = append(p.ListPPS, pps)
 	}
-	//
+	p.parseParameterSets()
 	return err
 }
 
@@ -625,7 +664,9 @@ func (p *HevcConfig) parseConfig(r *atomReader) error {
 			_, _ = r.ReadBytes(nal)
 			nalUint.NalUnit = append(nalUint.NalUnit, nal)
 		}
+		p.NalUnitArrays = append(p.NalUnitArrays, *nalUint)
 	}
+	p.parseParameterSets()
 	return err
 }
 
@@ -682,7 +723,11 @@ func (p *Av1cConfig) parseConfig(r *atomReader) error {
 	if p.InitialPresentationDelayPresent == 1 {
 		p.InitialPresentationDelayMinusOne = br.ReadBitsLE8(4)
 	}
-	//  configOBUs not read...
+	if len(p.DecoderSpecificInfo) > 4 {
+		if err := p.parseConfigOBUs(p.DecoderSpecificInfo[4:]); err != nil {
+			logW.Printf("failed to parse configOBUs of av1C: %v", err)
+		}
+	}
 	return err
 }
 
@@ -720,13 +765,13 @@ func (p *VpcConfig) parseConfig(r *atomReader) error {
 	p.Level = r.ReadUnsignedByte()
 	tmpU8 := r.ReadUnsignedByte()
 	p.BitDepth = tmpU8 >> 4
-	p.ChromaSubsampling = tmpU8 & 0x0E
+	p.ChromaSubsampling = tmpU8 >> 1 & 0x07
 	p.VideoFullRangeFlag = tmpU8 & 0x1
 	p.ColourPrimaries = r.ReadUnsignedByte()
 	p.TransferCharacteristics = r.ReadUnsignedByte()
 	p.MatrixCoefficients = r.ReadUnsignedByte()
 	p.CodecIntializationDataSize = r.Read2()
-	p.CodecIntializationData = make([]byte, r.Read2())
+	p.CodecIntializationData = make([]byte, p.CodecIntializationDataSize)
 	_, _ = r.ReadBytes(p.CodecIntializationData)
 	return err
 }
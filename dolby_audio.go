@@ -0,0 +1,338 @@
+package fmp4parser
+
+import "fmt"
+
+// the number of the channels of acmod, refer to ETSI TS 102 366 Table 4.3
+var ac3ChannelCounts = [8]uint16{2, 1, 2, 3, 3, 4, 4, 5}
+
+// the channel locations of chan_loc, refer to ETSI TS 102 366 Table F.6.1
+const (
+	eac3ChanLocLcRc   = 1 << 0
+	eac3ChanLocLrsRrs = 1 << 1
+	eac3ChanLocCs     = 1 << 2
+	eac3ChanLocTs     = 1 << 3
+	eac3ChanLocLsdRsd = 1 << 4
+	eac3ChanLocLwRw   = 1 << 5
+	eac3ChanLocLvhRvh = 1 << 6
+	eac3ChanLocCvh    = 1 << 7
+	eac3ChanLocLFE2   = 1 << 8
+)
+
+// eac3ChannelCounts returns the number of the main, the LFE and the height channels of
+// acmod, lfeon and chan_loc.
+func eac3ChannelCounts(acmod, lfeon uint8, chanLoc uint16) (main, lfe, height uint16) {
+	main, lfe = ac3ChannelCounts[acmod&7], uint16(lfeon)
+	for _, loc := range []struct {
+		bit   uint16
+		count uint16
+	}{{eac3ChanLocLcRc, 2}, {eac3ChanLocLrsRrs, 2}, {eac3ChanLocCs, 1}, {eac3ChanLocLsdRsd, 2}, {eac3ChanLocLwRw, 2}} {
+		if chanLoc&loc.bit != 0 {
+			main += loc.count
+		}
+	}
+	for _, loc := range []struct {
+		bit   uint16
+		count uint16
+	}{{eac3ChanLocTs, 1}, {eac3ChanLocLvhRvh, 2}, {eac3ChanLocCvh, 1}} {
+		if chanLoc&loc.bit != 0 {
+			height += loc.count
+		}
+	}
+	if chanLoc&eac3ChanLocLFE2 != 0 {
+		lfe++
+	}
+	return
+}
+
+// channelLayout returns the layout in the form of "main.lfe" or "main.lfe.height", e.g. "5.1"
+// or "7.1.2".
+func channelLayout(main, lfe, height uint16) string {
+	if height > 0 {
+		return fmt.Sprintf("%d.%d.%d", main, lfe, height)
+	}
+	return fmt.Sprintf("%d.%d", main, lfe)
+}
+
+// ChannelCount returns the number of the channels of the substream and its dependent
+// substreams, including the LFE channels.
+func (p *Eac3Substream) ChannelCount() uint16 {
+	main, lfe, height := eac3ChannelCounts(p.Acmod, p.Lfeon, p.ChanLoc)
+	return main + lfe + height
+}
+
+// ChannelLayout returns the channel layout of the substream and its dependent substreams,
+// e.g. "5.1" or "7.1".
+func (p *Eac3Substream) ChannelLayout() string {
+	return channelLayout(eac3ChannelCounts(p.Acmod, p.Lfeon, p.ChanLoc))
+}
+
+// ChannelLayout returns the channel layout of the main program, e.g. "2.0" or "5.1". The
+// objects of Dolby Atmos aren't included, see Atmos.
+func (p *Ac3Descriptor) ChannelLayout() string {
+	if len(p.Substreams) > 0 {
+		return p.Substreams[0].ChannelLayout()
+	}
+	return channelLayout(eac3ChannelCounts(p.Acmod, p.Lfeon, 0))
+}
+
+// dsi_presentation_ch_mode of AC-4, refer to ETSI TS 103 190-2 Table 79
+const (
+	Ac4ChannelMode704 = 11 // 7.0.4, the first immersive channel mode
+	Ac4ChannelMode222 = 15 // 22.2
+)
+
+// the layouts of dsi_presentation_ch_mode, the top channels of 11 ~ 14 are given by
+// pres_top_channel_pairs
+var ac4ChannelModeLayouts = [...]string{"1.0", "2.0", "3.0", "5.0", "5.1", "7.0", "7.1", "7.0", "7.1", "7.0", "7.1",
+	"7.0", "7.1", "9.0", "9.1", "22.2"}
+
+// Ac4Presentation is a presentation of the AC-4 decoder specific information. Only the
+// presentations of version 1 and 2 are parsed, the others have Version only.
+type Ac4Presentation struct {
+	Version uint8  // presentation_version
+	Config  uint8  // presentation_config_v1, 0x1F is a single substream group
+	ID      int    // presentation_id or extended_presentation_id, -1 if not present
+	Name    string // presentation_name of the alternative presentations
+
+	// the channel-based presentation
+	ChannelCoded    bool   // b_presentation_channel_coded
+	ChannelMode     uint8  // dsi_presentation_ch_mode, valid if ChannelCoded
+	BackChannels    bool   // pres_b_4_back_channels_present
+	TopChannelPairs uint8  // pres_top_channel_pairs
+	ChannelMask     uint32 // presentation_channel_mask_v1
+
+	// the immersive flags
+	ObjectBased         bool // some substreams carry the objects
+	Ajoc                bool // some substreams are coded by the Advanced Joint Object Coding
+	PreVirtualized      bool // b_pre_virtualized, rendered for headphones
+	DialogueEnhancement bool // de_indicator
+	Atmos               bool // dolby_atmos_indicator
+
+	ContentClassifier uint8  // content_classifier of the first substream group which has it, e.g. 0 is the complete main
+	Language          string // language_tag_bytes of the first substream group which has it, BCP 47
+
+	hasContentType bool
+}
+
+// Immersive returns whether the presentation is immersive, i.e. it has the top channels or
+// the objects.
+func (p *Ac4Presentation) Immersive() bool {
+	return p.ObjectBased || p.Atmos || (p.ChannelCoded && p.ChannelMode >= Ac4ChannelMode704)
+}
+
+// ChannelLayout returns the channel layout of the channel-based presentation, e.g. "5.1" or
+// "7.1.4". It's "" if the presentation isn't channel-based.
+func (p *Ac4Presentation) ChannelLayout() string {
+	if !p.ChannelCoded || int(p.ChannelMode) >= len(ac4ChannelModeLayouts) {
+		return ""
+	}
+	layout := ac4ChannelModeLayouts[p.ChannelMode]
+	if p.ChannelMode >= Ac4ChannelMode704 && p.ChannelMode < Ac4ChannelMode222 {
+		layout = fmt.Sprintf("%s.%d", layout, 2*p.TopChannelPairs)
+	}
+	return layout
+}
+
+/*
+parseDsi parses ac4_dsi_v1, refer to ETSI TS 103 190-2 E.6
+
+	ac4_dsi_v1() {
+		ac4_dsi_version;                 3
+		bitstream_version;               7
+		fs_index;                        1
+		frame_rate_index;                4
+		n_presentations;                 9
+		if (bitstream_version > 1) {
+			b_program_id;                1
+			if (b_program_id) {
+				short_program_id;        16
+				b_uuid;                  1
+				if (b_uuid) program_uuid 16*8
+			}
+		}
+		ac4_bitrate_dsi();
+		byte_align;
+		for (i = 0; i < n_presentations; i++) {
+			presentation_version;        8
+			pres_bytes;                  8
+			if (pres_bytes == 255) {
+				add_pres_bytes;          16
+				pres_bytes += add_pres_bytes;
+			}
+			if (presentation_version == 0) {
+				ac4_presentation_v0_dsi();
+			} else if (presentation_version == 1 || presentation_version == 2) {
+				ac4_presentation_v1_dsi(pres_bytes);
+			}
+			skip_bytes;
+		}
+	}
+*/
+func (p *Ac4Descriptor) parseDsi(data []byte) error {
+	r := &ascReader{br: newBitReaderFromSlice(data), size: len(data) * 8}
+	p.DsiVersion = uint8(r.read(3))
+	p.BitstreamVersion = uint8(r.read(7))
+	fsIndex := r.read(1)
+	p.FrameRateIndex = uint8(r.read(4))
+	if fsIndex == 0 { // ETSI TS 103 190-1 [1], clause 4.3.3.2.5
+		p.SampleRate = 44100
+	} else {
+		p.SampleRate = 48000
+	}
+	if p.DsiVersion != 1 {
+		return nil // ac4_dsi of the version 0 is obsolete
+	}
+	nPresentations := int(r.read(9))
+	if p.BitstreamVersion > 1 && r.readBool() {
+		p.ProgramID = uint16(r.read(16))
+		if r.readBool() {
+			r.skip(128)
+		}
+	}
+	r.skip(66) // ac4_bitrate_dsi
+	r.byteAlign()
+	for i := 0; i < nPresentations && r.left() >= 16; i++ {
+		presentation := Ac4Presentation{Version: uint8(r.read(8)), ID: -1}
+		presBytes := int(r.read(8))
+		if presBytes == 255 {
+			presBytes += int(r.read(16))
+		}
+		if presBytes*8 > r.left() {
+			break
+		}
+		body := make([]byte, presBytes)
+		for j := range body {
+			body[j] = byte(r.read(8))
+		}
+		if presentation.Version == 1 || presentation.Version == 2 {
+			presentation.parseV1(&ascReader{br: newBitReaderFromSlice(body), size: len(body) * 8})
+		}
+		p.Presentations = append(p.Presentations, presentation)
+	}
+	if r.br.Err() != nil {
+		return fmt.Errorf("%w : Ac4Descriptor", ErrIncompleteBox)
+	}
+	return nil
+}
+
+// parseV1 parses ac4_presentation_v1_dsi, refer to ETSI TS 103 190-2 E.10
+func (p *Ac4Presentation) parseV1(r *ascReader) {
+	p.Config = uint8(r.read(5))
+	addEmdfSubstreams := true
+	if p.Config != 0x06 {
+		r.skip(3) // mdcompat
+		if r.readBool() {
+			p.ID = int(r.read(5))
+		}
+		r.skip(2 + 2 + 5 + 10) // frame rate multiply and fraction info, emdf version and key id
+		if p.ChannelCoded = r.readBool(); p.ChannelCoded {
+			p.ChannelMode = uint8(r.read(5))
+			if p.ChannelMode >= 11 && p.ChannelMode <= 14 {
+				p.BackChannels = r.readBool()
+				p.TopChannelPairs = uint8(r.read(2))
+			}
+			p.ChannelMask = r.read(24)
+		}
+		if r.readBool() { // b_presentation_core_differs
+			if r.readBool() { // b_presentation_core_channel_coded
+				r.skip(2)
+			}
+		}
+		if r.readBool() { // b_presentation_filter
+			r.skip(1)
+			r.skip(8 * int(r.read(8)))
+		}
+		groups := 0
+		switch {
+		case p.Config == 0x1F:
+			groups = 1
+		case p.Config <= 2:
+			groups = 2
+		case p.Config <= 4:
+			groups = 3
+		case p.Config == 5:
+			groups = -2 // n_substream_groups_minus2 follows b_multi_pid
+		default:
+			return // the reserved configurations
+		}
+		if p.Config != 0x1F {
+			r.skip(1) // b_multi_pid
+			if groups < 0 {
+				groups = int(r.read(3)) + 2
+			}
+		}
+		for i := 0; i < groups && r.left() > 0; i++ {
+			p.parseSubstreamGroup(r)
+		}
+		p.PreVirtualized = r.readBool()
+		addEmdfSubstreams = r.readBool()
+	}
+	if addEmdfSubstreams {
+		r.skip(15 * int(r.read(7))) // substream_emdf_version and substream_key_id
+	}
+	if r.readBool() { // b_presentation_bitrate_info
+		r.skip(66)
+	}
+	if r.readBool() { // b_alternative
+		r.byteAlign()
+		name := make([]byte, r.read(16))
+		for i := range name {
+			name[i] = byte(r.read(8))
+		}
+		p.Name = string(name)
+		r.skip(11 * int(r.read(5))) // target_md_compat and target_device_category
+	}
+	r.byteAlign()
+	if r.left() >= 8 && r.br.Err() == nil {
+		p.DialogueEnhancement = r.readBool()
+		p.Atmos = r.readBool()
+		r.skip(4)
+		if r.readBool() { // b_extended_presentation_id
+			p.ID = int(r.read(9))
+		}
+	}
+}
+
+// parseSubstreamGroup parses ac4_substream_group_dsi, refer to ETSI TS 103 190-2 E.11
+func (p *Ac4Presentation) parseSubstreamGroup(r *ascReader) {
+	r.skip(2) // b_substreams_present, b_hsf_ext
+	channelCoded := r.readBool()
+	if !channelCoded {
+		p.ObjectBased = true
+	}
+	nSubstreams := int(r.read(8))
+	for i := 0; i < nSubstreams && r.left() > 0; i++ {
+		r.skip(2) // dsi_sf_multiplier
+		if r.readBool() {
+			r.skip(5) // substream_bitrate_indicator
+		}
+		if channelCoded {
+			r.skip(24) // dsi_substream_channel_mask
+			continue
+		}
+		if r.readBool() { // b_ajoc
+			p.Ajoc = true
+			if !r.readBool() { // b_static_dmx
+				r.skip(4)
+			}
+			r.skip(6)
+		}
+		r.skip(4) // the bed, dynamic and ISF objects, reserved
+	}
+	if r.readBool() { // b_content_type
+		classifier := uint8(r.read(3))
+		if r.readBool() { // b_language_indicator
+			tag := make([]byte, r.read(6))
+			for i := range tag {
+				tag[i] = byte(r.read(8))
+			}
+			if p.Language == "" {
+				p.Language = string(tag)
+			}
+		}
+		if !p.hasContentType {
+			p.ContentClassifier = classifier
+			p.hasContentType = true
+		}
+	}
+}
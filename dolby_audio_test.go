@@ -0,0 +1,89 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAc3Descriptor(t *testing.T) {
+	dac3 := (&bitWriter{}).write(1, 2).write(8, 5).write(0, 3).write(7, 3).write(1, 1).write(14, 5).write(0, 5)
+	// a 7.1 program of an independent substream of 5.1 and a dependent substream of Lrs/Rrs, and JOC
+	dec3 := (&bitWriter{}).write(640, 13).write(0, 3).
+		write(0, 2).write(16, 5).write(0, 1).write(0, 1).write(0, 3).write(7, 3).write(1, 1).write(0, 3).
+		write(1, 4).write(eac3ChanLocLrsRrs, 9).
+		write(0, 7).write(1, 1).write(16, 8)
+	p := newTestParser(t, mkAudioInit(mkAudioEntry("ac-3", 2, 48000, mkBox("dac3", dac3.b)),
+		mkAudioEntry("ec-3", 2, 48000, mkBox("dec3", dec3.b))))
+	entries := p.GetTracks()[0].SampleEntries
+
+	ac3 := entries[0].audioEntry.decoderDescriptors[AudioCodecAC3].(*Ac3Descriptor)
+	if ac3.SampleRate != 44100 || ac3.ChannelCount != 6 || ac3.BitRateCode != 14 || ac3.ChannelLayout() != "5.1" {
+		t.Errorf("Ac3Descriptor = %+v, layout %s", ac3, ac3.ChannelLayout())
+	}
+	eac3 := entries[1].audioEntry.decoderDescriptors[AudioCodecEAC3].(*Ac3Descriptor)
+	want := []Eac3Substream{{Bsid: 16, Acmod: 7, Lfeon: 1, NumDepSub: 1, ChanLoc: eac3ChanLocLrsRrs}}
+	if !reflect.DeepEqual(eac3.Substreams, want) {
+		t.Errorf("Substreams = %+v, want %+v", eac3.Substreams, want)
+	}
+	if eac3.DataRate != 640 || eac3.SampleRate != 48000 || eac3.ChannelCount != 8 || eac3.ChannelLayout() != "7.1" {
+		t.Errorf("Ac3Descriptor = %+v, layout %s", eac3, eac3.ChannelLayout())
+	}
+	if !eac3.Atmos || eac3.ComplexityIndex != 16 {
+		t.Errorf("Atmos = %v %d, want true 16", eac3.Atmos, eac3.ComplexityIndex)
+	}
+	if got := entries[1].ChannelCount; got != 8 {
+		t.Errorf("ChannelCount = %d, want 8", got)
+	}
+}
+
+func TestAc4Descriptor(t *testing.T) {
+	// a 7.1.4 presentation in English
+	channels := (&bitWriter{}).write(0x1F, 5).write(0, 3).write(1, 1).write(3, 5).write(0, 4).write(0, 15).
+		write(1, 1).write(12, 5).write(1, 1).write(2, 2).write(0x0003FF, 24).
+		write(0, 1).write(0, 1). // core differs, filter
+		write(1, 1).write(0, 1).write(1, 1).write(1, 8).write(0, 2).write(0, 1).write(0x0003FF, 24).
+		write(1, 1).write(0, 3).write(1, 1).write(3, 6).write('e', 8).write('n', 8).write('g', 8).
+		write(0, 1).write(0, 1). // pre-virtualized, emdf substreams
+		write(0, 1).write(0, 1).align().
+		write(1, 1).write(1, 1).write(0, 4).write(0, 1).write(0, 1)
+	// an alternative presentation of the objects of A-JOC
+	objects := (&bitWriter{}).write(0x1F, 5).write(0, 3).write(0, 1).write(0, 4).write(0, 15).
+		write(0, 1).write(0, 1).write(0, 1).
+		write(1, 1).write(0, 1).write(0, 1).write(1, 8).write(0, 2).write(0, 1).
+		write(1, 1).write(0, 1).write(3, 4).write(15, 6).write(0x0C, 4).
+		write(0, 1).
+		write(1, 1).write(0, 1).
+		write(0, 1).write(1, 1).align().write(3, 16).write('A', 8).write('l', 8).write('t', 8).write(0, 5).align()
+	dac4 := (&bitWriter{}).write(1, 3).write(2, 7).write(1, 1).write(2, 4).write(2, 9).
+		write(1, 1).write(0x1234, 16).write(0, 1).
+		write(0, 2).write(0, 32).write(0, 32).align()
+	for _, pres := range [][]byte{channels.b, objects.b} {
+		dac4.write(1, 8).write(uint64(len(pres)), 8)
+		for _, b := range pres {
+			dac4.write(uint64(b), 8)
+		}
+	}
+	p := newTestParser(t, mkAudioInit(mkAudioEntry("ac-4", 2, 48000, mkBox("dac4", dac4.b))))
+	ac4 := p.GetTracks()[0].audioEntry.decoderDescriptors[AudioCodecAC4].(*Ac4Descriptor)
+	if ac4.DsiVersion != 1 || ac4.BitstreamVersion != 2 || ac4.SampleRate != 48000 || ac4.FrameRateIndex != 2 || ac4.ProgramID != 0x1234 {
+		t.Errorf("Ac4Descriptor = %+v", ac4)
+	}
+	if len(ac4.Presentations) != 2 {
+		t.Fatalf("got %d presentations, want 2", len(ac4.Presentations))
+	}
+	ch := ac4.Presentations[0]
+	if ch.ID != 3 || !ch.ChannelCoded || ch.ChannelMode != 12 || !ch.BackChannels || ch.TopChannelPairs != 2 ||
+		ch.Language != "eng" || !ch.DialogueEnhancement || !ch.Atmos || ch.ObjectBased {
+		t.Errorf("presentation 0 = %+v", ch)
+	}
+	if ch.ChannelLayout() != "7.1.4" || !ch.Immersive() {
+		t.Errorf("presentation 0 layout = %s, immersive %v", ch.ChannelLayout(), ch.Immersive())
+	}
+	obj := ac4.Presentations[1]
+	if obj.ID != -1 || obj.ChannelCoded || !obj.ObjectBased || !obj.Ajoc || !obj.PreVirtualized || obj.Name != "Alt" || obj.Atmos {
+		t.Errorf("presentation 1 = %+v", obj)
+	}
+	if obj.ChannelLayout() != "" || !obj.Immersive() {
+		t.Errorf("presentation 1 layout = %s, immersive %v", obj.ChannelLayout(), obj.Immersive())
+	}
+}
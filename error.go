@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import "errors"
 
@@ -26,8 +26,13 @@ var (
 	ErrInvalidLengthOfSampleGroup           = errors.New("length individual sampleToGroup entry is invalid")
 	ErrInvalidLengthOfIVInSampleGroup       = errors.New("in cenc sample group entry, the length of (const)IV is not 8 or 16")
 
-	ErrNotFoundTrack = errors.New("not found the trak information in moov")
-	ErrNoImplement   = errors.New("function parse has not been implement")
+	ErrNotFoundTrack         = errors.New("not found the trak information in moov")
+	ErrNoSegmentIndex        = errors.New("there is no segment index box")
+	ErrNoSubsegmentIndex     = errors.New("there is no subsegment index box")
+	ErrNoImplement           = errors.New("function parse has not been implement")
+	ErrUnsupportedFraming    = errors.New("the audio can't be carried in the framing")
+	ErrUnsupportedExtraction = errors.New("the track can't be extracted to an elementary stream")
+	ErrNoTimecode            = errors.New("there is no timecode track for the track")
 )
 
 var (
@@ -0,0 +1,303 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// esWriter writes the packets of a track as an elementary stream.
+type esWriter interface {
+	writePacket(packet *Packet) error
+	close() error
+}
+
+// ElementaryStreamFormat returns the file extension of the raw format which the track is
+// extracted to by ExtractTrack:
+//
+//	"h264", "h265", "h266"  Annex B byte stream, of the base layer for Dolby Vision
+//	"ivf"                   VP8, VP9 and AV1
+//	"aac"                   AAC in ADTS
+//	"ac3", "ec3", "mp3"     the raw frames
+//	"opus"                  Opus in Ogg
+//	"flac"                  native FLAC
+//	"wav"                   "lpcm", "sowt", "twos", "alaw" and "ulaw"
+//	"vtt"                   WebVTT of "wvtt" and "tx3g"
+//
+// "" if the track can't be extracted, e.g. it's encrypted.
+func (p *Track) ElementaryStreamFormat() string {
+	if p.EncryptedInformation != nil {
+		return ""
+	}
+	switch p.Codec {
+	case VideoCodecH264:
+		return "h264"
+	case VideoCodecHEVC:
+		return "h265"
+	case VideoCodecVVC:
+		return "h266"
+	case VideoCodecDolbyVision:
+		if p.videoEntry != nil {
+			switch p.videoEntry.nalCodec() {
+			case VideoCodecH264:
+				return "h264"
+			case VideoCodecHEVC:
+				return "h265"
+			}
+		}
+		return ""
+	case VideoCodecVP8, VideoCodecVP9, VideoCodecAV1:
+		return "ivf"
+	case AudioCodecAAC:
+		return "aac"
+	case AudioCodecAC3:
+		return "ac3"
+	case AudioCodecEAC3:
+		return "ec3"
+	case AudioCodecMP3:
+		return "mp3"
+	case AudioCodecOPUS:
+		return "opus"
+	case AudioCodecFLAC:
+		return "flac"
+	case AudioCodecRAW, AudioCodecALAW, AudioCodecMULAW:
+		if p.audioEntry != nil {
+			if _, ok := wavSampleFormats[p.audioEntry.lpcmCodec]; ok {
+				return "wav"
+			}
+		}
+		return ""
+	}
+	if p.Type == SubtitleTrack && len(p.SampleEntries) > 0 {
+		switch p.SampleEntries[0].Format {
+		case "wvtt", "tx3g":
+			return "vtt"
+		}
+	}
+	return ""
+}
+
+// newESWriter returns the writer of ElementaryStreamFormat of the track. The packets are
+// the ones to be written, some formats need to know them before writing the header.
+func newESWriter(w io.Writer, track *Track, packets []Packet) (esWriter, error) {
+	switch track.ElementaryStreamFormat() {
+	case "h264", "h265", "h266":
+		return &annexBWriter{w: w, track: track, converters: make(map[int]*NalConverter)}, nil
+	case "ivf":
+		return newIvfWriter(w, track, len(packets))
+	case "aac":
+		return &adtsWriter{w: w, track: track, framers: make(map[int]*AacFramer)}, nil
+	case "ac3", "ec3", "mp3":
+		return &rawWriter{w: w}, nil
+	case "opus":
+		return newOggOpusWriter(w, track)
+	case "flac":
+		return newFlacWriter(w, track)
+	case "wav":
+		return newWavWriter(w, track, packets)
+	case "vtt":
+		return newWebVTTWriter(w, track)
+	}
+	return nil, ErrUnsupportedExtraction
+}
+
+// ExtractTrack reads all the packets of the track and writes them to w in the format of
+// Track.ElementaryStreamFormat. It must be called after Parse.
+func (p *Parser) ExtractTrack(trackID uint32, w io.Writer) error {
+	if p.m.movie == nil {
+		return ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return ErrNotFoundTrack
+	}
+	track := trak.newTrack()
+	packets, err := p.Packets(trackID, PrimingKeep)
+	if err != nil {
+		return err
+	}
+	writer, err := newESWriter(w, &track, packets)
+	if err != nil {
+		return err
+	}
+	for i := range packets {
+		if err = p.ReadPacket(&packets[i]); err != nil {
+			return err
+		}
+		if err = writer.writePacket(&packets[i]); err != nil {
+			return err
+		}
+		packets[i].Data = nil
+	}
+	return writer.close()
+}
+
+// rawWriter writes the data of the packets as is, the samples are self-delimiting frames.
+type rawWriter struct {
+	w io.Writer
+}
+
+func (p *rawWriter) writePacket(packet *Packet) error {
+	_, err := p.w.Write(packet.Data)
+	return err
+}
+
+func (p *rawWriter) close() error {
+	return nil
+}
+
+// annexBWriter writes the AVC/HEVC samples in Annex B by the NalConverter of the sample description.
+type annexBWriter struct {
+	w          io.Writer
+	track      *Track
+	converters map[int]*NalConverter // key: sample description index
+}
+
+func (p *annexBWriter) writePacket(packet *Packet) error {
+	converter, ok := p.converters[packet.DescriptorIndex]
+	if !ok {
+		entry := p.track.SampleEntry(packet.DescriptorIndex)
+		if entry == nil {
+			return ErrInvalidSampleDescription
+		}
+		var err error
+		if converter, err = entry.NalConverter(); err != nil {
+			return err
+		}
+		p.converters[packet.DescriptorIndex] = converter
+	}
+	data, err := converter.ToAnnexB(packet.Data)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *annexBWriter) close() error {
+	return nil
+}
+
+// adtsWriter writes the AAC frames with ADTS headers by the AacFramer of the sample description.
+type adtsWriter struct {
+	w       io.Writer
+	track   *Track
+	framers map[int]*AacFramer // key: sample description index
+}
+
+func (p *adtsWriter) writePacket(packet *Packet) error {
+	framer, ok := p.framers[packet.DescriptorIndex]
+	if !ok {
+		entry := p.track.SampleEntry(packet.DescriptorIndex)
+		if entry == nil {
+			return ErrInvalidSampleDescription
+		}
+		var err error
+		if framer, err = entry.AacFramer(); err != nil {
+			return err
+		}
+		p.framers[packet.DescriptorIndex] = framer
+	}
+	data, err := framer.ADTS(packet.Data)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *adtsWriter) close() error {
+	return nil
+}
+
+// ivfWriter writes VP8, VP9 and AV1 in IVF. The time base is the time scale of the track.
+//
+//	bytes 0-3    signature: "DKIF"
+//	bytes 4-5    version (0)
+//	bytes 6-7    length of header in bytes (32)
+//	bytes 8-11   codec FourCC, e.g. "VP80"
+//	bytes 12-13  width in pixels
+//	bytes 14-15  height in pixels
+//	bytes 16-23  time base denominator and numerator
+//	bytes 24-27  number of frames
+//	bytes 28-31  unused
+//
+// Each frame has a header of 4 bytes of the frame size and 8 bytes of the timestamp.
+type ivfWriter struct {
+	w   io.Writer
+	av1 bool
+}
+
+func newIvfWriter(w io.Writer, track *Track, frames int) (esWriter, error) {
+	header := make([]byte, 32)
+	copy(header, "DKIF")
+	binary.LittleEndian.PutUint16(header[6:], 32)
+	switch track.Codec {
+	case VideoCodecVP8:
+		copy(header[8:], "VP80")
+	case VideoCodecVP9:
+		copy(header[8:], "VP90")
+	default:
+		copy(header[8:], "AV01")
+	}
+	binary.LittleEndian.PutUint16(header[12:], track.Width)
+	binary.LittleEndian.PutUint16(header[14:], track.Height)
+	binary.LittleEndian.PutUint32(header[16:], track.TimeScale)
+	binary.LittleEndian.PutUint32(header[20:], 1)
+	binary.LittleEndian.PutUint32(header[24:], uint32(frames))
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &ivfWriter{w: w, av1: track.Codec == VideoCodecAV1}, nil
+}
+
+func (p *ivfWriter) writePacket(packet *Packet) error {
+	data := packet.Data
+	// the temporal delimiter OBU isn't stored in "av01" samples, but a temporal unit of
+	// the Low Overhead Bitstream Format starts with it
+	if p.av1 && (len(data) == 0 || data[0]>>3&0x0F != Av1OBUTemporalDelimiter) {
+		data = append([]byte{Av1OBUTemporalDelimiter<<3 | 0x02, 0}, data...)
+	}
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint32(header, uint32(len(data)))
+	binary.LittleEndian.PutUint64(header[4:], uint64(packet.PTS)) // signed
+	if _, err := p.w.Write(header); err != nil {
+		return err
+	}
+	_, err := p.w.Write(data)
+	return err
+}
+
+func (p *ivfWriter) close() error {
+	return nil
+}
+
+// flacWriter writes the FLAC frames in the native FLAC format, "fLaC" and the STREAMINFO
+// metadata block of "dfLa" followed by the frames.
+type flacWriter struct {
+	w io.Writer
+}
+
+func newFlacWriter(w io.Writer, track *Track) (esWriter, error) {
+	if track.audioEntry == nil {
+		return nil, ErrUnsupportedExtraction
+	}
+	flac, ok := track.audioEntry.decoderDescriptors[AudioCodecFLAC].(*FlacDescriptor)
+	if !ok || len(flac.StreamInfo) != 34 {
+		return nil, ErrUnsupportedExtraction
+	}
+	// the last metadata block of type STREAMINFO
+	header := append([]byte{'f', 'L', 'a', 'C', 0x80, 0, 0, 34}, flac.StreamInfo...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &flacWriter{w: w}, nil
+}
+
+func (p *flacWriter) writePacket(packet *Packet) error {
+	_, err := p.w.Write(packet.Data)
+	return err
+}
+
+func (p *flacWriter) close() error {
+	return nil
+}
@@ -0,0 +1,267 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParser_ExtractTrackAnnexB(t *testing.T) {
+	sps, pps := []byte{0x67, 0x64, 0x00, 0x1F}, []byte{0x68, 0xEE, 0x3C, 0x80}
+	avcC := mkBox("avcC", []byte{1, 0x64, 0, 0x1F, 0xFF, 0xE1}, u16(4), sps, u8(1), u16(4), pps)
+	init := mkVideoInit(mkVideoEntry("avc1", 1280, 720, avcC))
+	idr, slice := append(u32(3), 0x65, 0x88, 0x84), append(u32(2), 0x41, 0x9A)
+	trun := func(dataOffset uint32) []byte {
+		return mkFullBox("trun", 0, 0x000201, u32(2), u32(dataOffset), u32(uint32(len(idr))), u32(uint32(len(slice))))
+	}
+	traf := func(dataOffset uint32) []byte {
+		return mkBox("traf", mkFullBox("tfhd", 0, 0x020002, u32(1), u32(1)), trun(dataOffset))
+	}
+	moofSize := len(mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf(0)))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf(uint32(moofSize+8)))
+	file := append(append(init, moof...), mkBox("mdat", idr, slice)...)
+
+	p := newTestParser(t, file)
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "h264" {
+		t.Fatalf("ElementaryStreamFormat() = %q, want h264", format)
+	}
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	want := bytes.Join([][]byte{nil, sps, pps, idr[4:], slice[4:]}, startCode)
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("ExtractTrack() = % X, want % X", w.Bytes(), want)
+	}
+}
+
+func TestParser_ExtractTrackWav(t *testing.T) {
+	twos := mkBox("twos", make([]byte, 6), u16(1), make([]byte, 8), u16(2), u16(16), u16(0), u16(0), u16(48000), u16(0))
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(2), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(48000), u32(2), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("soun"), make([]byte, 12), []byte("audio\x00"))
+	stbl := func(offset uint32) []byte {
+		return mkBox("stbl",
+			mkFullBox("stsd", 0, 0, u32(1), twos),
+			mkFullBox("stts", 0, 0, u32(1), u32(2), u32(1)),
+			mkFullBox("stsc", 0, 0, u32(1), u32(1), u32(2), u32(1)),
+			mkFullBox("stsz", 0, 0, u32(4), u32(2)),
+			mkFullBox("stco", 0, 0, u32(1), u32(offset)))
+	}
+	moov := func(offset uint32) []byte {
+		trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl(offset))))
+		return mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(48000), u32(2), make([]byte, 80)), trak)
+	}
+	ftyp := mkBox("ftyp", []byte("qt  "), u32(0), []byte("qt  "))
+	offset := uint32(len(ftyp) + len(moov(0)) + 8)
+	file := append(append(ftyp, moov(offset)...), mkBox("mdat", []byte{0x12, 0x34, 0xFF, 0xFE, 0x00, 0x01, 0x80, 0x00})...)
+
+	p := newTestParser(t, file)
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	got := w.Bytes()
+	if len(got) != 44+8 || string(got[:4]) != "RIFF" || string(got[36:40]) != "data" {
+		t.Fatalf("unexpected WAVE header % X", got)
+	}
+	// 2 channels, 48000 Hz, 16 bits
+	if want := []byte{1, 0, 2, 0, 0x80, 0xBB, 0, 0, 0, 0xEE, 2, 0, 4, 0, 16, 0}; !bytes.Equal(got[20:36], want) {
+		t.Errorf("fmt = % X, want % X", got[20:36], want)
+	}
+	// the big endian samples are swapped
+	if want := []byte{0x34, 0x12, 0xFE, 0xFF, 0x01, 0x00, 0x00, 0x80}; !bytes.Equal(got[44:], want) {
+		t.Errorf("data = % X, want % X", got[44:], want)
+	}
+}
+
+func TestOggWriter(t *testing.T) {
+	if crc := oggCRC([]byte("123456789")); crc != 0x89A1897F {
+		t.Errorf("oggCRC() = %#x, want 0x89a1897f", crc)
+	}
+	var w bytes.Buffer
+	ogg := &oggWriter{w: &w, serial: 1}
+	if err := ogg.writePacket(make([]byte, 600), 960, true); err != nil {
+		t.Fatal(err)
+	}
+	page := w.Bytes()
+	if string(page[:4]) != "OggS" || page[5] != oggBeginOfStream|oggEndOfStream || page[6] != 0xC0 || page[7] != 0x03 {
+		t.Errorf("unexpected page header % X", page[:27])
+	}
+	if segments := page[26:30]; !bytes.Equal(segments, []byte{3, 255, 255, 90}) {
+		t.Errorf("lacing values = %v, want [255 255 90]", segments[1:])
+	}
+	if len(page) != 27+3+600 {
+		t.Errorf("page size = %d, want %d", len(page), 27+3+600)
+	}
+}
+
+func TestWebVTTWriter(t *testing.T) {
+	var w bytes.Buffer
+	writer := &webVTTWriter{w: &w, timeScale: 1000}
+	sample := append(mkBox("vttc", mkBox("iden", []byte("1")), mkBox("sttg", []byte("line:0")), mkBox("payl", []byte("Hello\n"))),
+		mkBox("vttc", mkBox("payl", []byte("World")))...)
+	if err := writer.writePacket(&Packet{PTS: 3723004, Duration: 1500, Data: sample}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.writePacket(&Packet{PTS: 3724504, Duration: 500, Data: mkBox("vtte")}); err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n01:02:03.004 --> 01:02:04.504 line:0\nHello\n\n01:02:03.004 --> 01:02:04.504\nWorld\n\n"
+	if w.String() != want {
+		t.Errorf("got %q, want %q", w.String(), want)
+	}
+}
+
+// mkFragment builds "moof" and "mdat" of the samples of track 1 of the first sample description,
+// each sample lasts duration.
+func mkFragment(duration uint32, samples ...[]byte) []byte {
+	moof := func(dataOffset uint32) []byte {
+		payloads := [][]byte{u32(uint32(len(samples))), u32(dataOffset)}
+		for _, sample := range samples {
+			payloads = append(payloads, u32(uint32(len(sample))))
+		}
+		tfhd := mkFullBox("tfhd", 0, 0x02000A, u32(1), u32(1), u32(duration))
+		return mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), mkBox("traf", tfhd, mkFullBox("trun", 0, 0x000201, payloads...)))
+	}
+	return append(moof(uint32(len(moof(0))+8)), mkBox("mdat", samples...)...)
+}
+
+// oggPage is a page of an Ogg stream without the lacing values.
+type oggPage struct {
+	headerType uint8
+	granule    uint64
+	data       []byte
+}
+
+func parseOggPages(t *testing.T, b []byte) []oggPage {
+	var pages []oggPage
+	for len(b) > 0 {
+		if len(b) < 27 || string(b[:4]) != "OggS" || len(b) < 27+int(b[26]) {
+			t.Fatalf("invalid Ogg page % X", b)
+		}
+		segments := int(b[26])
+		size := 0
+		for _, lacing := range b[27 : 27+segments] {
+			size += int(lacing)
+		}
+		end := 27 + segments + size
+		page := append([]byte{}, b[:end]...)
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		if crc := binary.LittleEndian.Uint32(b[22:]); crc != oggCRC(page) {
+			t.Errorf("CRC = %#x, want %#x", crc, oggCRC(page))
+		}
+		pages = append(pages, oggPage{headerType: b[5], granule: binary.LittleEndian.Uint64(b[6:]), data: b[27+segments : end]})
+		b = b[end:]
+	}
+	return pages
+}
+
+func TestParser_ExtractTrackOpus(t *testing.T) {
+	// 2 channels, pre-skip 312, 48000 Hz, channel mapping family 0
+	dOps := mkBox("dOps", u8(0), u8(2), u16(312), u32(48000), u16(0), u8(0))
+	file := append(mkAudioInit(mkAudioEntry("Opus", 2, 48000, dOps)), mkFragment(960, []byte{0xFC, 1}, []byte{0xFC, 2}, []byte{0xFC, 3})...)
+
+	p := newTestParser(t, file)
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "opus" {
+		t.Fatalf("ElementaryStreamFormat() = %q, want opus", format)
+	}
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	pages := parseOggPages(t, w.Bytes())
+	if len(pages) != 5 {
+		t.Fatalf("got %d pages, want 5", len(pages))
+	}
+	head := append([]byte("OpusHead"), 1, 2, 0x38, 0x01, 0x80, 0xBB, 0, 0, 0, 0, 0)
+	if pages[0].headerType != oggBeginOfStream || pages[0].granule != 0 || !bytes.Equal(pages[0].data, head) {
+		t.Errorf("OpusHead page = %+v, want % X", pages[0], head)
+	}
+	if tags := pages[1].data; len(tags) < 16 || string(tags[:8]) != "OpusTags" ||
+		int(binary.LittleEndian.Uint32(tags[8:]))+16 != len(tags) || binary.LittleEndian.Uint32(tags[len(tags)-4:]) != 0 {
+		t.Errorf("OpusTags = % X", tags)
+	}
+	// the granule position counts the pre-skip samples once, from the start of the stream
+	for i, granule := range []uint64{960, 1920, 2880} {
+		page := pages[2+i]
+		if page.granule != granule || !bytes.Equal(page.data, []byte{0xFC, byte(i + 1)}) {
+			t.Errorf("page %d = %+v, want granule %d", 2+i, page, granule)
+		}
+	}
+	if pages[4].headerType != oggEndOfStream {
+		t.Errorf("header_type of the last page = %#x, want end of stream", pages[4].headerType)
+	}
+}
+
+func TestParser_ExtractTrackIvf(t *testing.T) {
+	vpcC := mkFullBox("vpcC", 1, 0, []byte{0, 10, 8<<4 | 1<<1, 1, 1, 1}, u16(0))
+	file := append(mkVideoInit(mkVideoEntry("vp09", 1280, 720, vpcC)), mkFragment(40, []byte{0x82, 0x49}, []byte{0x86})...)
+
+	p := newTestParser(t, file)
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "ivf" {
+		t.Fatalf("ElementaryStreamFormat() = %q, want ivf", format)
+	}
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	got := w.Bytes()
+	if len(got) != 32+12+2+12+1 {
+		t.Fatalf("ExtractTrack() = % X", got)
+	}
+	// VP9, 1280x720, time base 1/1000, 2 frames
+	header := append([]byte("DKIF"), 0, 0, 32, 0, 'V', 'P', '9', '0', 0x00, 0x05, 0xD0, 0x02, 0xE8, 0x03, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0)
+	if !bytes.Equal(got[:32], header) {
+		t.Errorf("header = % X, want % X", got[:32], header)
+	}
+	// frame size and PTS
+	if want := []byte{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x82, 0x49}; !bytes.Equal(got[32:46], want) {
+		t.Errorf("frame 0 = % X, want % X", got[32:46], want)
+	}
+	if want := []byte{1, 0, 0, 0, 40, 0, 0, 0, 0, 0, 0, 0, 0x86}; !bytes.Equal(got[46:], want) {
+		t.Errorf("frame 1 = % X, want % X", got[46:], want)
+	}
+}
+
+func TestParser_ExtractTrackADTS(t *testing.T) {
+	// ES_Descriptor of DecoderConfigDescriptor of MPEG-4 audio and AAC LC 48000 Hz stereo
+	esds := mkFullBox("esds", 0, 0, []byte{0x03, 25}, u16(1), u8(0),
+		[]byte{0x04, 17, 0x40, 0x15}, make([]byte, 11), []byte{0x05, 2, 0x11, 0x90}, []byte{0x06, 1, 0x02})
+	file := append(mkAudioInit(mkAudioEntry("mp4a", 2, 48000, esds)), mkFragment(1024, []byte{0xAA, 0xBB}, []byte{0xCC})...)
+
+	p := newTestParser(t, file)
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "aac" {
+		t.Fatalf("ElementaryStreamFormat() = %q, want aac", format)
+	}
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	// AAC LC, 48000 Hz, 2 channels, 9 and 8 bytes
+	want := []byte{0xFF, 0xF1, 0x4C, 0x80, 0x01, 0x3F, 0xFC, 0xAA, 0xBB, 0xFF, 0xF1, 0x4C, 0x80, 0x01, 0x1F, 0xFC, 0xCC}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("ExtractTrack() = % X, want % X", w.Bytes(), want)
+	}
+}
+
+func TestParser_ExtractTrackFlac(t *testing.T) {
+	// 4096 samples per block, 44100 Hz, 2 channels, 16 bits
+	streamInfo := append([]byte{0x10, 0, 0x10, 0, 0, 0, 0, 0, 0, 0, 0x0A, 0xC4, 0x42, 0xF0}, make([]byte, 20)...)
+	dfLa := mkFullBox("dfLa", 0, 0, []byte{0, 0, 0, 34}, streamInfo, []byte{0x81, 0, 0, 0})
+	file := append(mkAudioInit(mkAudioEntry("fLaC", 2, 44100, dfLa)), mkFragment(4096, []byte{0xFF, 0xF8, 0x01}, []byte{0xFF, 0xF8, 0x02})...)
+
+	p := newTestParser(t, file)
+	if format := p.GetTracks()[0].ElementaryStreamFormat(); format != "flac" {
+		t.Fatalf("ElementaryStreamFormat() = %q, want flac", format)
+	}
+	var w bytes.Buffer
+	if err := p.ExtractTrack(1, &w); err != nil {
+		t.Fatal(err)
+	}
+	// "fLaC", the last metadata block of STREAMINFO and the frames
+	want := bytes.Join([][]byte{[]byte("fLaC"), {0x80, 0, 0, 34}, streamInfo, {0xFF, 0xF8, 0x01, 0xFF, 0xF8, 0x02}}, nil)
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("ExtractTrack() = % X, want % X", w.Bytes(), want)
+	}
+}
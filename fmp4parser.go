@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"io"
@@ -6,13 +6,21 @@ import (
 )
 
 type Packet struct {
-	Duration        uint32 // in ms
-	DTS             uint64
-	PTS             uint64
-	Data            []byte
-	Size            uint32
-	DescriptorIndex int
-	offset          uint64
+	Duration          uint32 // in ms
+	DTS               uint64
+	PTS               int64 // the presentation time, negative if the sample is before the presentation
+	Data              []byte
+	Size              uint32
+	DescriptorIndex   int
+	SampleFlags       SampleFlags
+	SubSamples        []SubSample            // from the "subs" of the lowest flags, nil if the packet isn't divided
+	SubSamplesByFlags map[uint32][]SubSample // from every "subs" by its flags
+	offset            uint64
+	av1c              *Av1cConfig // IsNonSync is detected from the data by ReadPacket, as there is no sync information
+
+	// for gapless audio, in the time scale of the track
+	SkipSamples    uint32 // the leading samples of the packet to discard, i.e. the priming samples
+	DiscardPadding uint32 // the trailing samples of the packet to discard, i.e. the padding samples
 }
 
 // Track is the struct of track in a media source file.
@@ -23,19 +31,41 @@ type Track struct {
 	Codec   CodecType // The codec
 	Format  string    // If track is encrypted, it's from enca/encv; If not, it's sampleEntry
 
-	Duration  uint64 // If track is VOD, it's duration by timeScale of the track; If not, this parameter is not accurate
-	TimeScale uint32 //
+	Duration  uint64    // If track is VOD, it's duration by timeScale of the track; If not, this parameter is not accurate
+	TimeScale uint32    //
+	Timeline  *Timeline // the presentation timeline from the edit list
 	// for audio
-	ChannelCount uint16 // For audio track
-	SampleSize   uint32 // Default sample size
-	SampleRate   uint32 // For audio track
+	ChannelCount uint16   // For audio track
+	SampleSize   uint32   // Default sample size
+	SampleRate   uint32   // For audio track
+	Gapless      *Gapless // encoder delay and padding of audio, nil if unknown
+
+	ChannelLayout *AudioChannelLayout // "chan" of QuickTime, nil if not present
 
 	// for video
 	Width  uint16 // picture width
 	Height uint16 // picture height
 
+	SequenceParameterSet *SequenceParameterSet // the first SPS of H.264/HEVC, nil for other codecs
+	HDRInfo              *HDRInfo              // nil if the video is SDR
+	Spatial              *Spatial              // spherical or stereoscopic 3D metadata, nil if not present
+
+	// for timecode
+	Timecode *TimecodeSampleEntry // the "tmcd" sample description of a timecode track
+
 	ExtraRawData         map[CodecType][]byte  // audio descriptor OR video codec configuration record
 	EncryptedInformation *ProtectedInformation // Track encryption information
+
+	SampleEntries []SampleEntry    // all the sample descriptions, the fields above are from the first one
+	References    []TrackReference // the references of "tref" to other tracks
+	Groups        []TrackGroup     // the track groups of "trgr" the track belongs to
+
+	DataReferences []DataReference // the entries of "dref", the sample entry refers to them by data_reference_index
+	QuickTime      *QuickTime      // the QuickTime specific atoms, nil if none of them exists
+
+	codingName uint32 // original format of the sample entry
+	audioEntry *audioSampleEntry
+	videoEntry *videoSampleEntry
 }
 
 type Movie struct {
@@ -85,24 +115,44 @@ func (p *Parser) Parse() error {
 	return nil
 }
 
+// GetTracks returns all the tracks of "moov". It must be called after Parse.
 func (p *Parser) GetTracks() []Track {
-	return nil
+	return p.tracksOf(UnknownTrack)
 }
 
 func (p *Parser) GetTrackCounts() int {
-	return 0
+	if p.m.movie == nil {
+		return 0
+	}
+	return len(p.m.movie.trak)
 }
 
 func (p *Parser) GetAudioTracks() []Track {
-	return nil
+	return p.tracksOf(AudioTrack)
 }
 
 func (p *Parser) GetVideoTracks() []Track {
-	return nil
+	return p.tracksOf(VideoTrack)
 }
 
 func (p *Parser) GetSubtitleTracks() []Track {
-	return nil
+	return p.tracksOf(SubtitleTrack)
+}
+
+// tracksOf returns the tracks of the type, UnknownTrack means all the tracks.
+func (p *Parser) tracksOf(trackType TrackType) []Track {
+	if p.m.movie == nil {
+		return nil
+	}
+	var tracks []Track
+	for _, trak := range p.m.movie.trak {
+		if trackType == UnknownTrack || trak.trackType == trackType {
+			track := trak.newTrack()
+			track.Gapless = p.m.gaplessOf(trak)
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
 }
 
 func (p *Parser) GetPacket(trackID int) Packet {
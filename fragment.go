@@ -0,0 +1,71 @@
+package fmp4parser
+
+// trex returns the "trex" of the track fragment. nil if not found.
+func (p *trackFragment) trex() *boxTrex {
+	if p.movie == nil || p.movie.mvex == nil {
+		return nil
+	}
+	for i := range p.movie.mvex.trex {
+		if p.movie.mvex.trex[i].trackId == p.trackID {
+			return &p.movie.mvex.trex[i]
+		}
+	}
+	return nil
+}
+
+// defaultSampleDurationOf returns the default duration of samples: "tfhd" first, then "trex".
+func (p *trackFragment) defaultSampleDurationOf() uint32 {
+	if p.defaultSampleDuration != nil {
+		return *p.defaultSampleDuration
+	}
+	if trex := p.trex(); trex != nil {
+		return trex.defaultSampleDuration
+	}
+	return 0
+}
+
+// defaultSampleSizeOf returns the default size of samples: "tfhd" first, then "trex".
+func (p *trackFragment) defaultSampleSizeOf() uint32 {
+	if p.defaultSampleSize != nil {
+		return *p.defaultSampleSize
+	}
+	if trex := p.trex(); trex != nil {
+		return trex.defaultSampleSize
+	}
+	return 0
+}
+
+// sampleCount returns the number of samples of all the "trun" in the track fragment.
+func (p *trackFragment) sampleCount() uint64 {
+	count := uint64(0)
+	for _, trun := range p.trun {
+		count += uint64(trun.sampleCount)
+	}
+	return count
+}
+
+// duration returns the sum of the sample durations in the track fragment.
+func (p *trackFragment) duration() uint64 {
+	defaultDuration := p.defaultSampleDurationOf()
+	duration := uint64(0)
+	for _, trun := range p.trun {
+		for _, sample := range trun.samples {
+			if sample.sampleDuration != nil {
+				duration += uint64(*sample.sampleDuration)
+			} else {
+				duration += uint64(defaultDuration)
+			}
+		}
+	}
+	return duration
+}
+
+// trackFragment returns the fragment of the track in the movie fragment. nil if not found.
+func (p *movieFragment) trackFragment(trackID uint32) *trackFragment {
+	for _, traf := range p.fragment {
+		if traf.trackID == trackID {
+			return traf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package fmp4parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Gapless is the encoder delay and padding of an audio track. The values are in the time
+// scale of the track, i.e. the number of PCM samples if the time scale is the sample rate.
+type Gapless struct {
+	Priming      uint64 // the leading samples added by the encoder (encoder delay)
+	Padding      uint64 // the trailing samples added by the encoder
+	ValidSamples uint64 // the samples of the original audio, 0 if unknown
+
+	// the roll_distance of "roll" or "prol" sample group, the number of samples (not the
+	// PCM samples) to decode before a sample to get the correct output. 0 if not present.
+	RollDistance int16
+}
+
+// PrimingMode is how the priming and padding samples of Gapless are handled by Parser.Packets.
+type PrimingMode int
+
+const (
+	PrimingKeep PrimingMode = iota // the packets are returned as they are
+	PrimingMark                    // Packet.SkipSamples and Packet.DiscardPadding are set
+	PrimingTrim                    // as PrimingMark, and the packets of which all the samples are discarded are dropped
+)
+
+// iTunSMPB is the gapless information of iTunes, the freeform tag "----" of "com.apple.iTunes"
+// in "moov/udta/meta/ilst", in the output sample rate:
+//
+//	" 00000000 00000840 000001CA 00000000003F31F6 00000000 ..."
+//	  reserved priming  padding  original sample count
+type iTunSMPB struct {
+	priming      uint64
+	padding      uint64
+	validSamples uint64
+}
+
+// parseITunSMPB parses the value of iTunSMPB. nil if it's malformed.
+func parseITunSMPB(value string) *iTunSMPB {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return nil
+	}
+	var values [3]uint64
+	for i := range values {
+		v, err := strconv.ParseUint(fields[i+1], 16, 64)
+		if err != nil {
+			return nil
+		}
+		values[i] = v
+	}
+	return &iTunSMPB{priming: values[0], padding: values[1], validSamples: values[2]}
+}
+
+// parseFreeformTag parses the freeform tag "----" of "ilst", which is the "mean", "name"
+// and "data" boxes.
+func (movie *MovieInfo) parseFreeformTag(r *atomReader) {
+	var mean, name, value string
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			break
+		}
+		switch ar.TypeCC() {
+		case fourCCmean, fourCCname:
+			_, _ = ar.ReadVersionFlags()
+			b := make([]byte, ar.Len())
+			_, _ = ar.ReadBytes(b)
+			if ar.TypeCC() == fourCCmean {
+				mean = string(b)
+			} else {
+				name = string(b)
+			}
+		case fourCCdata:
+			_ = ar.Move(8) // type indicator and locale indicator
+			b := make([]byte, ar.Len())
+			_, _ = ar.ReadBytes(b)
+			value = string(b)
+		}
+	}
+	if mean == "com.apple.iTunes" && name == "iTunSMPB" {
+		movie.iTunSMPB = parseITunSMPB(value)
+	}
+}
+
+// gapless returns the gapless information of the audio track. The sources in order are
+// iTunSMPB, the first media edit of "elst" and the pre-skip of Opus. mediaDuration is
+// the sum of the sample durations. nil if none of them is present.
+func (p *boxTrak) gapless(mediaDuration uint64) *Gapless {
+	if p.trackType != AudioTrack {
+		return nil
+	}
+	g := &Gapless{RollDistance: p.rollDistance()}
+	known := g.RollDistance != 0
+	var edit *Edit
+	timeline := p.timeline()
+	for i := range timeline.Edits {
+		if !timeline.Edits[i].IsEmpty() {
+			edit = &timeline.Edits[i]
+			break
+		}
+	}
+	switch {
+	case p.movie != nil && p.movie.iTunSMPB != nil:
+		// iTunSMPB is in the output sample rate which may be different from the time scale, e.g. HE-AAC
+		sampleRate := p.timeScale
+		if p.audioEntry != nil && p.audioEntry.sampleRate != 0 {
+			sampleRate = p.audioEntry.sampleRate
+		}
+		smpb := p.movie.iTunSMPB
+		g.Priming = rescaleTime(smpb.priming, p.timeScale, sampleRate)
+		g.Padding = rescaleTime(smpb.padding, p.timeScale, sampleRate)
+		g.ValidSamples = rescaleTime(smpb.validSamples, p.timeScale, sampleRate)
+		known = true
+	case edit != nil && edit.MediaTime >= 0 && (edit.MediaTime > 0 || edit.Duration != 0):
+		g.Priming = uint64(edit.MediaTime)
+		if edit.Duration != 0 && !edit.IsDwell() {
+			g.ValidSamples = edit.Duration
+			if mediaDuration > g.Priming+g.ValidSamples {
+				g.Padding = mediaDuration - g.Priming - g.ValidSamples
+			}
+		}
+		known = true
+	case p.audioEntry != nil:
+		if opus, ok := p.audioEntry.decoderDescriptors[AudioCodecOPUS].(*OpusDescriptor); ok && opus.PreSkip != 0 {
+			// the pre-skip is at 48 kHz
+			g.Priming = rescaleTime(uint64(opus.PreSkip), p.timeScale, 48000)
+			known = true
+		}
+	}
+	if !known {
+		return nil
+	}
+	if g.ValidSamples == 0 && mediaDuration > g.Priming+g.Padding {
+		g.ValidSamples = mediaDuration - g.Priming - g.Padding
+	}
+	return g
+}
+
+// rollDistance returns the roll distance of the first entry of "roll" or "prol" sample group.
+func (p *boxTrak) rollDistance() int16 {
+	for _, groupingType := range []uint32{fourCCprol, fourCCroll} {
+		if entry, ok := findSgpd(p.sgpd, groupingType).entry(1).(*RollRecoveryEntry); ok {
+			return entry.RollDistance
+		}
+	}
+	return 0
+}
+
+// gaplessOf returns the gapless information of the track with the samples of "stbl" and
+// the movie fragments.
+func (p *mediaInfo) gaplessOf(trak *boxTrak) *Gapless {
+	mediaDuration := uint64(0)
+	for _, s := range p.sampleTimes(trak) {
+		mediaDuration += uint64(s.duration)
+	}
+	return trak.gapless(mediaDuration)
+}
+
+// apply sets Packet.SkipSamples and Packet.DiscardPadding of the packets in decode order.
+// If trim is true, the packets of which all the samples are discarded are dropped.
+func (p *Gapless) apply(packets []Packet, trim bool) []Packet {
+	validEnd := p.Priming + p.ValidSamples
+	if p.ValidSamples == 0 {
+		validEnd = ^uint64(0)
+	}
+	var result []Packet
+	position := uint64(0)
+	for _, packet := range packets {
+		start, end := position, position+uint64(packet.Duration)
+		position = end
+		if start < p.Priming {
+			packet.SkipSamples = uint32(min(p.Priming, end) - start)
+		}
+		if end > validEnd {
+			packet.DiscardPadding = uint32(end - max(validEnd, start))
+		}
+		if packet.Duration > 0 && packet.SkipSamples+packet.DiscardPadding >= packet.Duration {
+			if trim {
+				continue
+			}
+			packet.DiscardPadding = packet.Duration - packet.SkipSamples
+		}
+		result = append(result, packet)
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseITunSMPB(t *testing.T) {
+	smpb := parseITunSMPB(" 00000000 00000840 000001CA 00000000003F31F6 00000000 00000000")
+	want := &iTunSMPB{priming: 2112, padding: 458, validSamples: 4141558}
+	if !reflect.DeepEqual(smpb, want) {
+		t.Errorf("parseITunSMPB() = %+v, want %+v", smpb, want)
+	}
+	if smpb := parseITunSMPB(" 00000000 0000084G"); smpb != nil {
+		t.Errorf("parseITunSMPB() = %+v, want nil", smpb)
+	}
+}
+
+func TestGapless_apply(t *testing.T) {
+	packets := []Packet{{Duration: 1024}, {Duration: 1024}, {Duration: 1024}, {Duration: 1024}}
+	g := &Gapless{Priming: 1500, Padding: 596, ValidSamples: 2000}
+	marked := g.apply(packets, false)
+	want := [][2]uint32{{1024, 0}, {476, 0}, {0, 0}, {0, 596}}
+	if len(marked) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(marked), len(want))
+	}
+	for i := range want {
+		if got := [2]uint32{marked[i].SkipSamples, marked[i].DiscardPadding}; got != want[i] {
+			t.Errorf("packet %d: skip, discard = %v, want %v", i, got, want[i])
+		}
+	}
+	if trimmed := g.apply(packets, true); len(trimmed) != 3 || trimmed[0].SkipSamples != 476 {
+		t.Errorf("unexpected trimmed packets %+v", trimmed)
+	}
+}
+
+// mkAudioFile builds a non-fragmented file of one audio track of 4 samples, the duration of
+// each sample is 1024. udta is the boxes of "moov/udta".
+func mkAudioFile(edts []byte, udta ...[]byte) []byte {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(4096), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(48000), u32(4096), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("soun"), make([]byte, 12), []byte("audio\x00"))
+	stbl := mkBox("stbl",
+		mkFullBox("stsd", 0, 0, u32(0)),
+		mkFullBox("stts", 0, 0, u32(1), u32(4), u32(1024)),
+		mkFullBox("stsc", 0, 0, u32(2), u32(1), u32(3), u32(1), u32(2), u32(1), u32(1)),
+		mkFullBox("stsz", 0, 0, u32(0), u32(4), u32(10), u32(20), u32(30), u32(40)),
+		mkFullBox("stco", 0, 0, u32(2), u32(1000), u32(2000)))
+	mdia := mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl))
+	trak := mkBox("trak", tkhd, mdia)
+	if edts != nil {
+		trak = mkBox("trak", tkhd, edts, mdia)
+	}
+	mvhd := mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(48000), u32(4096), make([]byte, 80))
+	moov := mkBox("moov", mvhd, trak)
+	if len(udta) > 0 {
+		moov = mkBox("moov", mvhd, trak, mkBox("udta", udta...))
+	}
+	ftyp := mkBox("ftyp", []byte("M4A "), u32(0), []byte("M4A "))
+	return append(ftyp, moov...)
+}
+
+func TestParser_Gapless(t *testing.T) {
+	tag := mkBox("----",
+		mkFullBox("mean", 0, 0, []byte("com.apple.iTunes")),
+		mkFullBox("name", 0, 0, []byte("iTunSMPB")),
+		mkBox("data", u32(1), u32(0), []byte(" 00000000 000005DC 00000254 00000000000007D0")))
+	meta := mkFullBox("meta", 0, 0, mkFullBox("hdlr", 0, 0, u32(0), []byte("mdir"), make([]byte, 12), u8(0)), mkBox("ilst", tag))
+	p := newTestParser(t, mkAudioFile(nil, meta))
+	tracks := p.GetTracks()
+	if len(tracks) != 1 {
+		t.Fatalf("got %d tracks, want 1", len(tracks))
+	}
+	if want := (&Gapless{Priming: 1500, Padding: 596, ValidSamples: 2000}); !reflect.DeepEqual(tracks[0].Gapless, want) {
+		t.Errorf("Gapless = %+v, want %+v", tracks[0].Gapless, want)
+	}
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := []uint64{1000, 1010, 1030, 2000}
+	if len(packets) != len(offsets) {
+		t.Fatalf("got %d packets, want %d", len(packets), len(offsets))
+	}
+	for i, offset := range offsets {
+		if packets[i].offset != offset || packets[i].DTS != uint64(i*1024) {
+			t.Errorf("packet %d: offset %d, DTS %d, want %d, %d", i, packets[i].offset, packets[i].DTS, offset, i*1024)
+		}
+	}
+	if packets, _ := p.Packets(1, PrimingTrim); len(packets) != 3 || packets[0].SkipSamples != 476 || packets[2].DiscardPadding != 596 {
+		t.Errorf("unexpected trimmed packets %+v", packets)
+	}
+
+	// the edit list, the media of [1500, 3500) is presented
+	elst := mkFullBox("elst", 0, 0, u32(1), u32(2000), u32(1500), u16(1), u16(0))
+	p = newTestParser(t, mkAudioFile(mkBox("edts", elst)))
+	if want := (&Gapless{Priming: 1500, Padding: 596, ValidSamples: 2000}); !reflect.DeepEqual(p.GetTracks()[0].Gapless, want) {
+		t.Errorf("Gapless = %+v, want %+v", p.GetTracks()[0].Gapless, want)
+	}
+}
@@ -0,0 +1,195 @@
+package fmp4parser
+
+// Chromaticity is the CIE 1931 xy chromaticity coordinate.
+type Chromaticity struct {
+	X float64
+	Y float64
+}
+
+// MasteringDisplayColourVolume is the colour volume of the mastering display, SMPTE ST 2086.
+type MasteringDisplayColourVolume struct {
+	Primaries    [3]Chromaticity // red, green, blue
+	WhitePoint   Chromaticity
+	MaxLuminance float64 // in cd/m2
+	MinLuminance float64 // in cd/m2
+}
+
+// ContentLightLevel is the content light level information, CTA-861.3.
+type ContentLightLevel struct {
+	MaxCLL  uint16 // maximum content light level in cd/m2
+	MaxFALL uint16 // maximum frame-average light level in cd/m2
+}
+
+// HDRFormat is the HDR format of a video track.
+type HDRFormat uint32
+
+const (
+	HDRFormatSDR HDRFormat = iota
+	HDRFormatHDR10
+	HDRFormatHDR10Plus // HDR10 with SMPTE ST 2094-40 dynamic metadata
+	HDRFormatHLG
+	HDRFormatDolbyVision
+)
+
+// human-readable HDR format
+var hdrFormatString = map[HDRFormat]string{
+	HDRFormatSDR:         "SDR",
+	HDRFormatHDR10:       "HDR10",
+	HDRFormatHDR10Plus:   "HDR10+",
+	HDRFormatHLG:         "HLG",
+	HDRFormatDolbyVision: "Dolby Vision",
+}
+
+func (p HDRFormat) String() string {
+	return hdrFormatString[p]
+}
+
+// TransferFunction is the classification of transfer_characteristics.
+type TransferFunction uint32
+
+const (
+	TransferSDR TransferFunction = iota // BT.709, BT.601, sRGB, etc.
+	TransferPQ                          // SMPTE ST 2084, transfer_characteristics 16
+	TransferHLG                         // ARIB STD-B67, transfer_characteristics 18
+)
+
+const (
+	transferCharacteristicsPQ  = 16
+	transferCharacteristicsHLG = 18
+)
+
+// HDRInfo is the HDR and colour information of a video track.
+type HDRInfo struct {
+	Format                  HDRFormat
+	Transfer                TransferFunction
+	ColourPrimaries         uint16 // ISO/IEC 23091-2 (CICP), 2 (unspecified) if unknown
+	TransferCharacteristics uint16
+	MatrixCoefficients      uint16
+	FullRange               bool
+
+	MasteringDisplay *MasteringDisplayColourVolume // "mdcv", "SmDm" or the metadata OBU of AV1
+	ContentLight     *ContentLightLevel            // "clli", "CoLL" or the metadata OBU of AV1
+	DolbyVision      *DvcConfig                    // "dvcC", "dvvC" or "dvwC"
+}
+
+// parseMdcv parses MasteringDisplayColourVolumeBox, refer to ISO/IEC 23001-17. The chromaticity
+// coordinates are in units of 0.00002 in the order of green, blue and red, and the luminance
+// is in units of 0.0001 cd/m2 like the SEI message of HEVC.
+func parseMdcv(r *atomReader) *MasteringDisplayColourVolume {
+	mdcv := new(MasteringDisplayColourVolume)
+	for _, i := range []int{1, 2, 0} {
+		mdcv.Primaries[i].X = float64(r.Read2()) * 0.00002
+		mdcv.Primaries[i].Y = float64(r.Read2()) * 0.00002
+	}
+	mdcv.WhitePoint.X = float64(r.Read2()) * 0.00002
+	mdcv.WhitePoint.Y = float64(r.Read2()) * 0.00002
+	mdcv.MaxLuminance = float64(r.Read4()) * 0.0001
+	mdcv.MinLuminance = float64(r.Read4()) * 0.0001
+	return mdcv
+}
+
+// parseSmDm parses SMPTE2086MasteringDisplayMetadataBox of VP9, refer to https://www.webmproject.org/vp9/mp4/.
+// The chromaticity coordinates are 0.16 fixed-point, the luminance is 24.8 (max) and 18.14 (min) fixed-point.
+func parseSmDm(r *atomReader) *MasteringDisplayColourVolume {
+	_, _ = r.ReadVersionFlags()
+	mdcv := new(MasteringDisplayColourVolume)
+	for i := range mdcv.Primaries {
+		mdcv.Primaries[i].X = float64(r.Read2()) / (1 << 16)
+		mdcv.Primaries[i].Y = float64(r.Read2()) / (1 << 16)
+	}
+	mdcv.WhitePoint.X = float64(r.Read2()) / (1 << 16)
+	mdcv.WhitePoint.Y = float64(r.Read2()) / (1 << 16)
+	mdcv.MaxLuminance = float64(r.Read4()) / (1 << 8)
+	mdcv.MinLuminance = float64(r.Read4()) / (1 << 14)
+	return mdcv
+}
+
+// parseClli parses ContentLightLevelBox "clli", or ContentLightLevelBox "CoLL" of VP9 which is a FullBox.
+func parseClli(r *atomReader, fullBox bool) *ContentLightLevel {
+	if fullBox {
+		_, _ = r.ReadVersionFlags()
+	}
+	return &ContentLightLevel{MaxCLL: r.Read2(), MaxFALL: r.Read2()}
+}
+
+// isHDR10Plus returns whether the metadata is ITU-T T.35 of SMPTE ST 2094-40: the country code
+// is USA (0xB5), the provider code is Samsung (0x003C) and the application identifier is 4.
+func (p *Av1Metadata) isHDR10Plus() bool {
+	return p.Type == Av1MetadataItutT35 && p.CountryCode == 0xB5 && len(p.Payload) >= 5 &&
+		p.Payload[0] == 0x00 && p.Payload[1] == 0x3C && p.Payload[2] == 0x00 && p.Payload[3] == 0x01 && p.Payload[4] == 4
+}
+
+// hdrInfo returns the HDR information of the sample entry. The colour description of "colr" is
+// preferred, then the ones of the codec configuration. nil if the video is SDR.
+func (p *videoSampleEntry) hdrInfo() *HDRInfo {
+	if p == nil {
+		return nil
+	}
+	info := &HDRInfo{ColourPrimaries: 2, TransferCharacteristics: 2, MatrixCoefficients: 2,
+		MasteringDisplay: p.masteringDisplay, ContentLight: p.contentLight}
+	hdr10Plus := false
+	if p.colourType == colourTypeNCLX {
+		info.ColourPrimaries, info.TransferCharacteristics, info.MatrixCoefficients = p.colorPrimaries,
+			p.transferCharacteristics, p.matrixCoefficients
+		info.FullRange = p.fullRangeFlag
+	} else {
+		if sps := p.sequenceParameterSet(); sps != nil && sps.ColourDescription {
+			info.ColourPrimaries, info.TransferCharacteristics, info.MatrixCoefficients = uint16(sps.ColourPrimaries),
+				uint16(sps.TransferCharacteristics), uint16(sps.MatrixCoefficients)
+			info.FullRange = sps.VideoFullRange
+		}
+		for _, codec := range []CodecType{VideoCodecVP9, VideoCodecVP8} {
+			if vpc, ok := p.decoderConfigurationRecords[codec].(*VpcConfig); ok {
+				info.ColourPrimaries, info.TransferCharacteristics, info.MatrixCoefficients = uint16(vpc.ColourPrimaries),
+					uint16(vpc.TransferCharacteristics), uint16(vpc.MatrixCoefficients)
+				info.FullRange = vpc.VideoFullRangeFlag == 1
+			}
+		}
+		if av1c, ok := p.decoderConfigurationRecords[VideoCodecAV1].(*Av1cConfig); ok && av1c.SequenceHeader != nil &&
+			av1c.SequenceHeader.ColorDescriptionPresent {
+			seq := av1c.SequenceHeader
+			info.ColourPrimaries, info.TransferCharacteristics, info.MatrixCoefficients = uint16(seq.ColorPrimaries),
+				uint16(seq.TransferCharacteristics), uint16(seq.MatrixCoefficients)
+			info.FullRange = seq.FullRange
+		}
+	}
+	if av1c, ok := p.decoderConfigurationRecords[VideoCodecAV1].(*Av1cConfig); ok {
+		for _, m := range av1c.Metadata {
+			if m.MasteringDisplay != nil && info.MasteringDisplay == nil {
+				info.MasteringDisplay = m.MasteringDisplay
+			}
+			if m.ContentLight != nil && info.ContentLight == nil {
+				info.ContentLight = m.ContentLight
+			}
+			hdr10Plus = hdr10Plus || m.isHDR10Plus()
+		}
+	}
+	switch info.TransferCharacteristics {
+	case transferCharacteristicsPQ:
+		info.Transfer = TransferPQ
+		info.Format = HDRFormatHDR10
+		if hdr10Plus {
+			info.Format = HDRFormatHDR10Plus
+		}
+	case transferCharacteristicsHLG:
+		info.Transfer = TransferHLG
+		info.Format = HDRFormatHLG
+	}
+	if dvc, ok := p.decoderConfigurationRecords[VideoCodecDolbyVision].(*DvcConfig); ok {
+		info.DolbyVision = dvc
+		info.Format = HDRFormatDolbyVision
+		if info.TransferCharacteristics == 2 {
+			// the transfer of the base layer is implied by dv_bl_signal_compatibility_id
+			switch dvc.DvBlSingalCompatibilityId {
+			case 0, 1, 6:
+				info.Transfer = TransferPQ
+			case 4:
+				info.Transfer = TransferHLG
+			}
+		}
+	}
+	if info.Format == HDRFormatSDR && info.MasteringDisplay == nil && info.ContentLight == nil {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,71 @@
+package fmp4parser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrack_HDRInfo(t *testing.T) {
+	hvcC := mkBox("hvcC", []byte{1, 0x02}, u32(0x20000000), make([]byte, 6), []byte{120, 0xF0, 0, 0xFC, 0xFD, 0xFA, 0xFA},
+		u16(0), []byte{0x0F, 0})
+	colr := func(primaries, transfer, matrix uint16) []byte {
+		return mkBox("colr", []byte("nclx"), u16(primaries), u16(transfer), u16(matrix), []byte{0})
+	}
+	mdcv := mkBox("mdcv", u16(8500), u16(39850), u16(6550), u16(2300), u16(35400), u16(14600),
+		u16(15635), u16(16450), u32(10000000), u32(50))
+	clli := mkBox("clli", u16(1000), u16(400))
+	vpcC := func(transfer uint8) []byte {
+		return mkFullBox("vpcC", 1, 0, []byte{2, 40, 0xA2, 9, transfer, 9}, u16(0))
+	}
+	smdm := mkFullBox("SmDm", 0, 0, u16(46399), u16(19137), u16(11141), u16(52232), u16(8585), u16(3015),
+		u16(20493), u16(21561), u32(1000<<8), u32(82))
+	coll := mkFullBox("CoLL", 0, 0, u16(1000), u16(400))
+	dvvC := mkBox("dvvC", []byte{1, 0, 8<<1 | 0, 6<<3 | 0x05, 4 << 4}, make([]byte, 19)) // profile 8.4
+
+	tests := []struct {
+		name     string
+		entry    []byte
+		format   HDRFormat
+		transfer TransferFunction
+		metadata bool
+	}{
+		{"sdr", mkVideoEntry("hvc1", 1920, 1080, hvcC, colr(1, 1, 1)), HDRFormatSDR, TransferSDR, false},
+		{"hdr10", mkVideoEntry("hvc1", 3840, 2160, hvcC, colr(9, 16, 9), mdcv, clli), HDRFormatHDR10, TransferPQ, true},
+		{"vp9 hlg", mkVideoEntry("vp09", 3840, 2160, vpcC(18)), HDRFormatHLG, TransferHLG, false},
+		{"vp9 pq", mkVideoEntry("vp09", 3840, 2160, vpcC(16), smdm, coll), HDRFormatHDR10, TransferPQ, true},
+		{"dolby vision", mkVideoEntry("dvh1", 3840, 2160, hvcC, dvvC, colr(9, 18, 9)), HDRFormatDolbyVision, TransferHLG, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, mkVideoInit(tt.entry))
+			info := p.GetTracks()[0].HDRInfo
+			if tt.format == HDRFormatSDR {
+				if info != nil {
+					t.Errorf("got %+v, want nil", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("HDRInfo is nil")
+			}
+			if info.Format != tt.format || info.Transfer != tt.transfer {
+				t.Errorf("got %s/%d, want %s/%d", info.Format, info.Transfer, tt.format, tt.transfer)
+			}
+			if !tt.metadata {
+				return
+			}
+			m, c := info.MasteringDisplay, info.ContentLight
+			if m == nil || c == nil {
+				t.Fatalf("mastering display %v, content light %v", m, c)
+			}
+			near := func(a, b float64) bool { return math.Abs(a-b) < 0.001 }
+			if !near(m.Primaries[0].X, 0.708) || !near(m.Primaries[1].Y, 0.797) || !near(m.Primaries[2].X, 0.131) ||
+				!near(m.WhitePoint.X, 0.3127) || !near(m.MaxLuminance, 1000) || !near(m.MinLuminance, 0.005) {
+				t.Errorf("mastering display = %+v", m)
+			}
+			if c.MaxCLL != 1000 || c.MaxFALL != 400 {
+				t.Errorf("content light = %+v", c)
+			}
+		})
+	}
+}
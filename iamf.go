@@ -0,0 +1,365 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/*
+IAMF (Immersive Audio Model and Formats) of AOMedia, refer to
+	https://aomediacodec.github.io/iamf/
+
+The "iacb" box of the "iamf" sample entry carries the descriptor OBUs: the IA sequence
+header, the codec configs, the audio elements and the mix presentations.
+*/
+
+// obu_type of IAMF OBUs
+const (
+	IamfOBUCodecConfig       = 0
+	IamfOBUAudioElement      = 1
+	IamfOBUMixPresentation   = 2
+	IamfOBUParameterBlock    = 3
+	IamfOBUTemporalDelimiter = 4
+	IamfOBUAudioFrame        = 5
+	IamfOBUSequenceHeader    = 31
+)
+
+// audio_element_type of the audio element OBU
+const (
+	IamfChannelBased = 0
+	IamfSceneBased   = 1
+)
+
+// param_definition_type of the audio element OBU
+const (
+	iamfParameterMixGain   = 0
+	iamfParameterDemixing  = 1
+	iamfParameterReconGain = 2
+)
+
+const (
+	iamfLoudspeakerExpanded = 15 // loudspeaker_layout of the expanded layouts
+	iamfLayoutSoundSystem   = 2  // layout_type of the loudspeaker layouts of ITU-R BS.2051
+)
+
+var errInvalidIamfOBU = errors.New("invalid IAMF OBU")
+
+// IamfDescriptor IAMF Descriptor of "iacb"
+type IamfDescriptor struct {
+	ConfigurationVersion uint8
+	PrimaryProfile       uint8 // of the IA sequence header, 0 is simple, 1 is base and 2 is base-enhanced
+	AdditionalProfile    uint8
+	CodecConfigs         []IamfCodecConfig
+	AudioElements        []IamfAudioElement
+	MixPresentations     []IamfMixPresentation
+	DecoderSpecificInfo  []byte // configOBUs
+}
+
+// IamfCodecConfig is a codec config OBU.
+type IamfCodecConfig struct {
+	ID                 uint32
+	CodecID            string // "Opus", "mp4a", "fLaC" or "ipcm"
+	NumSamplesPerFrame uint32
+	AudioRollDistance  int16
+	DecoderConfig      []byte // the configuration of the codec, e.g. "dOps" without the box header for Opus
+}
+
+// IamfAudioElement is an audio element OBU.
+type IamfAudioElement struct {
+	ID            uint32
+	Type          uint8 // IamfChannelBased or IamfSceneBased
+	CodecConfigID uint32
+	SubstreamIDs  []uint32
+
+	Layers []IamfChannelLayer // the scalable channel layout of IamfChannelBased
+
+	// the ambisonics of IamfSceneBased
+	AmbisonicsMode     uint32 // 0 is mono, 1 is projection
+	OutputChannelCount uint8
+}
+
+// IamfChannelLayer is a layer of the scalable channel layout.
+type IamfChannelLayer struct {
+	LoudspeakerLayout         uint8 // 0 is mono, 1 is stereo, 2 is 5.1, ... 15 is expanded
+	ExpandedLoudspeakerLayout uint8 // only if LoudspeakerLayout is 15
+	SubstreamCount            uint8
+	CoupledSubstreamCount     uint8
+	OutputGain                int16 // Q7.8 in dB, 0 if not present
+}
+
+// IamfMixPresentation is a mix presentation OBU.
+type IamfMixPresentation struct {
+	ID        uint32
+	Languages []string // language_label, BCP 47
+	Labels    []string // mix_presentation_friendly_label for each language
+	SubMixes  []IamfSubMix
+}
+
+// IamfSubMix is a sub-mix of the mix presentation.
+type IamfSubMix struct {
+	AudioElementIDs []uint32
+	Layouts         []IamfLayout // the layouts which the loudness is measured of
+}
+
+// IamfLayout is the layout and the loudness of a sub-mix.
+type IamfLayout struct {
+	LayoutType         uint8 // 2 is the loudspeakers of ITU-R BS.2051, 3 is binaural
+	SoundSystem        uint8 // only if LayoutType is 2
+	IntegratedLoudness int16 // Q7.8 in LKFS
+	DigitalPeak        int16 // Q7.8 in dBFS
+}
+
+// iamfReader reads the fields of IAMF OBUs, the error is kept and the values are 0 after it.
+type iamfReader struct {
+	b   []byte
+	err error
+}
+
+func (r *iamfReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.b) || n < 0 {
+		r.err = errInvalidIamfOBU
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *iamfReader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *iamfReader) i16() int16 {
+	if b := r.bytes(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *iamfReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *iamfReader) leb128() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	v, n, err := readLeb128(r.b)
+	if err != nil || v > 0xFFFFFFFF {
+		r.err = errInvalidIamfOBU
+		return 0
+	}
+	r.b = r.b[n:]
+	return uint32(v)
+}
+
+// string reads a null terminated string of 128 bytes at most.
+func (r *iamfReader) string() string {
+	for i := 0; i < len(r.b) && i < 128; i++ {
+		if r.b[i] == 0 {
+			s := string(r.b[:i])
+			r.b = r.b[i+1:]
+			return s
+		}
+	}
+	r.err = errInvalidIamfOBU
+	return ""
+}
+
+/*
+parseDescriptor parses iacb, refer to IAMF 6.2.3
+
+	class IAConfigurationBox extends Box('iacb') {
+		unsigned int(8) configurationVersion = 1;
+		leb128() configOBUs_size;
+		unsigned int(8) configOBUs[configOBUs_size];
+	}
+*/
+func (p *IamfDescriptor) parseDescriptor(r *atomReader) error {
+	if r.Size() < 2 {
+		return fmt.Errorf("%w : IamfDescriptor", ErrInvalidAtomSize)
+	}
+	p.ConfigurationVersion = r.ReadUnsignedByte()
+	body := make([]byte, r.Len())
+	_, _ = r.ReadBytes(body)
+	size, n, err := readLeb128(body)
+	if err != nil || size > uint64(len(body)-n) {
+		return fmt.Errorf("%w : IamfDescriptor.configOBUs", ErrIncompleteBox)
+	}
+	p.DecoderSpecificInfo = body[n : n+int(size)]
+	return p.parseOBUs(p.DecoderSpecificInfo)
+}
+
+// parseOBUs parses the descriptor OBUs, the other OBUs are skipped.
+func (p *IamfDescriptor) parseOBUs(data []byte) error {
+	r := &iamfReader{b: data}
+	for len(r.b) > 0 && r.err == nil {
+		header := r.u8()
+		payload := &iamfReader{b: r.bytes(int(r.leb128()))}
+		if r.err != nil {
+			break
+		}
+		if header&0x02 != 0 { // obu_trimming_status_flag
+			_ = payload.leb128() // num_samples_to_trim_at_end
+			_ = payload.leb128() // num_samples_to_trim_at_start
+		}
+		if header&0x01 != 0 { // obu_extension_flag
+			_ = payload.bytes(int(payload.leb128()))
+		}
+		switch header >> 3 {
+		case IamfOBUSequenceHeader:
+			if string(payload.bytes(4)) != "iamf" {
+				return fmt.Errorf("%w : ia_code", errInvalidIamfOBU)
+			}
+			p.PrimaryProfile = payload.u8()
+			p.AdditionalProfile = payload.u8()
+		case IamfOBUCodecConfig:
+			config := IamfCodecConfig{ID: payload.leb128()}
+			config.CodecID = int2String(payload.u32())
+			config.NumSamplesPerFrame = payload.leb128()
+			config.AudioRollDistance = payload.i16()
+			config.DecoderConfig = payload.b
+			p.CodecConfigs = append(p.CodecConfigs, config)
+		case IamfOBUAudioElement:
+			p.AudioElements = append(p.AudioElements, parseIamfAudioElement(payload))
+		case IamfOBUMixPresentation:
+			p.MixPresentations = append(p.MixPresentations, parseIamfMixPresentation(payload))
+		}
+		if payload.err != nil {
+			return payload.err
+		}
+	}
+	return r.err
+}
+
+// parseIamfParamDefinition skips ParamDefinition, refer to IAMF 3.6.1
+func parseIamfParamDefinition(r *iamfReader) {
+	_ = r.leb128() // parameter_id
+	_ = r.leb128() // parameter_rate
+	// param_definition_mode is 1 if the durations are in the parameter blocks
+	if r.u8()&0x80 != 0 {
+		return
+	}
+	_ = r.leb128() // duration
+	// constant_subblock_duration, the subblock durations follow if it's 0
+	if r.leb128() != 0 {
+		return
+	}
+	for n := r.leb128(); n > 0 && r.err == nil; n-- {
+		_ = r.leb128() // subblock_duration
+	}
+}
+
+// parseIamfAudioElement parses the audio element OBU, refer to IAMF 3.6
+func parseIamfAudioElement(r *iamfReader) IamfAudioElement {
+	e := IamfAudioElement{ID: r.leb128()}
+	e.Type = r.u8() >> 5
+	e.CodecConfigID = r.leb128()
+	for n := r.leb128(); n > 0 && r.err == nil; n-- {
+		e.SubstreamIDs = append(e.SubstreamIDs, r.leb128())
+	}
+	for n := r.leb128(); n > 0 && r.err == nil; n-- {
+		switch r.leb128() {
+		case iamfParameterDemixing:
+			parseIamfParamDefinition(r)
+			_ = r.bytes(2) // dmixp_mode, default_w
+		case iamfParameterReconGain:
+			parseIamfParamDefinition(r)
+		default:
+			_ = r.bytes(int(r.leb128()))
+		}
+	}
+	switch e.Type {
+	case IamfChannelBased:
+		numLayers := r.u8() >> 5
+		for i := uint8(0); i < numLayers && r.err == nil; i++ {
+			b := r.u8()
+			layer := IamfChannelLayer{LoudspeakerLayout: b >> 4}
+			layer.SubstreamCount = r.u8()
+			layer.CoupledSubstreamCount = r.u8()
+			if b&0x08 != 0 { // output_gain_is_present_flag
+				_ = r.u8() // output_gain_flag
+				layer.OutputGain = r.i16()
+			}
+			if layer.LoudspeakerLayout == iamfLoudspeakerExpanded {
+				layer.ExpandedLoudspeakerLayout = r.u8()
+			}
+			e.Layers = append(e.Layers, layer)
+		}
+	case IamfSceneBased:
+		e.AmbisonicsMode = r.leb128()
+		e.OutputChannelCount = r.u8()
+	}
+	return e
+}
+
+// parseIamfMixPresentation parses the mix presentation OBU, refer to IAMF 3.7
+func parseIamfMixPresentation(r *iamfReader) IamfMixPresentation {
+	m := IamfMixPresentation{ID: r.leb128()}
+	countLabel := r.leb128()
+	for i := uint32(0); i < countLabel && r.err == nil; i++ {
+		m.Languages = append(m.Languages, r.string())
+	}
+	for i := uint32(0); i < countLabel && r.err == nil; i++ {
+		m.Labels = append(m.Labels, r.string())
+	}
+	for n := r.leb128(); n > 0 && r.err == nil; n-- {
+		var subMix IamfSubMix
+		for n := r.leb128(); n > 0 && r.err == nil; n-- {
+			subMix.AudioElementIDs = append(subMix.AudioElementIDs, r.leb128())
+			for i := uint32(0); i < countLabel && r.err == nil; i++ {
+				_ = r.string() // audio_element_friendly_label
+			}
+			_ = r.u8()                   // headphones_rendering_mode
+			_ = r.bytes(int(r.leb128())) // rendering_config_extension
+			parseIamfParamDefinition(r)  // element_mix_gain
+			_ = r.i16()                  // default_mix_gain
+		}
+		parseIamfParamDefinition(r) // output_mix_gain
+		_ = r.i16()
+		for n := r.leb128(); n > 0 && r.err == nil; n-- {
+			b := r.u8()
+			layout := IamfLayout{LayoutType: b >> 6}
+			if layout.LayoutType == iamfLayoutSoundSystem {
+				layout.SoundSystem = b >> 2 & 0xF
+			}
+			infoType := r.u8()
+			layout.IntegratedLoudness = r.i16()
+			layout.DigitalPeak = r.i16()
+			if infoType&0x01 != 0 {
+				_ = r.i16() // true_peak
+			}
+			if infoType&0x02 != 0 {
+				_ = r.bytes(3 * int(r.u8())) // anchor_element, anchored_loudness
+			}
+			if infoType&0xFC != 0 {
+				_ = r.bytes(int(r.leb128()))
+			}
+			subMix.Layouts = append(subMix.Layouts, layout)
+		}
+		m.SubMixes = append(m.SubMixes, subMix)
+	}
+	return m
+}
+
+// codecString returns the codecs parameter of IAMF, refer to IAMF 6.4: "iamf", the primary
+// and the additional profile in 3 digits and the codec of the first codec config, e.g.
+// "iamf.000.000.Opus" or "iamf.001.001.mp4a.40.2".
+func (p *IamfDescriptor) codecString() string {
+	s := fmt.Sprintf("iamf.%03d.%03d", p.PrimaryProfile, p.AdditionalProfile)
+	if len(p.CodecConfigs) == 0 {
+		return s
+	}
+	codec := p.CodecConfigs[0].CodecID
+	if codec == "mp4a" {
+		codec = "mp4a.40.2" // AAC-LC is the only AAC profile of IAMF
+	}
+	return s + "." + codec
+}
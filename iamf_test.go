@@ -0,0 +1,63 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mkIamfOBU builds an IAMF OBU without the trimming and the extension.
+func mkIamfOBU(obuType uint8, payload ...byte) []byte {
+	return append([]byte{obuType << 3, byte(len(payload))}, payload...)
+}
+
+func TestIamfDescriptor(t *testing.T) {
+	var obus []byte
+	obus = append(obus, mkIamfOBU(IamfOBUSequenceHeader, 'i', 'a', 'm', 'f', 1, 1)...)
+	// codec_config_id 0, "Opus", 960 samples, audio_roll_distance -4, a part of dOps
+	obus = append(obus, mkIamfOBU(IamfOBUCodecConfig, 0, 'O', 'p', 'u', 's', 0xC0, 0x07, 0xFF, 0xFC, 0, 2)...)
+	// audio_element_id 1, channel-based, codec_config_id 0, 2 substreams, a recon gain parameter,
+	// 2 layers: stereo of 1 coupled substream and 5.1.2 with output gain -1 dB
+	obus = append(obus, mkIamfOBU(IamfOBUAudioElement, 1, 0x00, 0, 2, 10, 11,
+		1, iamfParameterReconGain, 5, 0x80, 0xF7, 0x02, 0x80,
+		0x40, 0x10, 1, 1, 0x38, 4, 3, 0x80, 0xFF, 0x00)...)
+	// mix_presentation_id 2, "en-us" of "Main", a sub-mix of the audio element 1 and a stereo layout
+	obus = append(obus, mkIamfOBU(IamfOBUMixPresentation, 2, 1, 'e', 'n', '-', 'u', 's', 0, 'M', 'a', 'i', 'n', 0,
+		1, 1, 1, 'S', 0, 0, 0, 6, 0x80, 0xF7, 0x02, 0x80, 0, 0, 7, 0x80, 0xF7, 0x02, 0x80, 0, 0,
+		1, 0x80, 0x00, 0xE8, 0x00, 0xFF, 0x00)...)
+	obus = append(obus, mkIamfOBU(IamfOBUTemporalDelimiter)...)
+	iacb := mkBox("iacb", []byte{1, byte(len(obus))}, obus)
+
+	p := newTestParser(t, mkAudioInit(mkAudioEntry("iamf", 0, 0, iacb)))
+	track := p.GetTracks()[0]
+	if track.Type != AudioTrack || track.Codec != AudioCodecIAMF {
+		t.Fatalf("track = %v %v, want IAMF audio", track.Type, track.Codec)
+	}
+	iamf, ok := track.audioEntry.decoderDescriptors[AudioCodecIAMF].(*IamfDescriptor)
+	if !ok {
+		t.Fatal("no IamfDescriptor")
+	}
+	if iamf.PrimaryProfile != 1 || iamf.AdditionalProfile != 1 {
+		t.Errorf("profiles = %d %d, want 1 1", iamf.PrimaryProfile, iamf.AdditionalProfile)
+	}
+	wantConfig := []IamfCodecConfig{{ID: 0, CodecID: "Opus", NumSamplesPerFrame: 960, AudioRollDistance: -4, DecoderConfig: []byte{0, 2}}}
+	if !reflect.DeepEqual(iamf.CodecConfigs, wantConfig) {
+		t.Errorf("CodecConfigs = %+v", iamf.CodecConfigs)
+	}
+	wantElement := []IamfAudioElement{{ID: 1, Type: IamfChannelBased, SubstreamIDs: []uint32{10, 11}, Layers: []IamfChannelLayer{
+		{LoudspeakerLayout: 1, SubstreamCount: 1, CoupledSubstreamCount: 1},
+		{LoudspeakerLayout: 3, SubstreamCount: 4, CoupledSubstreamCount: 3, OutputGain: -256},
+	}}}
+	if !reflect.DeepEqual(iamf.AudioElements, wantElement) {
+		t.Errorf("AudioElements = %+v", iamf.AudioElements)
+	}
+	wantMix := []IamfMixPresentation{{ID: 2, Languages: []string{"en-us"}, Labels: []string{"Main"}, SubMixes: []IamfSubMix{{
+		AudioElementIDs: []uint32{1},
+		Layouts:         []IamfLayout{{LayoutType: 2, SoundSystem: 0, IntegratedLoudness: -6144, DigitalPeak: -256}},
+	}}}}
+	if !reflect.DeepEqual(iamf.MixPresentations, wantMix) {
+		t.Errorf("MixPresentations = %+v", iamf.MixPresentations)
+	}
+	if got := track.CodecString(); got != "iamf.001.001.Opus" {
+		t.Errorf("CodecString() = %v, want iamf.001.001.Opus", got)
+	}
+}
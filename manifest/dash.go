@@ -0,0 +1,302 @@
+package manifest
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// DASH MPD elements, refer to ISO/IEC 23009-1.
+type mpd struct {
+	XMLName                   xml.Name  `xml:"MPD"`
+	Xmlns                     string    `xml:"xmlns,attr"`
+	XmlnsCenc                 string    `xml:"xmlns:cenc,attr,omitempty"`
+	Profiles                  string    `xml:"profiles,attr"`
+	Type                      string    `xml:"type,attr"`
+	MediaPresentationDuration string    `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string    `xml:"minBufferTime,attr"`
+	Period                    mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ContentType        string                 `xml:"contentType,attr"`
+	MimeType           string                 `xml:"mimeType,attr"`
+	Lang               string                 `xml:"lang,attr,omitempty"`
+	SegmentAlignment   bool                   `xml:"segmentAlignment,attr"`
+	StartWithSAP       int                    `xml:"startWithSAP,attr,omitempty"`
+	ContentProtections []mpdContentProtection `xml:"ContentProtection"`
+	Representations    []mpdRepresentation    `xml:"Representation"`
+}
+
+type mpdContentProtection struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr,omitempty"`
+	DefaultKID  string `xml:"cenc:default_KID,attr,omitempty"`
+	PSSH        string `xml:"cenc:pssh,omitempty"`
+}
+
+type mpdDescriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+type mpdRepresentation struct {
+	ID                        string          `xml:"id,attr"`
+	Bandwidth                 uint64          `xml:"bandwidth,attr"`
+	Codecs                    string          `xml:"codecs,attr,omitempty"`
+	Width                     uint32          `xml:"width,attr,omitempty"`
+	Height                    uint32          `xml:"height,attr,omitempty"`
+	FrameRate                 string          `xml:"frameRate,attr,omitempty"`
+	AudioSamplingRate         uint32          `xml:"audioSamplingRate,attr,omitempty"`
+	AudioChannelConfiguration *mpdDescriptor  `xml:"AudioChannelConfiguration"`
+	BaseURL                   string          `xml:"BaseURL,omitempty"`
+	SegmentBase               *mpdSegmentBase `xml:"SegmentBase"`
+	SegmentList               *mpdSegmentList `xml:"SegmentList"`
+}
+
+type mpdURL struct {
+	SourceURL string `xml:"sourceURL,attr,omitempty"`
+	Range     string `xml:"range,attr,omitempty"`
+}
+
+type mpdSegmentBase struct {
+	Timescale      uint32 `xml:"timescale,attr"`
+	IndexRange     string `xml:"indexRange,attr"`
+	Initialization mpdURL `xml:"Initialization"`
+}
+
+type mpdSegmentList struct {
+	Timescale       uint32          `xml:"timescale,attr"`
+	Initialization  mpdURL          `xml:"Initialization"`
+	SegmentTimeline mpdTimeline     `xml:"SegmentTimeline"`
+	SegmentURLs     []mpdSegmentURL `xml:"SegmentURL"`
+}
+
+type mpdTimeline struct {
+	S []mpdS `xml:"S"`
+}
+
+type mpdS struct {
+	T *uint64 `xml:"t,attr"`
+	D uint64  `xml:"d,attr"`
+	R int     `xml:"r,attr,omitempty"`
+}
+
+type mpdSegmentURL struct {
+	Media      string `xml:"media,attr,omitempty"`
+	MediaRange string `xml:"mediaRange,attr,omitempty"`
+}
+
+const (
+	dashNamespace                   = "urn:mpeg:dash:schema:mpd:2011"
+	cencNamespace                   = "urn:mpeg:cenc:2013"
+	dashProfileOnDemand             = "urn:mpeg:dash:profile:isoff-on-demand:2011"
+	dashProfileMain                 = "urn:mpeg:dash:profile:isoff-main:2011"
+	mp4ProtectionScheme             = "urn:mpeg:dash:mp4protection:2011"
+	audioChannelConfigurationScheme = "urn:mpeg:dash:23003:3:audio_channel_configuration:2011"
+)
+
+// WriteMPD writes a static DASH MPD of the representations. The representations
+// with the same content type, language, codec family and protection are grouped
+// in one AdaptationSet.
+// If Representation.Index is set, SegmentBase is used. Otherwise, SegmentList with
+// SegmentTimeline is generated from the segments.
+func WriteMPD(w io.Writer, reps []*Representation) error {
+	if len(reps) == 0 {
+		return errors.New("manifest: no representation")
+	}
+	m := mpd{
+		Xmlns:    dashNamespace,
+		Profiles: dashProfileOnDemand,
+		Type:     "static",
+		Period:   mpdPeriod{ID: "0", Start: "PT0S"},
+	}
+	duration := 0.0
+	maxSegmentDuration := 0.0
+	groups := make(map[string]int)
+	for i, rep := range reps {
+		if rep.Index == nil || rep.Init == nil {
+			m.Profiles = dashProfileMain
+		}
+		if rep.Protection != nil {
+			m.XmlnsCenc = cencNamespace
+		}
+		duration = math.Max(duration, rep.Duration())
+		for _, s := range rep.Segments {
+			if rep.TimeScale != 0 {
+				maxSegmentDuration = math.Max(maxSegmentDuration, float64(s.Duration)/float64(rep.TimeScale))
+			}
+		}
+		r, err := rep.mpdRepresentation(i)
+		if err != nil {
+			return err
+		}
+		key := rep.adaptationSetKey()
+		index, ok := groups[key]
+		if !ok {
+			index = len(m.Period.AdaptationSets)
+			groups[key] = index
+			m.Period.AdaptationSets = append(m.Period.AdaptationSets, mpdAdaptationSet{
+				ContentType:        rep.ContentType,
+				MimeType:           rep.MimeType,
+				Lang:               rep.Language,
+				SegmentAlignment:   true,
+				StartWithSAP:       1,
+				ContentProtections: rep.mpdContentProtections(),
+			})
+		}
+		m.Period.AdaptationSets[index].Representations = append(m.Period.AdaptationSets[index].Representations, r)
+	}
+	if maxSegmentDuration == 0 {
+		maxSegmentDuration = 2
+	}
+	m.MediaPresentationDuration = "PT" + formatSeconds(duration) + "S"
+	m.MinBufferTime = "PT" + formatSeconds(math.Ceil(maxSegmentDuration)) + "S"
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (r *Representation) adaptationSetKey() string {
+	family := r.Codecs
+	if n := strings.IndexByte(family, '.'); n >= 0 {
+		family = family[:n]
+	}
+	key := r.ContentType + "|" + r.MimeType + "|" + r.Language + "|" + family
+	if r.Protection != nil {
+		key += fmt.Sprintf("|%s|%x", r.Protection.Scheme, r.Protection.DefaultKID)
+	}
+	return key
+}
+
+func (r *Representation) mpdContentProtections() []mpdContentProtection {
+	if r.Protection == nil {
+		return nil
+	}
+	cps := []mpdContentProtection{{
+		SchemeIDURI: mp4ProtectionScheme,
+		Value:       r.Protection.Scheme,
+		DefaultKID:  uuid(r.Protection.DefaultKID),
+	}}
+	for _, system := range r.Protection.Systems {
+		cp := mpdContentProtection{SchemeIDURI: "urn:uuid:" + uuid(system.SystemID)}
+		if len(system.PSSH) > 0 {
+			cp.PSSH = base64.StdEncoding.EncodeToString(system.PSSH)
+		}
+		cps = append(cps, cp)
+	}
+	return cps
+}
+
+func (r *Representation) mpdRepresentation(n int) (mpdRepresentation, error) {
+	peak, _ := r.bandwidth()
+	rep := mpdRepresentation{
+		ID:        r.ID,
+		Bandwidth: peak,
+		Codecs:    r.Codecs,
+		BaseURL:   r.URI,
+	}
+	if rep.ID == "" {
+		rep.ID = fmt.Sprintf("%d", n+1)
+	}
+	switch r.ContentType {
+	case "video":
+		rep.Width = r.Width
+		rep.Height = r.Height
+		rep.FrameRate = frameRate(r.FrameRate)
+	case "audio":
+		rep.AudioSamplingRate = r.SampleRate
+		if r.Channels != 0 {
+			rep.AudioChannelConfiguration = &mpdDescriptor{
+				SchemeIDURI: audioChannelConfigurationScheme,
+				Value:       fmt.Sprintf("%d", r.Channels),
+			}
+		}
+	}
+	if r.Index != nil {
+		if r.Init == nil {
+			return rep, fmt.Errorf("manifest: representation %s has index range but no initialization range", rep.ID)
+		}
+		rep.SegmentBase = &mpdSegmentBase{
+			Timescale:      r.TimeScale,
+			IndexRange:     r.Index.dash(),
+			Initialization: mpdURL{Range: r.Init.dash()},
+		}
+		return rep, nil
+	}
+	if len(r.Segments) == 0 {
+		return rep, fmt.Errorf("manifest: representation %s has neither index range nor segments", rep.ID)
+	}
+	list := &mpdSegmentList{Timescale: r.TimeScale}
+	if r.Init != nil {
+		list.Initialization.Range = r.Init.dash()
+	} else {
+		// the initialization segment is a separated file, it's the BaseURL
+		list.Initialization.SourceURL = r.URI
+		rep.BaseURL = ""
+	}
+	list.SegmentTimeline = timeline(r.Segments)
+	for _, s := range r.Segments {
+		u := mpdSegmentURL{Media: s.URI}
+		if s.Range != nil {
+			u.MediaRange = s.Range.dash()
+		}
+		list.SegmentURLs = append(list.SegmentURLs, u)
+	}
+	rep.SegmentList = list
+	return rep, nil
+}
+
+// timeline compresses the segments to SegmentTimeline. The consecutive segments
+// with the same duration share one "S" element.
+func timeline(segments []Segment) mpdTimeline {
+	var tl mpdTimeline
+	next := uint64(0)
+	for i, s := range segments {
+		n := len(tl.S)
+		if i > 0 && s.Time == next && tl.S[n-1].D == s.Duration {
+			tl.S[n-1].R++
+		} else {
+			e := mpdS{D: s.Duration}
+			if i == 0 || s.Time != next {
+				t := s.Time
+				e.T = &t
+			}
+			tl.S = append(tl.S, e)
+		}
+		next = s.Time + s.Duration
+	}
+	return tl
+}
+
+// frameRate formats the frame rate as the FrameRateType of DASH, e.g. "25" or "30000/1001".
+func frameRate(f float64) string {
+	if f <= 0 {
+		return ""
+	}
+	if math.Abs(f-math.Round(f)) < 0.001 {
+		return fmt.Sprintf("%d", int64(math.Round(f)))
+	}
+	if ntsc := f * 1.001; math.Abs(ntsc-math.Round(ntsc)) < 0.001 {
+		return fmt.Sprintf("%d/1001", int64(math.Round(ntsc))*1000)
+	}
+	return fmt.Sprintf("%d/1000", int64(math.Round(f*1000)))
+}
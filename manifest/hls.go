@@ -0,0 +1,239 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// HLS version 7 is required by the fragmented MP4 media segments, refer to RFC 8216 4.3.2.5.
+const hlsVersion = 7
+
+// well known DRM systems and their KEYFORMAT in HLS
+var hlsKeyFormats = map[string]string{
+	"edef8ba9-79d6-4ace-a3c8-27dcd51d21ed": "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed", // Widevine
+	"9a04f079-9840-4286-ab92-e65be0885f95": "com.microsoft.playready",                       // PlayReady
+	"94ce86fb-07ff-4f43-adb8-93d2fa968ca2": "com.apple.streamingkeydelivery",                // FairPlay
+}
+
+// WriteMediaPlaylist writes a VOD HLS media playlist of the representation.
+// The initialization segment is signalled by EXT-X-MAP, the segments in a single
+// file are signalled by EXT-X-BYTERANGE and the encryption by EXT-X-KEY.
+func WriteMediaPlaylist(w io.Writer, rep *Representation) error {
+	if rep == nil || len(rep.Segments) == 0 {
+		return errors.New("manifest: no segment in representation")
+	}
+	if rep.TimeScale == 0 {
+		return errors.New("manifest: time scale of representation is 0")
+	}
+	bw := bufio.NewWriter(w)
+	targetDuration := 0.0
+	for _, s := range rep.Segments {
+		targetDuration = math.Max(targetDuration, math.Round(float64(s.Duration)/float64(rep.TimeScale)))
+	}
+	fmt.Fprintln(bw, "#EXTM3U")
+	fmt.Fprintf(bw, "#EXT-X-VERSION:%d\n", hlsVersion)
+	fmt.Fprintf(bw, "#EXT-X-TARGETDURATION:%d\n", int64(math.Max(targetDuration, 1)))
+	fmt.Fprintln(bw, "#EXT-X-MEDIA-SEQUENCE:0")
+	fmt.Fprintln(bw, "#EXT-X-PLAYLIST-TYPE:VOD")
+	fmt.Fprintln(bw, "#EXT-X-INDEPENDENT-SEGMENTS")
+	for _, key := range rep.hlsKeys() {
+		fmt.Fprintln(bw, key)
+	}
+	if rep.Init != nil {
+		fmt.Fprintf(bw, "#EXT-X-MAP:URI=%q,BYTERANGE=%q\n", rep.URI, rep.Init.hls())
+	} else {
+		fmt.Fprintf(bw, "#EXT-X-MAP:URI=%q\n", rep.URI)
+	}
+	next := uint64(0)
+	for i, s := range rep.Segments {
+		uri := s.URI
+		if uri == "" {
+			uri = rep.URI
+		}
+		fmt.Fprintf(bw, "#EXTINF:%s,\n", formatSeconds(float64(s.Duration)/float64(rep.TimeScale)))
+		if s.Range != nil {
+			// the offset can be omitted if the segment follows the previous one
+			if i > 0 && s.URI == rep.Segments[i-1].URI && rep.Segments[i-1].Range != nil && s.Range.Offset == next {
+				fmt.Fprintf(bw, "#EXT-X-BYTERANGE:%d\n", s.Range.Size)
+			} else {
+				fmt.Fprintf(bw, "#EXT-X-BYTERANGE:%s\n", s.Range.hls())
+			}
+			next = s.Range.Offset + s.Range.Size
+		}
+		fmt.Fprintln(bw, uri)
+	}
+	fmt.Fprintln(bw, "#EXT-X-ENDLIST")
+	return bw.Flush()
+}
+
+// hlsKeys returns the EXT-X-KEY tags of the representation, one for each DRM system.
+func (r *Representation) hlsKeys() []string {
+	if r.Protection == nil {
+		return nil
+	}
+	method := "SAMPLE-AES"
+	if r.Protection.Scheme == "cenc" || r.Protection.Scheme == "cens" {
+		method = "SAMPLE-AES-CTR"
+	}
+	var keys []string
+	for _, system := range r.Protection.Systems {
+		id := uuid(system.SystemID)
+		uri := system.KeyURI
+		if uri == "" {
+			if len(system.PSSH) == 0 {
+				continue
+			}
+			uri = "data:text/plain;base64," + base64.StdEncoding.EncodeToString(system.PSSH)
+		}
+		format, ok := hlsKeyFormats[id]
+		if !ok {
+			format = "urn:uuid:" + id
+		}
+		key := fmt.Sprintf("#EXT-X-KEY:METHOD=%s,URI=%q", method, uri)
+		if len(r.Protection.DefaultKID) == 16 {
+			key += fmt.Sprintf(",KEYID=0x%X", r.Protection.DefaultKID)
+		}
+		key += fmt.Sprintf(",KEYFORMAT=%q,KEYFORMATVERSIONS=\"1\"", format)
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// WriteMasterPlaylist writes a HLS master playlist. Every video representation is a
+// variant stream, the audio and text representations are the renditions of the groups
+// "audio" and "subs". If there is no video, every audio representation is a variant stream.
+// Representation.PlaylistURI is the URI of the media playlists.
+func WriteMasterPlaylist(w io.Writer, reps []*Representation) error {
+	var videos, audios, texts []*Representation
+	for _, rep := range reps {
+		switch rep.ContentType {
+		case "video":
+			videos = append(videos, rep)
+		case "audio":
+			audios = append(audios, rep)
+		case "text":
+			texts = append(texts, rep)
+		}
+	}
+	if len(videos) == 0 && len(audios) == 0 {
+		return errors.New("manifest: no video or audio representation")
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	fmt.Fprintf(bw, "#EXT-X-VERSION:%d\n", hlsVersion)
+	fmt.Fprintln(bw, "#EXT-X-INDEPENDENT-SEGMENTS")
+	if len(videos) == 0 {
+		for _, a := range audios {
+			peak, average := a.bandwidth()
+			fmt.Fprintf(bw, "#EXT-X-STREAM-INF:%s\n", streamInf(peak, average, []string{a.Codecs}, nil, "", ""))
+			fmt.Fprintln(bw, a.PlaylistURI)
+		}
+		return bw.Flush()
+	}
+
+	var audioCodecs []string
+	audioPeak, audioAverage := uint64(0), uint64(0)
+	for i, a := range audios {
+		name := a.Language
+		if name == "" {
+			name = fmt.Sprintf("audio %d", i+1)
+		}
+		media := fmt.Sprintf("#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=%q", name)
+		if a.Language != "" {
+			media += fmt.Sprintf(",LANGUAGE=%q", a.Language)
+		}
+		media += fmt.Sprintf(",DEFAULT=%s,AUTOSELECT=YES", yesNo(i == 0))
+		if a.Channels != 0 {
+			media += fmt.Sprintf(",CHANNELS=\"%d\"", a.Channels)
+		}
+		media += fmt.Sprintf(",URI=%q", a.PlaylistURI)
+		fmt.Fprintln(bw, media)
+		peak, average := a.bandwidth()
+		if peak > audioPeak {
+			audioPeak = peak
+		}
+		if average > audioAverage {
+			audioAverage = average
+		}
+		audioCodecs = appendUnique(audioCodecs, a.Codecs)
+	}
+	for i, t := range texts {
+		name := t.Language
+		if name == "" {
+			name = fmt.Sprintf("subtitle %d", i+1)
+		}
+		media := fmt.Sprintf("#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=%q", name)
+		if t.Language != "" {
+			media += fmt.Sprintf(",LANGUAGE=%q", t.Language)
+		}
+		media += fmt.Sprintf(",DEFAULT=NO,AUTOSELECT=YES,URI=%q", t.PlaylistURI)
+		fmt.Fprintln(bw, media)
+	}
+	for _, v := range videos {
+		peak, average := v.bandwidth()
+		codecs := append([]string{v.Codecs}, audioCodecs...)
+		attrs := streamInf(peak+audioPeak, average+audioAverage, codecs, v, groupName(audios, "audio"), groupName(texts, "subs"))
+		fmt.Fprintf(bw, "#EXT-X-STREAM-INF:%s\n", attrs)
+		fmt.Fprintln(bw, v.PlaylistURI)
+	}
+	return bw.Flush()
+}
+
+func streamInf(peak, average uint64, codecs []string, video *Representation, audio, subtitles string) string {
+	attrs := fmt.Sprintf("BANDWIDTH=%d", peak)
+	if average != 0 {
+		attrs += fmt.Sprintf(",AVERAGE-BANDWIDTH=%d", average)
+	}
+	var nonEmpty []string
+	for _, c := range codecs {
+		if c != "" {
+			nonEmpty = append(nonEmpty, c)
+		}
+	}
+	if len(nonEmpty) > 0 {
+		attrs += fmt.Sprintf(",CODECS=%q", strings.Join(nonEmpty, ","))
+	}
+	if video != nil {
+		if video.Width != 0 && video.Height != 0 {
+			attrs += fmt.Sprintf(",RESOLUTION=%dx%d", video.Width, video.Height)
+		}
+		if video.FrameRate > 0 {
+			attrs += fmt.Sprintf(",FRAME-RATE=%.3f", video.FrameRate)
+		}
+	}
+	if audio != "" {
+		attrs += fmt.Sprintf(",AUDIO=%q", audio)
+	}
+	if subtitles != "" {
+		attrs += fmt.Sprintf(",SUBTITLES=%q", subtitles)
+	}
+	return attrs
+}
+
+func groupName(reps []*Representation, name string) string {
+	if len(reps) == 0 {
+		return ""
+	}
+	return name
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
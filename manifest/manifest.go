@@ -0,0 +1,130 @@
+// Package manifest generates DASH MPD and HLS playlists for fragmented MP4 files.
+//
+// The input of the package is a list of Representation, each of them describes a
+// media stream: its initialization segment, its media segments (by byte range of a
+// single file or by separated files) and the properties of the stream.
+package manifest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteRange is a range of bytes in a file.
+type ByteRange struct {
+	Offset uint64 // the first byte
+	Size   uint64
+}
+
+// dash returns the byte range in the format of DASH, i.e. "first-last".
+func (r ByteRange) dash() string {
+	return fmt.Sprintf("%d-%d", r.Offset, r.Offset+r.Size-1)
+}
+
+// hls returns the byte range in the format of HLS, i.e. "size@offset".
+func (r ByteRange) hls() string {
+	return fmt.Sprintf("%d@%d", r.Size, r.Offset)
+}
+
+// Segment is a media segment.
+type Segment struct {
+	URI      string     // location of the segment. If empty, the segment is in Representation.URI
+	Range    *ByteRange // byte range of the segment in the file. nil means the whole file
+	Time     uint64     // presentation start time in Representation.TimeScale
+	Duration uint64     // in Representation.TimeScale
+}
+
+// ProtectionSystem is the DRM system specific information of an encrypted stream.
+type ProtectionSystem struct {
+	SystemID []byte // 16 bytes
+	PSSH     []byte // the full "pssh" box
+	KeyURI   string // key URI for HLS, e.g. "skd://..." for FairPlay. If empty, the data URI of PSSH is used
+}
+
+// Protection is the common encryption information of a stream, from "tenc" and "pssh".
+type Protection struct {
+	Scheme     string // "cenc", "cens", "cbc1" or "cbcs"
+	DefaultKID []byte // 16 bytes
+	Systems    []ProtectionSystem
+}
+
+// Representation is a media stream.
+type Representation struct {
+	ID               string
+	ContentType      string // "video", "audio" or "text"
+	MimeType         string // "video/mp4", "audio/mp4" or "application/mp4"
+	Codecs           string // RFC 6381 codecs parameter
+	Bandwidth        uint64 // peak bit rate in bits per second. If 0, it's computed by the segments
+	AverageBandwidth uint64 // average bit rate in bits per second. If 0, it's computed by the segments
+	Language         string
+	Width            uint32  // for video
+	Height           uint32  // for video
+	FrameRate        float64 // for video
+	SampleRate       uint32  // for audio
+	Channels         uint16  // for audio
+
+	URI         string     // location of the media file, i.e. BaseURL of DASH or URI of HLS
+	PlaylistURI string     // location of the HLS media playlist, it's used by the master playlist
+	TimeScale   uint32     // time scale of the segments
+	Init        *ByteRange // initialization segment ("ftyp" + "moov") in URI
+	Index       *ByteRange // "sidx" in URI. If not nil, SegmentBase is used in DASH
+	Segments    []Segment
+	Protection  *Protection
+}
+
+// Duration returns the duration of the representation in seconds.
+func (r *Representation) Duration() float64 {
+	if r.TimeScale == 0 || len(r.Segments) == 0 {
+		return 0
+	}
+	first := r.Segments[0]
+	last := r.Segments[len(r.Segments)-1]
+	return float64(last.Time+last.Duration-first.Time) / float64(r.TimeScale)
+}
+
+// bandwidth returns the peak and the average bit rate.
+// The values set by the invoker are preferred, otherwise they are computed by the segments.
+func (r *Representation) bandwidth() (peak uint64, average uint64) {
+	peak, average = r.Bandwidth, r.AverageBandwidth
+	if (peak != 0 && average != 0) || r.TimeScale == 0 {
+		return
+	}
+	var totalSize, totalDuration, maxRate float64
+	for _, s := range r.Segments {
+		if s.Range == nil || s.Duration == 0 {
+			continue
+		}
+		size := float64(s.Range.Size)
+		duration := float64(s.Duration) / float64(r.TimeScale)
+		totalSize += size
+		totalDuration += duration
+		maxRate = math.Max(maxRate, size*8/duration)
+	}
+	if peak == 0 {
+		peak = uint64(math.Ceil(maxRate))
+	}
+	if average == 0 && totalDuration > 0 {
+		average = uint64(math.Ceil(totalSize * 8 / totalDuration))
+	}
+	if peak == 0 {
+		peak = average
+	}
+	return
+}
+
+// uuid formats 16 bytes as a UUID string.
+func uuid(b []byte) string {
+	if len(b) != 16 {
+		return fmt.Sprintf("%x", b)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// formatSeconds formats the seconds with at most 3 decimals, trailing zeros are removed.
+func formatSeconds(seconds float64) string {
+	s := strconv.FormatFloat(seconds, 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
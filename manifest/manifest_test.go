@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testRepresentations() []*Representation {
+	video := &Representation{
+		ID:          "1",
+		ContentType: "video",
+		MimeType:    "video/mp4",
+		Codecs:      "avc1.64001f",
+		Width:       1280,
+		Height:      720,
+		FrameRate:   30000.0 / 1001,
+		URI:         "video.mp4",
+		PlaylistURI: "video.m3u8",
+		TimeScale:   1000,
+		Init:        &ByteRange{Offset: 0, Size: 800},
+		Index:       &ByteRange{Offset: 800, Size: 68},
+		Segments: []Segment{
+			{Range: &ByteRange{Offset: 868, Size: 250000}, Time: 0, Duration: 2000},
+			{Range: &ByteRange{Offset: 250868, Size: 125000}, Time: 2000, Duration: 2000},
+		},
+	}
+	audio := &Representation{
+		ID:          "2",
+		ContentType: "audio",
+		MimeType:    "audio/mp4",
+		Codecs:      "mp4a.40.2",
+		Language:    "eng",
+		SampleRate:  48000,
+		Channels:    2,
+		URI:         "audio.mp4",
+		PlaylistURI: "audio.m3u8",
+		TimeScale:   48000,
+		Init:        &ByteRange{Offset: 0, Size: 700},
+		Segments: []Segment{
+			{Range: &ByteRange{Offset: 700, Size: 32000}, Time: 0, Duration: 96000},
+			{Range: &ByteRange{Offset: 32700, Size: 32000}, Time: 96000, Duration: 96000},
+			{Range: &ByteRange{Offset: 64700, Size: 16000}, Time: 192000, Duration: 48000},
+		},
+	}
+	return []*Representation{video, audio}
+}
+
+func TestWriteMPD(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteMPD(&b, testRepresentations()); err != nil {
+		t.Fatal(err)
+	}
+	mpd := b.String()
+	for _, want := range []string{
+		`profiles="urn:mpeg:dash:profile:isoff-main:2011"`,
+		`mediaPresentationDuration="PT5S"`,
+		`<Representation id="1" bandwidth="1000000" codecs="avc1.64001f" width="1280" height="720" frameRate="30000/1001">`,
+		`<SegmentBase timescale="1000" indexRange="800-867">`,
+		`<Initialization range="0-799"></Initialization>`,
+		`<AdaptationSet contentType="audio" mimeType="audio/mp4" lang="eng"`,
+		`<AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="2"></AudioChannelConfiguration>`,
+		`<S t="0" d="96000" r="1"></S>`,
+		`<S d="48000"></S>`,
+		`<SegmentURL mediaRange="64700-80699"></SegmentURL>`,
+	} {
+		if !strings.Contains(mpd, want) {
+			t.Errorf("MPD doesn't contain %s\n%s", want, mpd)
+		}
+	}
+}
+
+func TestWriteMediaPlaylist(t *testing.T) {
+	rep := testRepresentations()[1]
+	rep.Protection = &Protection{
+		Scheme:     "cbcs",
+		DefaultKID: bytes.Repeat([]byte{0xAB}, 16),
+		Systems: []ProtectionSystem{{
+			SystemID: []byte{0x94, 0xce, 0x86, 0xfb, 0x07, 0xff, 0x4f, 0x43, 0xad, 0xb8, 0x93, 0xd2, 0xfa, 0x96, 0x8c, 0xa2},
+			KeyURI:   "skd://key",
+		}},
+	}
+	var b bytes.Buffer
+	if err := WriteMediaPlaylist(&b, rep); err != nil {
+		t.Fatal(err)
+	}
+	want := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key",KEYID=0xABABABABABABABABABABABABABABABAB,KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-MAP:URI="audio.mp4",BYTERANGE="700@0"
+#EXTINF:2,
+#EXT-X-BYTERANGE:32000@700
+audio.mp4
+#EXTINF:2,
+#EXT-X-BYTERANGE:32000
+audio.mp4
+#EXTINF:1,
+#EXT-X-BYTERANGE:16000
+audio.mp4
+#EXT-X-ENDLIST
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteMasterPlaylist(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteMasterPlaylist(&b, testRepresentations()); err != nil {
+		t.Fatal(err)
+	}
+	want := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="eng",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2",URI="audio.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1128000,AVERAGE-BANDWIDTH=878000,CODECS="avc1.64001f,mp4a.40.2",RESOLUTION=1280x720,FRAME-RATE=29.970,AUDIO="audio"
+video.m3u8
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"bufio"
@@ -44,7 +44,10 @@ func (p *mp4Reader) PeekAtomHeader() (a *atom, err error) {
 	startPos, _ := p.readSeeker.Seek(0, io.SeekCurrent)
 	a, err = p.ReadAtomHeader()
 	p.readSeeker.Seek(startPos, io.SeekStart)
-	return a, nil
+	if a == nil && err == nil {
+		err = io.EOF
+	}
+	return a, err
 }
 
 // ReadAtomHeader will read the next atom's header if no error occur.
@@ -220,6 +223,40 @@ func (br *bitReader) Err() error {
 	return br.err
 }
 
+// bitWriter writes the values MSB first, the counterpart of bitReader.
+type bitWriter struct {
+	b    []byte
+	bits int
+}
+
+func (w *bitWriter) write(v uint64, bits int) *bitWriter {
+	for i := bits - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.b[len(w.b)-1] |= 0x80 >> uint(w.bits%8)
+		}
+		w.bits++
+	}
+	return w
+}
+
+func (w *bitWriter) writeBytes(b []byte) *bitWriter {
+	for _, v := range b {
+		w.write(uint64(v), 8)
+	}
+	return w
+}
+
+// align writes zero bits up to the byte boundary.
+func (w *bitWriter) align() *bitWriter {
+	for w.bits%8 != 0 {
+		w.write(0, 1)
+	}
+	return w
+}
+
 func int2String(n uint32) string {
 	return fmt.Sprintf("%c%c%c%c", uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
 }
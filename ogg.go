@@ -0,0 +1,174 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// oggCRCTable is the table of the CRC of Ogg pages: polynomial 0x04c11db7, no reflection,
+// the initial value and the final XOR are 0.
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func oggCRC(data []byte) uint32 {
+	crc := uint32(0)
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// header_type_flag of Ogg pages
+const (
+	oggContinuedPacket = 0x01
+	oggBeginOfStream   = 0x02
+	oggEndOfStream     = 0x04
+)
+
+// oggWriter writes the packets of a logical bitstream in Ogg pages, refer to RFC 3533.
+// A page ends with the end of each packet, so the granule position of every packet is kept.
+type oggWriter struct {
+	w        io.Writer
+	serial   uint32
+	sequence uint32
+	started  bool
+}
+
+// writePacket writes the packet in one or more pages. The packet is split if it needs more
+// than 255 lacing values, the pages which no packet ends in have the granule position -1.
+func (p *oggWriter) writePacket(data []byte, granule uint64, eos bool) error {
+	continued := false
+	for {
+		// the lacing values: 255 for each 255 bytes, then the rest which is less than 255
+		segments := len(data)/255 + 1
+		last := segments <= 255
+		if !last {
+			segments = 255
+		}
+		size := len(data)
+		if !last {
+			size = 255 * 255
+		}
+		header := make([]byte, 27+segments)
+		copy(header, "OggS")
+		if continued {
+			header[5] |= oggContinuedPacket
+		}
+		if !p.started {
+			header[5] |= oggBeginOfStream
+			p.started = true
+		}
+		if eos && last {
+			header[5] |= oggEndOfStream
+		}
+		position := granule
+		if !last {
+			position = ^uint64(0)
+		}
+		binary.LittleEndian.PutUint64(header[6:], position)
+		binary.LittleEndian.PutUint32(header[14:], p.serial)
+		binary.LittleEndian.PutUint32(header[18:], p.sequence)
+		header[26] = byte(segments)
+		for i := 0; i < segments; i++ {
+			header[27+i] = 255
+		}
+		if last {
+			header[27+segments-1] = byte(size % 255)
+		}
+		page := append(header, data[:size]...)
+		binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+		if _, err := p.w.Write(page); err != nil {
+			return err
+		}
+		p.sequence++
+		if last {
+			return nil
+		}
+		data = data[size:]
+		continued = true
+	}
+}
+
+// oggOpusWriter writes Opus in Ogg, refer to RFC 7845. The identification header is built from
+// the OpusDescriptor, the granule position is the number of 48 kHz samples including pre-skip,
+// i.e. the sum of the durations of the samples, which include the pre-skip samples too.
+type oggOpusWriter struct {
+	ogg       oggWriter
+	timeScale uint32
+	granule   uint64
+	pending   []byte // the last packet, it's written with the end of stream flag by close
+}
+
+func newOggOpusWriter(w io.Writer, track *Track) (esWriter, error) {
+	if track.audioEntry == nil {
+		return nil, ErrUnsupportedExtraction
+	}
+	opus, ok := track.audioEntry.decoderDescriptors[AudioCodecOPUS].(*OpusDescriptor)
+	if !ok {
+		return nil, ErrUnsupportedExtraction
+	}
+	p := &oggOpusWriter{ogg: oggWriter{w: w, serial: track.TrackID}, timeScale: track.TimeScale}
+	if err := p.ogg.writePacket(opus.opusHead(), 0, false); err != nil {
+		return nil, err
+	}
+	vendor := "fmp4parser-go"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	if err := p.ogg.writePacket(tags, 0, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// opusHead returns the identification header of Ogg Opus. The fields are the same as "dOps"
+// except the version and the byte order.
+func (p *OpusDescriptor) opusHead() []byte {
+	head := make([]byte, 19, 21+len(p.ChannelMapping))
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = p.OutputChannelCount
+	binary.LittleEndian.PutUint16(head[10:], p.PreSkip)
+	binary.LittleEndian.PutUint32(head[12:], p.InputSampleRate)
+	binary.LittleEndian.PutUint16(head[16:], p.OutputGain)
+	head[18] = p.ChannelMappingFamily
+	if p.ChannelMappingFamily != 0 {
+		head = append(head, p.StreamCount, p.CoupledCount)
+		head = append(head, p.ChannelMapping...)
+	}
+	return head
+}
+
+func (p *oggOpusWriter) writePacket(packet *Packet) error {
+	if p.pending != nil {
+		if err := p.ogg.writePacket(p.pending, p.granule, false); err != nil {
+			return err
+		}
+	}
+	p.granule += rescaleTime(uint64(packet.Duration), 48000, p.timeScale)
+	p.pending = packet.Data
+	return nil
+}
+
+func (p *oggOpusWriter) close() error {
+	if p.pending == nil {
+		return nil
+	}
+	err := p.ogg.writePacket(p.pending, p.granule, true)
+	p.pending = nil
+	return err
+}
@@ -0,0 +1,158 @@
+package fmp4parser
+
+import "io"
+
+// baseOffsetOf returns the base data offset of the track fragment. Without base_data_offset
+// and default-base-is-moof, it's the end of the data of the previous track fragment of
+// "moof", which is the start of "moof" for the first one, refer to ISO/IEC 14496-12 8.8.7.1.
+func (p *trackFragment) baseOffsetOf(previousEnd uint64) uint64 {
+	switch {
+	case p.baseDataOffset != nil:
+		return *p.baseDataOffset
+	case p.defaultBaseIsMoof && p.moof != nil:
+		return uint64(p.moof.offset)
+	default:
+		return previousEnd
+	}
+}
+
+// dataEnd returns the end of the data of the samples of the track fragment.
+func (p *trackFragment) dataEnd(base uint64) uint64 {
+	defaultSize := p.defaultSampleSizeOf()
+	offset := base
+	for _, trun := range p.trun {
+		if trun.dataOffset != nil {
+			offset = uint64(int64(base) + int64(int32(*trun.dataOffset)))
+		}
+		for _, sample := range trun.samples {
+			if sample.sampleSize != nil {
+				offset += uint64(*sample.sampleSize)
+			} else {
+				offset += uint64(defaultSize)
+			}
+		}
+	}
+	return offset
+}
+
+// packets builds the packets of the track fragment without reading the data. base is the
+// base data offset of baseOffsetOf. decodeTime is the decode time of the first sample if
+// there is no "tfdt", the decode time of the next fragment is returned.
+func (p *trackFragment) packets(timeline *Timeline, base, decodeTime uint64) ([]Packet, uint64) {
+	if p.baseMediaDecodeTime != nil {
+		decodeTime = *p.baseMediaDecodeTime
+	}
+	defaultDuration, defaultSize := p.defaultSampleDurationOf(), p.defaultSampleSizeOf()
+	descriptorIndex := int(p.sampleDescriptionIndexOf())
+	var av1c *Av1cConfig
+	if trak := p.trackInfo(); trak != nil {
+		av1c = trak.av1Config()
+	}
+	var packets []Packet
+	offset := base
+	for _, trun := range p.trun {
+		if trun.dataOffset != nil {
+			offset = uint64(int64(base) + int64(int32(*trun.dataOffset)))
+		}
+		for i, sample := range trun.samples {
+			packet := Packet{DTS: decodeTime, Duration: defaultDuration, Size: defaultSize,
+				DescriptorIndex: descriptorIndex, SampleFlags: newSampleFlags(p.sampleFlagsOf(trun, i)), offset: offset}
+			if av1c != nil && !p.hasSampleFlags(trun, i) {
+				packet.av1c = av1c
+			}
+			if sample.sampleDuration != nil {
+				packet.Duration = *sample.sampleDuration
+			}
+			if sample.sampleSize != nil {
+				packet.Size = *sample.sampleSize
+			}
+			compositionTime := int64(decodeTime)
+			if sample.sampleCompositionTimeOffset != nil {
+				compositionTime += int64(*sample.sampleCompositionTimeOffset)
+			}
+			packet.PTS = timeline.presentationTimeOf(compositionTime)
+			packets = append(packets, packet)
+			decodeTime += uint64(packet.Duration)
+			offset += uint64(packet.Size)
+		}
+	}
+	if trak := p.trackInfo(); trak != nil {
+		setSubSamples(packets, p.subs, trak.codec())
+	}
+	return packets, decodeTime
+}
+
+// packetsOf returns the packets of all the samples of the track: the samples of "stbl"
+// first, then the samples of the movie fragments in file order.
+func (p *mediaInfo) packetsOf(trak *boxTrak) []Packet {
+	if trak.packets == nil {
+		trak.constructPacketList()
+	}
+	packets := append([]Packet{}, trak.packets...)
+	timeline := trak.timeline()
+	decodeTime := uint64(0)
+	if n := len(packets); n > 0 {
+		decodeTime = packets[n-1].DTS + uint64(packets[n-1].Duration)
+	}
+	for _, moof := range p.fragments {
+		end := uint64(moof.offset)
+		for _, traf := range moof.fragment {
+			base := traf.baseOffsetOf(end)
+			end = traf.dataEnd(base)
+			if traf.trackID == trak.id {
+				var fragmentPackets []Packet
+				fragmentPackets, decodeTime = traf.packets(timeline, base, decodeTime)
+				packets = append(packets, fragmentPackets...)
+				break
+			}
+		}
+	}
+	return packets
+}
+
+// Packets returns the packets of the track in decode order, the data isn't read. The
+// priming and padding samples of Track.Gapless are handled by mode. It must be called
+// after Parse.
+func (p *Parser) Packets(trackID uint32, mode PrimingMode) ([]Packet, error) {
+	if p.m.movie == nil {
+		return nil, ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return nil, ErrNotFoundTrack
+	}
+	packets := p.m.packetsOf(trak)
+	if mode != PrimingKeep {
+		if gapless := p.m.gaplessOf(trak); gapless != nil {
+			packets = gapless.apply(packets, mode == PrimingTrim)
+		}
+	}
+	return packets, nil
+}
+
+// ReadPacket reads the data of the packet from the input stream into Packet.Data. The sync
+// sample of "av01" without "stss" or sample flags is detected from the data, i.e. it sets
+// SampleFlags.IsNonSync by Av1cConfig.IsKeyFrame.
+func (p *Parser) ReadPacket(packet *Packet) error {
+	rs := p.m.r.readSeeker
+	current, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	defer func() { _, _ = rs.Seek(current, io.SeekStart) }()
+	if _, err = rs.Seek(int64(packet.offset), io.SeekStart); err != nil {
+		return err
+	}
+	packet.Data = make([]byte, packet.Size)
+	if _, err = io.ReadFull(rs, packet.Data); err != nil {
+		packet.Data = nil
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrUnexpectedEof
+		}
+		return err
+	}
+	if packet.av1c != nil {
+		packet.SampleFlags.IsNonSync = !packet.av1c.IsKeyFrame(packet.Data)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParser_PacketOffsetsOfTrackFragments(t *testing.T) {
+	mkTrak := func(id uint32, handler string) []byte {
+		tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(id), u32(0), u32(0), make([]byte, 52), u32(0), u32(0))
+		mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), u16(0x15C7), u16(0))
+		hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte(handler), make([]byte, 12), []byte{0})
+		return mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr))
+	}
+	mvex := mkBox("mvex", mkFullBox("trex", 0, 0, u32(1), u32(1), u32(20), u32(10), u32(0)),
+		mkFullBox("trex", 0, 0, u32(2), u32(1), u32(40), u32(0), u32(0)))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), mkTrak(1, "soun"), mkTrak(2, "vide"), mvex)
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	// no base_data_offset, default-base-is-moof or data_offset: the data of the second
+	// "traf" follows the data of the first one, the data of the first one is at "moof"
+	traf1 := mkBox("traf", mkFullBox("tfhd", 0, 0, u32(1)), mkFullBox("trun", 0, 0, u32(3)))
+	traf2 := mkBox("traf", mkFullBox("tfhd", 0, 0, u32(2)), mkFullBox("trun", 0, 0x000200, u32(2), u32(100), u32(50)))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf1, traf2)
+	file := append(append(append(ftyp, moov...), moof...), mkBox("mdat", make([]byte, 180))...)
+	moofOffset := uint64(len(ftyp) + len(moov))
+
+	p := newTestParser(t, file)
+	for _, tt := range []struct {
+		trackID uint32
+		want    []uint64
+	}{
+		{1, []uint64{moofOffset, moofOffset + 10, moofOffset + 20}},
+		{2, []uint64{moofOffset + 30, moofOffset + 130}},
+	} {
+		packets, err := p.Packets(tt.trackID, PrimingKeep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var offsets []uint64
+		for _, packet := range packets {
+			offsets = append(offsets, packet.offset)
+		}
+		if !reflect.DeepEqual(offsets, tt.want) {
+			t.Errorf("offsets of track %d = %v, want %v", tt.trackID, offsets, tt.want)
+		}
+	}
+}
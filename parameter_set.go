@@ -0,0 +1,789 @@
+package fmp4parser
+
+import (
+	"errors"
+)
+
+/*
+The parameter sets of H.264 and HEVC are carried by "avcC" and "hvcC" as NAL units.
+refer to
+	ITU-T H.264 (08/2021) 7.3.2 and Annex E
+	ITU-T H.265 (08/2021) 7.3.2 and Annex E
+
+Only the syntax elements which describe the stream are parsed, the parsing of the
+parameter set stops as soon as they are read.
+*/
+
+// SequenceParameterSet is the parsed sequence parameter set of H.264 or HEVC.
+type SequenceParameterSet struct {
+	ID                  uint32
+	VPSID               uint8 // HEVC only
+	ProfileIdc          uint8
+	LevelIdc            uint8
+	ChromaFormatIdc     uint8 // 0: monochrome, 1: 4:2:0, 2: 4:2:2, 3: 4:4:4
+	SeparateColourPlane bool
+	BitDepthLuma        uint8
+	BitDepthChroma      uint8
+
+	CodedWidth  uint32 // in luma samples, before cropping
+	CodedHeight uint32
+	CropLeft    uint32 // frame cropping (H.264) or conformance window (HEVC) in luma samples
+	CropRight   uint32
+	CropTop     uint32
+	CropBottom  uint32
+	Width       uint32 // the cropped picture size
+	Height      uint32
+
+	Interlaced   bool   // the pictures are fields or field pairs
+	MaxDpbFrames uint32 // the max number of frames in the decoded picture buffer
+
+	// VUI, the aspect ratio is 1:1 if it's not present
+	SarWidth                uint16
+	SarHeight               uint16
+	VideoFullRange          bool
+	ColourDescription       bool // the following 3 fields are valid
+	ColourPrimaries         uint8
+	TransferCharacteristics uint8
+	MatrixCoefficients      uint8
+	NumUnitsInTick          uint32 // timing info, 0 if not present
+	TimeScale               uint32
+	FixedFrameRate          bool    // H.264 only
+	FrameRate               float64 // frames per second calculated by the timing info, 0 if unknown
+}
+
+// PictureParameterSet is the parsed picture parameter set of H.264 or HEVC.
+type PictureParameterSet struct {
+	ID    uint32
+	SPSID uint32
+	CABAC bool // entropy_coding_mode_flag of H.264, always true for HEVC
+
+	// H.264 only
+	BottomFieldPicOrderInFramePresent bool
+	NumSliceGroups                    uint32
+
+	// HEVC only
+	DependentSliceSegmentsEnabled bool
+	OutputFlagPresent             bool
+	NumExtraSliceHeaderBits       uint8
+	TilesEnabled                  bool
+	EntropyCodingSyncEnabled      bool
+}
+
+// VideoParameterSet is the parsed video parameter set of HEVC.
+type VideoParameterSet struct {
+	ID             uint8
+	MaxLayers      uint8
+	MaxSubLayers   uint8
+	ProfileIdc     uint8
+	LevelIdc       uint8
+	NumUnitsInTick uint32 // timing info, 0 if not present
+	TimeScale      uint32
+}
+
+var errInvalidParameterSet = errors.New("invalid parameter set")
+
+// sample aspect ratios of aspect_ratio_idc 1~16, refer to H.264 Table E-1
+var sampleAspectRatios = [17][2]uint16{{0, 0}, {1, 1}, {12, 11}, {10, 11}, {16, 11}, {40, 33}, {24, 11}, {20, 11},
+	{32, 11}, {80, 33}, {18, 11}, {15, 11}, {64, 33}, {160, 99}, {4, 3}, {3, 2}, {2, 1}}
+
+const aspectRatioIdcExtendedSAR = 255
+
+// nal unit types of the parameter sets
+const (
+	avcNalSPS  = 7
+	avcNalPPS  = 8
+	hevcNalVPS = 32
+	hevcNalSPS = 33
+	hevcNalPPS = 34
+)
+
+// unescapeRBSP removes the emulation prevention bytes, i.e. 0x03 of 0x000003, from the NAL unit.
+func unescapeRBSP(nal []byte) []byte {
+	rbsp := make([]byte, 0, len(nal))
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+// rbspReader reads the exp-Golomb codes of the raw byte sequence payload.
+type rbspReader struct {
+	br bitReader
+}
+
+func newRBSPReader(nal []byte, headerSize int) (*rbspReader, error) {
+	if len(nal) <= headerSize {
+		return nil, errInvalidParameterSet
+	}
+	return &rbspReader{br: newBitReaderFromSlice(unescapeRBSP(nal[headerSize:]))}, nil
+}
+
+func (r *rbspReader) read(bits uint) uint32 {
+	return r.br.ReadBitsLE32(bits)
+}
+
+func (r *rbspReader) readBool() bool {
+	return r.br.ReadBool()
+}
+
+func (r *rbspReader) skip(bits int) {
+	for ; bits > 32; bits -= 32 {
+		_ = r.read(32)
+	}
+	_ = r.read(uint(bits))
+}
+
+// ue reads ue(v), the unsigned exp-Golomb code.
+func (r *rbspReader) ue() uint32 {
+	leadingZeros := uint(0)
+	for !r.readBool() {
+		if r.br.Err() != nil || leadingZeros == 31 {
+			r.br.err = errInvalidParameterSet
+			return 0
+		}
+		leadingZeros++
+	}
+	return 1<<leadingZeros - 1 + r.read(leadingZeros)
+}
+
+// se reads se(v), the signed exp-Golomb code.
+func (r *rbspReader) se() int32 {
+	k := r.ue()
+	if k&1 == 1 {
+		return int32(k/2 + 1)
+	}
+	return -int32(k / 2)
+}
+
+func (r *rbspReader) err() error {
+	return r.br.Err()
+}
+
+// parseAvcSPS parses seq_parameter_set_rbsp() of H.264, refer to H.264 7.3.2.1.1.
+func parseAvcSPS(nal []byte) (*SequenceParameterSet, error) {
+	r, err := newRBSPReader(nal, 1)
+	if err != nil {
+		return nil, err
+	}
+	sps := &SequenceParameterSet{ChromaFormatIdc: 1, BitDepthLuma: 8, BitDepthChroma: 8, SarWidth: 1, SarHeight: 1}
+	sps.ProfileIdc = uint8(r.read(8))
+	_ = r.read(8) // constraint_set0_flag ~ constraint_set5_flag, reserved_zero_2bits
+	sps.LevelIdc = uint8(r.read(8))
+	sps.ID = r.ue()
+	switch sps.ProfileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		sps.ChromaFormatIdc = uint8(r.ue())
+		if sps.ChromaFormatIdc == 3 {
+			sps.SeparateColourPlane = r.readBool()
+		}
+		sps.BitDepthLuma = uint8(r.ue() + 8)
+		sps.BitDepthChroma = uint8(r.ue() + 8)
+		_ = r.readBool()  // qpprime_y_zero_transform_bypass_flag
+		if r.readBool() { // seq_scaling_matrix_present_flag
+			count := 8
+			if sps.ChromaFormatIdc == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				if !r.readBool() { // seq_scaling_list_present_flag
+					continue
+				}
+				if i < 6 {
+					skipAvcScalingList(r, 16)
+				} else {
+					skipAvcScalingList(r, 64)
+				}
+			}
+		}
+	}
+	_ = r.ue()      // log2_max_frame_num_minus4
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		_ = r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		_ = r.readBool() // delta_pic_order_always_zero_flag
+		_ = r.se()       // offset_for_non_ref_pic
+		_ = r.se()       // offset_for_top_to_bottom_field
+		numRefFramesInPicOrderCntCycle := r.ue()
+		for i := uint32(0); i < numRefFramesInPicOrderCntCycle && r.err() == nil; i++ {
+			_ = r.se() // offset_for_ref_frame
+		}
+	}
+	maxNumRefFrames := r.ue()
+	_ = r.readBool() // gaps_in_frame_num_value_allowed_flag
+	picWidthInMbs := r.ue() + 1
+	picHeightInMapUnits := r.ue() + 1
+	frameMbsOnly := r.readBool()
+	if !frameMbsOnly {
+		_ = r.readBool() // mb_adaptive_frame_field_flag
+		sps.Interlaced = true
+	}
+	_ = r.readBool() // direct_8x8_inference_flag
+	frameHeightInMbs := picHeightInMapUnits
+	if !frameMbsOnly {
+		frameHeightInMbs *= 2
+	}
+	sps.CodedWidth = picWidthInMbs * 16
+	sps.CodedHeight = frameHeightInMbs * 16
+	if r.readBool() { // frame_cropping_flag
+		cropUnitX, cropUnitY := uint32(1), uint32(1)
+		if sps.ChromaFormatIdc != 0 && !sps.SeparateColourPlane {
+			subWidthC, subHeightC := chromaSubsampling(sps.ChromaFormatIdc)
+			cropUnitX, cropUnitY = subWidthC, subHeightC
+		}
+		if !frameMbsOnly {
+			cropUnitY *= 2
+		}
+		sps.CropLeft = r.ue() * cropUnitX
+		sps.CropRight = r.ue() * cropUnitX
+		sps.CropTop = r.ue() * cropUnitY
+		sps.CropBottom = r.ue() * cropUnitY
+	}
+	sps.MaxDpbFrames = avcMaxDpbFrames(sps.LevelIdc, picWidthInMbs*frameHeightInMbs, maxNumRefFrames)
+	if r.readBool() { // vui_parameters_present_flag
+		sps.parseAvcVUI(r)
+	}
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	if err = sps.crop(); err != nil {
+		return nil, err
+	}
+	return sps, nil
+}
+
+func skipAvcScalingList(r *rbspReader, size int) {
+	lastScale, nextScale := int32(8), int32(8)
+	for j := 0; j < size && r.err() == nil; j++ {
+		if nextScale != 0 {
+			nextScale = (lastScale + r.se() + 256) % 256
+		}
+		if nextScale != 0 {
+			lastScale = nextScale
+		}
+	}
+}
+
+// chromaSubsampling returns SubWidthC and SubHeightC of chroma_format_idc, refer to H.264 Table 6-1.
+func chromaSubsampling(chromaFormatIdc uint8) (uint32, uint32) {
+	switch chromaFormatIdc {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// MaxDpbMbs of the levels, refer to H.264 Table A-1. level_idc 9 is level 1b
+var avcMaxDpbMbs = map[uint8]uint32{9: 396, 10: 396, 11: 900, 12: 2376, 13: 2376, 20: 2376, 21: 4752, 22: 8100,
+	30: 8100, 31: 18000, 32: 20480, 40: 32768, 41: 32768, 42: 34816, 50: 110400, 51: 184320, 52: 184320,
+	60: 696320, 61: 696320, 62: 696320}
+
+// avcMaxDpbFrames returns the max DPB size derived from the level, refer to H.264 A.3.1.
+// It's replaced by max_dec_frame_buffering of VUI if present.
+func avcMaxDpbFrames(levelIdc uint8, frameSizeInMbs uint32, maxNumRefFrames uint32) uint32 {
+	maxDpbMbs, ok := avcMaxDpbMbs[levelIdc]
+	if !ok || frameSizeInMbs == 0 {
+		return maxNumRefFrames
+	}
+	frames := maxDpbMbs / frameSizeInMbs
+	if frames > 16 {
+		frames = 16
+	}
+	if frames < maxNumRefFrames {
+		frames = maxNumRefFrames
+	}
+	return frames
+}
+
+// parseAvcVUI parses vui_parameters() of H.264, refer to H.264 E.1.1.
+func (p *SequenceParameterSet) parseAvcVUI(r *rbspReader) {
+	p.parseVUIHeader(r)
+	if r.readBool() { // timing_info_present_flag
+		p.NumUnitsInTick = r.read(32)
+		p.TimeScale = r.read(32)
+		p.FixedFrameRate = r.readBool()
+		if p.NumUnitsInTick != 0 {
+			// a frame is two fields, and num_units_in_tick is the duration of a field
+			p.FrameRate = float64(p.TimeScale) / float64(2*uint64(p.NumUnitsInTick))
+		}
+	}
+	nalHrd := r.readBool()
+	if nalHrd {
+		skipAvcHrdParameters(r)
+	}
+	vclHrd := r.readBool()
+	if vclHrd {
+		skipAvcHrdParameters(r)
+	}
+	if nalHrd || vclHrd {
+		_ = r.readBool() // low_delay_hrd_flag
+	}
+	_ = r.readBool()  // pic_struct_present_flag
+	if r.readBool() { // bitstream_restriction_flag
+		_ = r.readBool() // motion_vectors_over_pic_boundaries_flag
+		_ = r.ue()       // max_bytes_per_pic_denom
+		_ = r.ue()       // max_bits_per_mb_denom
+		_ = r.ue()       // log2_max_mv_length_horizontal
+		_ = r.ue()       // log2_max_mv_length_vertical
+		_ = r.ue()       // max_num_reorder_frames
+		maxDecFrameBuffering := r.ue()
+		if r.err() == nil {
+			p.MaxDpbFrames = maxDecFrameBuffering
+		}
+	}
+}
+
+// parseVUIHeader parses the syntax elements shared by H.264 and HEVC: the aspect ratio,
+// overscan, video signal type and chroma location.
+func (p *SequenceParameterSet) parseVUIHeader(r *rbspReader) {
+	if r.readBool() { // aspect_ratio_info_present_flag
+		aspectRatioIdc := r.read(8)
+		if aspectRatioIdc == aspectRatioIdcExtendedSAR {
+			p.SarWidth = uint16(r.read(16))
+			p.SarHeight = uint16(r.read(16))
+		} else if aspectRatioIdc < uint32(len(sampleAspectRatios)) && aspectRatioIdc != 0 {
+			p.SarWidth, p.SarHeight = sampleAspectRatios[aspectRatioIdc][0], sampleAspectRatios[aspectRatioIdc][1]
+		}
+	}
+	if r.readBool() { // overscan_info_present_flag
+		_ = r.readBool() // overscan_appropriate_flag
+	}
+	if r.readBool() { // video_signal_type_present_flag
+		_ = r.read(3) // video_format
+		p.VideoFullRange = r.readBool()
+		if r.readBool() { // colour_description_present_flag
+			p.ColourDescription = true
+			p.ColourPrimaries = uint8(r.read(8))
+			p.TransferCharacteristics = uint8(r.read(8))
+			p.MatrixCoefficients = uint8(r.read(8))
+		}
+	}
+	if r.readBool() { // chroma_loc_info_present_flag
+		_ = r.ue() // chroma_sample_loc_type_top_field
+		_ = r.ue() // chroma_sample_loc_type_bottom_field
+	}
+}
+
+func skipAvcHrdParameters(r *rbspReader) {
+	cpbCnt := r.ue() + 1
+	_ = r.read(4) // bit_rate_scale
+	_ = r.read(4) // cpb_size_scale
+	for i := uint32(0); i < cpbCnt && r.err() == nil; i++ {
+		_ = r.ue()       // bit_rate_value_minus1
+		_ = r.ue()       // cpb_size_value_minus1
+		_ = r.readBool() // cbr_flag
+	}
+	// initial_cpb_removal_delay_length_minus1, cpb_removal_delay_length_minus1,
+	// dpb_output_delay_length_minus1, time_offset_length
+	_ = r.read(20)
+}
+
+// crop calculates the cropped picture size.
+func (p *SequenceParameterSet) crop() error {
+	if p.CropLeft+p.CropRight >= p.CodedWidth || p.CropTop+p.CropBottom >= p.CodedHeight {
+		return errInvalidParameterSet
+	}
+	p.Width = p.CodedWidth - p.CropLeft - p.CropRight
+	p.Height = p.CodedHeight - p.CropTop - p.CropBottom
+	return nil
+}
+
+// parseAvcPPS parses the beginning of pic_parameter_set_rbsp() of H.264, refer to H.264 7.3.2.2.
+func parseAvcPPS(nal []byte) (*PictureParameterSet, error) {
+	r, err := newRBSPReader(nal, 1)
+	if err != nil {
+		return nil, err
+	}
+	pps := new(PictureParameterSet)
+	pps.ID = r.ue()
+	pps.SPSID = r.ue()
+	pps.CABAC = r.readBool()
+	pps.BottomFieldPicOrderInFramePresent = r.readBool()
+	pps.NumSliceGroups = r.ue() + 1
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	return pps, nil
+}
+
+// hevcProfileTierLevel is the general part of profile_tier_level().
+type hevcProfileTierLevel struct {
+	profileIdc         uint8
+	levelIdc           uint8
+	progressiveSource  bool
+	interlacedSource   bool
+	maxSubLayersMinus1 uint8
+}
+
+// parseHevcProfileTierLevel parses profile_tier_level(1, maxSubLayersMinus1), refer to H.265 7.3.3.
+func parseHevcProfileTierLevel(r *rbspReader, maxSubLayersMinus1 uint8) hevcProfileTierLevel {
+	ptl := hevcProfileTierLevel{maxSubLayersMinus1: maxSubLayersMinus1}
+	_ = r.read(2)    // general_profile_space
+	_ = r.readBool() // general_tier_flag
+	ptl.profileIdc = uint8(r.read(5))
+	_ = r.read(32) // general_profile_compatibility_flag[32]
+	ptl.progressiveSource = r.readBool()
+	ptl.interlacedSource = r.readBool()
+	_ = r.readBool() // general_non_packed_constraint_flag
+	_ = r.readBool() // general_frame_only_constraint_flag
+	r.skip(44)       // the other constraint flags
+	ptl.levelIdc = uint8(r.read(8))
+	subLayerProfilePresent := make([]bool, maxSubLayersMinus1)
+	subLayerLevelPresent := make([]bool, maxSubLayersMinus1)
+	for i := range subLayerProfilePresent {
+		subLayerProfilePresent[i] = r.readBool()
+		subLayerLevelPresent[i] = r.readBool()
+	}
+	if maxSubLayersMinus1 > 0 {
+		r.skip(2 * (8 - int(maxSubLayersMinus1))) // reserved_zero_2bits
+	}
+	for i := range subLayerProfilePresent {
+		if subLayerProfilePresent[i] {
+			r.skip(88)
+		}
+		if subLayerLevelPresent[i] {
+			r.skip(8)
+		}
+	}
+	return ptl
+}
+
+// parseHevcVPS parses the beginning of video_parameter_set_rbsp() of HEVC, refer to H.265 7.3.2.1.
+func parseHevcVPS(nal []byte) (*VideoParameterSet, error) {
+	r, err := newRBSPReader(nal, 2)
+	if err != nil {
+		return nil, err
+	}
+	vps := new(VideoParameterSet)
+	vps.ID = uint8(r.read(4))
+	_ = r.read(2) // vps_base_layer_internal_flag, vps_base_layer_available_flag
+	vps.MaxLayers = uint8(r.read(6) + 1)
+	maxSubLayersMinus1 := uint8(r.read(3))
+	vps.MaxSubLayers = maxSubLayersMinus1 + 1
+	_ = r.readBool() // vps_temporal_id_nesting_flag
+	_ = r.read(16)   // vps_reserved_0xffff_16bits
+	ptl := parseHevcProfileTierLevel(r, maxSubLayersMinus1)
+	vps.ProfileIdc, vps.LevelIdc = ptl.profileIdc, ptl.levelIdc
+	i := uint8(0)
+	if !r.readBool() { // vps_sub_layer_ordering_info_present_flag
+		i = maxSubLayersMinus1
+	}
+	for ; i <= maxSubLayersMinus1; i++ {
+		_ = r.ue() // vps_max_dec_pic_buffering_minus1
+		_ = r.ue() // vps_max_num_reorder_pics
+		_ = r.ue() // vps_max_latency_increase_plus1
+	}
+	maxLayerID := int(r.read(6))
+	numLayerSets := r.ue() + 1
+	for i := uint32(1); i < numLayerSets && r.err() == nil; i++ {
+		r.skip(maxLayerID + 1) // layer_id_included_flag
+	}
+	if r.readBool() { // vps_timing_info_present_flag
+		vps.NumUnitsInTick = r.read(32)
+		vps.TimeScale = r.read(32)
+	}
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	return vps, nil
+}
+
+// parseHevcSPS parses seq_parameter_set_rbsp() of HEVC, refer to H.265 7.3.2.2.
+func parseHevcSPS(nal []byte) (*SequenceParameterSet, error) {
+	r, err := newRBSPReader(nal, 2)
+	if err != nil {
+		return nil, err
+	}
+	sps := &SequenceParameterSet{SarWidth: 1, SarHeight: 1}
+	sps.VPSID = uint8(r.read(4))
+	maxSubLayersMinus1 := uint8(r.read(3))
+	_ = r.readBool() // sps_temporal_id_nesting_flag
+	ptl := parseHevcProfileTierLevel(r, maxSubLayersMinus1)
+	sps.ProfileIdc, sps.LevelIdc = ptl.profileIdc, ptl.levelIdc
+	sps.Interlaced = ptl.interlacedSource && !ptl.progressiveSource
+	sps.ID = r.ue()
+	sps.ChromaFormatIdc = uint8(r.ue())
+	if sps.ChromaFormatIdc == 3 {
+		sps.SeparateColourPlane = r.readBool()
+	}
+	sps.CodedWidth = r.ue()
+	sps.CodedHeight = r.ue()
+	if r.readBool() { // conformance_window_flag
+		subWidthC, subHeightC := uint32(1), uint32(1)
+		if !sps.SeparateColourPlane {
+			subWidthC, subHeightC = chromaSubsampling(sps.ChromaFormatIdc)
+		}
+		sps.CropLeft = r.ue() * subWidthC
+		sps.CropRight = r.ue() * subWidthC
+		sps.CropTop = r.ue() * subHeightC
+		sps.CropBottom = r.ue() * subHeightC
+	}
+	sps.BitDepthLuma = uint8(r.ue() + 8)
+	sps.BitDepthChroma = uint8(r.ue() + 8)
+	log2MaxPicOrderCntLsb := r.ue() + 4
+	i := uint8(0)
+	if !r.readBool() { // sps_sub_layer_ordering_info_present_flag
+		i = maxSubLayersMinus1
+	}
+	for ; i <= maxSubLayersMinus1; i++ {
+		// the value of the highest sub-layer is used
+		sps.MaxDpbFrames = r.ue() + 1 // sps_max_dec_pic_buffering_minus1
+		_ = r.ue()                    // sps_max_num_reorder_pics
+		_ = r.ue()                    // sps_max_latency_increase_plus1
+	}
+	_ = r.ue()        // log2_min_luma_coding_block_size_minus3
+	_ = r.ue()        // log2_diff_max_min_luma_coding_block_size
+	_ = r.ue()        // log2_min_luma_transform_block_size_minus2
+	_ = r.ue()        // log2_diff_max_min_luma_transform_block_size
+	_ = r.ue()        // max_transform_hierarchy_depth_inter
+	_ = r.ue()        // max_transform_hierarchy_depth_intra
+	if r.readBool() { // scaling_list_enabled_flag
+		if r.readBool() { // sps_scaling_list_data_present_flag
+			skipHevcScalingListData(r)
+		}
+	}
+	_ = r.readBool()  // amp_enabled_flag
+	_ = r.readBool()  // sample_adaptive_offset_enabled_flag
+	if r.readBool() { // pcm_enabled_flag
+		_ = r.read(8)    // pcm_sample_bit_depth_luma_minus1, pcm_sample_bit_depth_chroma_minus1
+		_ = r.ue()       // log2_min_pcm_luma_coding_block_size_minus3
+		_ = r.ue()       // log2_diff_max_min_pcm_luma_coding_block_size
+		_ = r.readBool() // pcm_loop_filter_disabled_flag
+	}
+	numShortTermRefPicSets := r.ue()
+	if numShortTermRefPicSets > 64 {
+		return nil, errInvalidParameterSet
+	}
+	numDeltaPocs := make([]uint32, numShortTermRefPicSets)
+	for i := uint32(0); i < numShortTermRefPicSets && r.err() == nil; i++ {
+		if numDeltaPocs[i], err = parseHevcShortTermRefPicSet(r, i, numDeltaPocs); err != nil {
+			return nil, err
+		}
+	}
+	if r.readBool() { // long_term_ref_pics_present_flag
+		numLongTermRefPics := r.ue()
+		for i := uint32(0); i < numLongTermRefPics && r.err() == nil; i++ {
+			_ = r.read(uint(log2MaxPicOrderCntLsb)) // lt_ref_pic_poc_lsb_sps
+			_ = r.readBool()                        // used_by_curr_pic_lt_sps_flag
+		}
+	}
+	_ = r.readBool()  // sps_temporal_mvp_enabled_flag
+	_ = r.readBool()  // strong_intra_smoothing_enabled_flag
+	if r.readBool() { // vui_parameters_present_flag
+		sps.parseHevcVUI(r)
+	}
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	if err = sps.crop(); err != nil {
+		return nil, err
+	}
+	return sps, nil
+}
+
+// skipHevcScalingListData skips scaling_list_data(), refer to H.265 7.3.4.
+func skipHevcScalingListData(r *rbspReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if !r.readBool() { // scaling_list_pred_mode_flag
+				_ = r.ue() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefNum := 1 << (4 + uint(sizeID)<<1)
+			if coefNum > 64 {
+				coefNum = 64
+			}
+			if sizeID > 1 {
+				_ = r.se() // scaling_list_dc_coef_minus8
+			}
+			for i := 0; i < coefNum && r.err() == nil; i++ {
+				_ = r.se() // scaling_list_delta_coef
+			}
+		}
+	}
+}
+
+// parseHevcShortTermRefPicSet parses st_ref_pic_set(stRpsIdx) of SPS and returns NumDeltaPocs
+// of the set, refer to H.265 7.3.7 and 7.4.8.
+func parseHevcShortTermRefPicSet(r *rbspReader, stRpsIdx uint32, numDeltaPocs []uint32) (uint32, error) {
+	if stRpsIdx != 0 && r.readBool() { // inter_ref_pic_set_prediction_flag
+		// delta_idx_minus1 is present in slice headers only, so the reference is the previous set
+		_ = r.readBool() // delta_rps_sign
+		_ = r.ue()       // abs_delta_rps_minus1
+		count := uint32(0)
+		for j := uint32(0); j <= numDeltaPocs[stRpsIdx-1] && r.err() == nil; j++ {
+			used := r.readBool()      // used_by_curr_pic_flag
+			if used || r.readBool() { // use_delta_flag
+				count++
+			}
+		}
+		return count, nil
+	}
+	numNegativePics := r.ue()
+	numPositivePics := r.ue()
+	if numNegativePics > 16 || numPositivePics > 16 {
+		return 0, errInvalidParameterSet
+	}
+	for i := uint32(0); i < numNegativePics+numPositivePics; i++ {
+		_ = r.ue()       // delta_poc_s0_minus1 or delta_poc_s1_minus1
+		_ = r.readBool() // used_by_curr_pic_s0_flag or used_by_curr_pic_s1_flag
+	}
+	return numNegativePics + numPositivePics, nil
+}
+
+// parseHevcVUI parses vui_parameters() of HEVC until the timing info, refer to H.265 E.2.1.
+func (p *SequenceParameterSet) parseHevcVUI(r *rbspReader) {
+	p.parseVUIHeader(r)
+	_ = r.readBool() // neutral_chroma_indication_flag
+	fieldSeq := r.readBool()
+	if fieldSeq {
+		p.Interlaced = true
+	}
+	_ = r.readBool()  // frame_field_info_present_flag
+	if r.readBool() { // default_display_window_flag
+		_, _, _, _ = r.ue(), r.ue(), r.ue(), r.ue() // def_disp_win_left/right/top/bottom_offset
+	}
+	if r.readBool() { // vui_timing_info_present_flag
+		p.NumUnitsInTick = r.read(32)
+		p.TimeScale = r.read(32)
+		p.setHevcFrameRate(fieldSeq)
+	}
+}
+
+// setHevcFrameRate calculates the frame rate. Each picture is a field if field_seq_flag is set.
+func (p *SequenceParameterSet) setHevcFrameRate(fieldSeq bool) {
+	if p.NumUnitsInTick == 0 {
+		return
+	}
+	p.FrameRate = float64(p.TimeScale) / float64(p.NumUnitsInTick)
+	if fieldSeq {
+		p.FrameRate /= 2
+	}
+}
+
+// parseHevcPPS parses the beginning of pic_parameter_set_rbsp() of HEVC, refer to H.265 7.3.2.3.
+func parseHevcPPS(nal []byte) (*PictureParameterSet, error) {
+	r, err := newRBSPReader(nal, 2)
+	if err != nil {
+		return nil, err
+	}
+	pps := &PictureParameterSet{CABAC: true, NumSliceGroups: 1}
+	pps.ID = r.ue()
+	pps.SPSID = r.ue()
+	pps.DependentSliceSegmentsEnabled = r.readBool()
+	pps.OutputFlagPresent = r.readBool()
+	pps.NumExtraSliceHeaderBits = uint8(r.read(3))
+	_ = r.readBool()  // sign_data_hiding_enabled_flag
+	_ = r.readBool()  // cabac_init_present_flag
+	_ = r.ue()        // num_ref_idx_l0_default_active_minus1
+	_ = r.ue()        // num_ref_idx_l1_default_active_minus1
+	_ = r.se()        // init_qp_minus26
+	_ = r.readBool()  // constrained_intra_pred_flag
+	_ = r.readBool()  // transform_skip_enabled_flag
+	if r.readBool() { // cu_qp_delta_enabled_flag
+		_ = r.ue() // diff_cu_qp_delta_depth
+	}
+	_ = r.se()       // pps_cb_qp_offset
+	_ = r.se()       // pps_cr_qp_offset
+	_ = r.readBool() // pps_slice_chroma_qp_offsets_present_flag
+	_ = r.readBool() // weighted_pred_flag
+	_ = r.readBool() // weighted_bipred_flag
+	_ = r.readBool() // transquant_bypass_enabled_flag
+	pps.TilesEnabled = r.readBool()
+	pps.EntropyCodingSyncEnabled = r.readBool()
+	if err = r.err(); err != nil {
+		return nil, err
+	}
+	return pps, nil
+}
+
+// parseParameterSets parses the SPS and PPS of the configuration record.
+func (p *AvcConfig) parseParameterSets() {
+	for _, nal := range p.ListSPS {
+		sps, err := parseAvcSPS(nal)
+		if err != nil {
+			logW.Printf("failed to parse SPS of avcC: %v", err)
+			continue
+		}
+		p.SequenceParameterSets = append(p.SequenceParameterSets, sps)
+	}
+	for _, nal := range p.ListPPS {
+		pps, err := parseAvcPPS(nal)
+		if err != nil {
+			logW.Printf("failed to parse PPS of avcC: %v", err)
+			continue
+		}
+		p.PictureParameterSets = append(p.PictureParameterSets, pps)
+	}
+}
+
+// parseParameterSets parses the VPS, SPS and PPS of the configuration record. The frame
+// rate of the SPS is taken from the VPS if the VUI has no timing info.
+func (p *HevcConfig) parseParameterSets() {
+	for _, array := range p.NalUnitArrays {
+		for _, nal := range array.NalUnit {
+			var err error
+			switch array.NALUnitType {
+			case hevcNalVPS:
+				var vps *VideoParameterSet
+				if vps, err = parseHevcVPS(nal); err == nil {
+					p.VideoParameterSets = append(p.VideoParameterSets, vps)
+				}
+			case hevcNalSPS:
+				var sps *SequenceParameterSet
+				if sps, err = parseHevcSPS(nal); err == nil {
+					p.SequenceParameterSets = append(p.SequenceParameterSets, sps)
+				}
+			case hevcNalPPS:
+				var pps *PictureParameterSet
+				if pps, err = parseHevcPPS(nal); err == nil {
+					p.PictureParameterSets = append(p.PictureParameterSets, pps)
+				}
+			}
+			if err != nil {
+				logW.Printf("failed to parse NAL unit %d of hvcC: %v", array.NALUnitType, err)
+			}
+		}
+	}
+	for _, sps := range p.SequenceParameterSets {
+		if sps.NumUnitsInTick != 0 {
+			continue
+		}
+		for _, vps := range p.VideoParameterSets {
+			if vps.ID == sps.VPSID && vps.NumUnitsInTick != 0 {
+				sps.NumUnitsInTick, sps.TimeScale = vps.NumUnitsInTick, vps.TimeScale
+				sps.setHevcFrameRate(false)
+			}
+		}
+	}
+}
+
+// sequenceParameterSet returns the first SPS of the H.264 or HEVC configuration record.
+func (p *videoSampleEntry) sequenceParameterSet() *SequenceParameterSet {
+	if p == nil {
+		return nil
+	}
+	if avc, ok := p.decoderConfigurationRecords[VideoCodecH264].(*AvcConfig); ok && len(avc.SequenceParameterSets) > 0 {
+		return avc.SequenceParameterSets[0]
+	}
+	if hevc, ok := p.decoderConfigurationRecords[VideoCodecHEVC].(*HevcConfig); ok && len(hevc.SequenceParameterSets) > 0 {
+		return hevc.SequenceParameterSets[0]
+	}
+	return nil
+}
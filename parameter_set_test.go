@@ -0,0 +1,172 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// ue writes the value as ue(v)
+func (w *bitWriter) ue(v uint32) *bitWriter {
+	n := 0
+	for (v+1)>>uint(n+1) != 0 {
+		n++
+	}
+	return w.write(0, n).write(uint64(v+1), n+1)
+}
+
+// rbspTrailing writes rbsp_trailing_bits()
+func (w *bitWriter) rbspTrailing() *bitWriter {
+	return w.write(1, 1).align()
+}
+
+// hevcProfileTierLevel writes profile_tier_level(1, 0) of Main 10
+func (w *bitWriter) hevcProfileTierLevel(progressive, interlaced uint64) *bitWriter {
+	return w.write(0, 2).write(0, 1).write(2, 5).write(0x20000000, 32).
+		write(progressive, 1).write(interlaced, 1).write(0, 1).write(1, 1).write(0, 32).write(0, 12).write(120, 8)
+}
+
+func TestUnescapeRBSP(t *testing.T) {
+	got := unescapeRBSP([]byte{0x67, 0, 0, 3, 1, 0, 0, 3, 0, 3, 0, 0})
+	if want := []byte{0x67, 0, 0, 1, 0, 0, 0, 3, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestParseAvcSPS(t *testing.T) {
+	w := new(bitWriter).write(0x67, 8).write(100, 8).write(0, 8).write(40, 8).
+		ue(0).ue(1).ue(0).ue(0).write(0, 1).write(0, 1). // sps_id, chroma_format_idc, bit depth, no scaling matrix
+		ue(0).ue(0).ue(2).ue(4).write(0, 1).             // frame_num, poc type 0, max_num_ref_frames
+		ue(119).ue(67).write(1, 1).write(1, 1).          // 120x68 macroblocks, frame_mbs_only_flag
+		write(1, 1).ue(0).ue(0).ue(0).ue(4).             // cropping 8 lines at the bottom
+		write(1, 1).                                     // vui
+		write(1, 1).write(1, 8).write(0, 1).             // aspect_ratio_idc 1
+		write(1, 1).write(5, 3).write(0, 1).write(1, 1).write(1, 8).write(1, 8).write(1, 8).write(0, 1).
+		write(1, 1).write(1001, 32).write(60000, 32).write(1, 1). // timing
+		write(0, 1).write(0, 1).write(0, 1).                      // no hrd, pic_struct_present_flag
+		write(1, 1).write(1, 1).ue(0).ue(0).ue(16).ue(16).ue(2).ue(4).
+		rbspTrailing()
+	sps, err := parseAvcSPS(w.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sps.CodedWidth != 1920 || sps.CodedHeight != 1088 || sps.Width != 1920 || sps.Height != 1080 {
+		t.Errorf("size = %dx%d (%dx%d), want 1920x1080 (1920x1088)", sps.Width, sps.Height, sps.CodedWidth, sps.CodedHeight)
+	}
+	if sps.ProfileIdc != 100 || sps.LevelIdc != 40 || sps.ChromaFormatIdc != 1 || sps.BitDepthLuma != 8 || sps.Interlaced {
+		t.Errorf("unexpected sps %+v", sps)
+	}
+	if sps.SarWidth != 1 || sps.SarHeight != 1 || !sps.ColourDescription || sps.ColourPrimaries != 1 || sps.VideoFullRange {
+		t.Errorf("unexpected vui %+v", sps)
+	}
+	if sps.FrameRate < 29.97 || sps.FrameRate > 29.98 || !sps.FixedFrameRate {
+		t.Errorf("frame rate = %f, want 29.97", sps.FrameRate)
+	}
+	if sps.MaxDpbFrames != 4 {
+		t.Errorf("max dpb frames = %d, want 4", sps.MaxDpbFrames)
+	}
+}
+
+func TestParseAvcSPS_Interlaced(t *testing.T) {
+	// baseline profile, 720x576 interlaced, no vui
+	w := new(bitWriter).write(0x67, 8).write(66, 8).write(0, 8).write(30, 8).
+		ue(0).ue(0).ue(0).ue(0).ue(1).write(0, 1).
+		ue(44).ue(17).write(0, 1).write(1, 1).write(1, 1).
+		write(0, 1).write(0, 1).rbspTrailing()
+	sps, err := parseAvcSPS(w.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sps.Width != 720 || sps.Height != 576 || !sps.Interlaced || sps.FrameRate != 0 {
+		t.Errorf("unexpected sps %+v", sps)
+	}
+	// MaxDpbMbs 8100 of level 3.0 / (45 * 36)
+	if sps.MaxDpbFrames != 5 {
+		t.Errorf("max dpb frames = %d, want 5", sps.MaxDpbFrames)
+	}
+}
+
+func TestParseAvcPPS(t *testing.T) {
+	pps, err := parseAvcPPS(new(bitWriter).write(0x68, 8).ue(1).ue(0).write(1, 1).write(0, 1).ue(0).rbspTrailing().b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pps.ID != 1 || pps.SPSID != 0 || !pps.CABAC || pps.NumSliceGroups != 1 {
+		t.Errorf("unexpected pps %+v", pps)
+	}
+}
+
+func mkHevcSPS(vui bool) []byte {
+	w := new(bitWriter).write(hevcNalSPS<<9|1, 16).
+		write(0, 4).write(0, 3).write(1, 1).hevcProfileTierLevel(1, 0).
+		ue(0).ue(1).ue(3840).ue(2160).write(0, 1).                         // sps_id, chroma_format_idc, size, no conformance window
+		ue(2).ue(2).ue(4).                                                 // bit depth, log2_max_pic_order_cnt_lsb_minus4
+		write(1, 1).ue(5).ue(2).ue(0).                                     // sub layer ordering info
+		ue(0).ue(3).ue(0).ue(3).ue(0).ue(0).                               // coding block and transform block sizes
+		write(0, 1).write(1, 1).write(1, 1).write(0, 1).                   // scaling list, amp, sao, pcm
+		ue(2).ue(1).ue(0).ue(0).write(1, 1).                               // st_ref_pic_set(0)
+		write(1, 1).write(0, 1).ue(0).write(1, 1).write(0, 1).write(0, 1). // st_ref_pic_set(1), predicted
+		write(0, 1).write(1, 1).write(1, 1)                                // long term, tmvp, strong intra smoothing
+	if !vui {
+		return w.write(0, 1).rbspTrailing().b
+	}
+	return w.write(1, 1).
+		write(1, 1).write(255, 8).write(4, 16).write(3, 16).write(0, 1). // SAR 4:3, no overscan
+		write(1, 1).write(5, 3).write(1, 1).write(1, 1).write(9, 8).write(16, 8).write(9, 8).write(0, 1).
+		write(0, 1).write(0, 1).write(0, 1).write(0, 1). // neutral chroma, field_seq, frame_field_info, display window
+		write(1, 1).write(1, 32).write(50, 32).write(0, 1).write(0, 1).write(0, 1).
+		rbspTrailing().b
+}
+
+func TestParseHevcSPS(t *testing.T) {
+	sps, err := parseHevcSPS(mkHevcSPS(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sps.Width != 3840 || sps.Height != 2160 || sps.BitDepthLuma != 10 || sps.BitDepthChroma != 10 || sps.ChromaFormatIdc != 1 {
+		t.Errorf("unexpected sps %+v", sps)
+	}
+	if sps.ProfileIdc != 2 || sps.LevelIdc != 120 || sps.MaxDpbFrames != 6 || sps.Interlaced {
+		t.Errorf("unexpected sps %+v", sps)
+	}
+	if sps.SarWidth != 4 || sps.SarHeight != 3 || !sps.VideoFullRange || sps.ColourPrimaries != 9 ||
+		sps.TransferCharacteristics != 16 || sps.MatrixCoefficients != 9 {
+		t.Errorf("unexpected vui %+v", sps)
+	}
+	if sps.FrameRate != 50 {
+		t.Errorf("frame rate = %f, want 50", sps.FrameRate)
+	}
+}
+
+func TestHevcConfig_parseParameterSets(t *testing.T) {
+	newLog(ioutil.Discard)
+	vps := new(bitWriter).write(hevcNalVPS<<9|1, 16).
+		write(0, 4).write(3, 2).write(0, 6).write(0, 3).write(1, 1).write(0xffff, 16).hevcProfileTierLevel(1, 0).
+		write(1, 1).ue(5).ue(2).ue(0).write(0, 6).ue(0).
+		write(1, 1).write(1001, 32).write(24000, 32).write(0, 1).write(0, 1).rbspTrailing().b
+	pps := new(bitWriter).write(hevcNalPPS<<9|1, 16).ue(0).ue(0).write(0, 1).write(0, 1).write(2, 3).
+		write(0, 1).write(0, 1).ue(0).ue(0).write(1, 1).write(0, 1).write(0, 1).write(0, 1).
+		write(1, 1).write(1, 1).write(0, 1).write(0, 1).write(0, 1).write(0, 1).write(1, 1).write(0, 1).
+		rbspTrailing().b
+	hevc := &HevcConfig{NalUnitArrays: []NalUnitInfo{
+		{NALUnitType: hevcNalVPS, NalUnit: [][]byte{vps}},
+		{NALUnitType: hevcNalSPS, NalUnit: [][]byte{mkHevcSPS(false)}},
+		{NALUnitType: hevcNalPPS, NalUnit: [][]byte{pps, {0x44}}},
+	}}
+	hevc.parseParameterSets()
+	if len(hevc.VideoParameterSets) != 1 || len(hevc.SequenceParameterSets) != 1 || len(hevc.PictureParameterSets) != 1 {
+		t.Fatalf("got %d VPS, %d SPS, %d PPS, want 1 of each", len(hevc.VideoParameterSets),
+			len(hevc.SequenceParameterSets), len(hevc.PictureParameterSets))
+	}
+	sps := hevc.SequenceParameterSets[0]
+	if sps.FrameRate < 23.97 || sps.FrameRate > 23.98 {
+		t.Errorf("frame rate = %f, want 23.976 from VPS", sps.FrameRate)
+	}
+	if p := hevc.PictureParameterSets[0]; p.NumExtraSliceHeaderBits != 2 || !p.TilesEnabled || !p.CABAC {
+		t.Errorf("unexpected pps %+v", p)
+	}
+	entry := &videoSampleEntry{decoderConfigurationRecords: map[CodecType]interface{}{VideoCodecHEVC: hevc}}
+	if entry.sequenceParameterSet() != sps {
+		t.Error("sequenceParameterSet() should return the first SPS")
+	}
+}
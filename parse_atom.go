@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"errors"
@@ -25,10 +25,13 @@ func parseFtyp(p *MovieInfo, r *atomReader) error {
 	return err
 }
 
-// parse ssix box (SubSegment Index box)
+// parse ssix box (SubSegment Index box). The "ssix" box follows the "sidx" box it pertains to.
 func parseSsix(p *MovieInfo, r *atomReader) {
-	_ = r.Move(8) // version + flags
+	_ = r.Move(4) // version + flags
 	ssix := new(boxSsix)
+	if len(p.sidx) > 0 {
+		ssix.sidx = p.sidx[len(p.sidx)-1]
+	}
 	ssix.subSegmentCount = r.Read4()
 	for i := 0; i < int(ssix.subSegmentCount); i++ {
 		var tmpRange struct {
@@ -54,10 +57,13 @@ func parseSsix(p *MovieInfo, r *atomReader) {
 	p.ssix = append(p.ssix, ssix)
 }
 
-// parse sdix box (Segment Index box)
-func parseSidx(p *MovieInfo, r *atomReader) {
+// parse sdix box (Segment Index box). offset is the absolute position of the box in the file,
+// it's the anchor point of the byte ranges of the references.
+func parseSidx(p *MovieInfo, r *atomReader, offset int64) {
 	version, _ := r.ReadVersionFlags()
 	sidx := new(boxSidx)
+	sidx.offset = offset
+	sidx.size = r.AtomSize()
 	sidx.referenceID = r.Read4()
 	sidx.timeScale = r.Read4()
 	if version == 0 {
@@ -84,7 +90,7 @@ func parseSidx(p *MovieInfo, r *atomReader) {
 		reference.subSegmentDuration = r.Read4()
 		sap := r.Read4()
 		reference.startWithSAP = uint8(sap >> 31 & 0x1)
-		reference.sapType = uint8(sap >> 24 & 0x7)
+		reference.sapType = uint8(sap >> 28 & 0x7)
 		reference.sapDeltaTime = sap & 0xFFFFFFF
 		sidx.reference = append(sidx.reference, reference)
 	}
@@ -112,6 +118,10 @@ func parseMoov(movie *MovieInfo, reader *atomReader) error {
 		case fourCCtrak:
 			err = movie.parseTrak(itemReader)
 			break
+		case fourCCpssh:
+			err = parsePssh(movie, itemReader)
+		case fourCCudta:
+			movie.parseUdta(itemReader)
 		}
 		if err != nil {
 			return nil
@@ -119,6 +129,32 @@ func parseMoov(movie *MovieInfo, reader *atomReader) error {
 	}
 }
 
+// parse moov/udta box, only the freeform tags of "meta/ilst" are parsed currently
+func (movie *MovieInfo) parseUdta(r *atomReader) {
+	meta, err := r.FindSubAtom(fourCCmeta)
+	if err != nil || meta == nil {
+		return
+	}
+	// "meta" is a FullBox in ISO/IEC 14496-12 but a plain box in QuickTime
+	header := make([]byte, 8)
+	if meta.Peek(header) == nil && string(header[4:]) != "hdlr" {
+		_ = meta.Move(4)
+	}
+	ilst, err := meta.FindSubAtom(fourCCilst)
+	if err != nil || ilst == nil {
+		return
+	}
+	for {
+		item, err := ilst.GetSubAtom()
+		if err != nil {
+			break
+		}
+		if item.TypeCC() == fourCC____ {
+			movie.parseFreeformTag(item)
+		}
+	}
+}
+
 func constructTrackInfo(movie *MovieInfo) error {
 	return nil
 }
@@ -147,7 +183,7 @@ func (movie *MovieInfo) parseMvex(reader *atomReader) error {
 	parseTrex := func(p *boxMvex, r *atomReader) {
 		logD.Print("parsing moov.mvex.trex, ", r.a)
 		trex := new(boxTrex)
-		_ = r.Move(8)
+		_ = r.Move(4)
 		// Version + flags
 		trex.trackId = r.Read4()
 		trex.defaultSampleDescriptionIndex = r.Read4()
@@ -169,6 +205,7 @@ func (movie *MovieInfo) parseMvex(reader *atomReader) error {
 	parseLeva := func(p *boxMvex, r *atomReader) {
 		logD.Print("parsing moov.mvex.leva, ", r.a)
 		leva := new(boxLeva)
+		_ = r.Move(4) // version + flags
 		leva.levelCount = r.ReadUnsignedByte()
 		for i := 0; i < int(leva.levelCount); i++ {
 			var level struct {
@@ -240,7 +277,9 @@ func (movie *MovieInfo) parseMvex(reader *atomReader) error {
 // parse trak box
 func (movie *MovieInfo) parseTrak(reader *atomReader) error {
 	trak := new(boxTrak)
-	trak.quickTimeFormat = movie.ftyp.isQuickTimeFormat
+	trak.movie = movie
+	// the classic QuickTime movie has no "ftyp"
+	trak.quickTimeFormat = movie.ftyp == nil || movie.ftyp.isQuickTimeFormat
 	for {
 		itemReader, err := reader.GetSubAtom()
 		if err != nil {
@@ -257,9 +296,24 @@ func (movie *MovieInfo) parseTrak(reader *atomReader) error {
 		case fourCCedts:
 			trak.parseEdts(itemReader)
 			break
+		case fourCCtref:
+			trak.parseTref(itemReader)
+			break
+		case fourCCtrgr:
+			trak.parseTrgr(itemReader)
+			break
 		case fourCCmdia:
 			err = trak.parseMdia(itemReader)
 			break
+		case fourCCuuid:
+			trak.parseUuid(itemReader)
+			break
+		case fourCCclip:
+			trak.parseClip(itemReader)
+			break
+		case fourCCmatt:
+			trak.parseMatt(itemReader)
+			break
 		default:
 			break
 		}
@@ -268,6 +322,7 @@ func (movie *MovieInfo) parseTrak(reader *atomReader) error {
 		}
 	}
 	//trak.constructPacketList()
+	movie.trak = append(movie.trak, trak)
 	return nil
 }
 
@@ -1,6 +1,7 @@
-package main
+package fmp4parser
 
 import (
+	"bytes"
 	"errors"
 	"io"
 )
@@ -35,39 +36,83 @@ func (p *boxTrak) parseTkhd(r *atomReader) {
 
 // parse edts box
 func (p *boxTrak) parseEdts(r *atomReader) {
+	ar, err := r.FindSubAtom(fourCCelst)
+	if err != nil || ar == nil {
+		return
+	}
 	edts := new(boxEdts)
-	_ = r.Move(4) // fourCCelts
-	version, _ := r.ReadVersionFlags()
-	edts.entryCount = r.Read4()
+	version, _ := ar.ReadVersionFlags()
+	edts.entryCount = ar.Read4()
 	for i := uint32(0); i < edts.entryCount; i++ {
 		if version == 1 {
-			edts.editDuration = append(edts.editDuration, r.Read8())
-			edts.mediaTime = append(edts.mediaTime, r.Read8S())
+			edts.editDuration = append(edts.editDuration, ar.Read8())
+			edts.mediaTime = append(edts.mediaTime, ar.Read8S())
 		} else {
-			edts.editDuration = append(edts.editDuration, uint64(r.Read4()))
-			edts.mediaTime = append(edts.mediaTime, int64(r.Read4S()))
+			edts.editDuration = append(edts.editDuration, uint64(ar.Read4()))
+			edts.mediaTime = append(edts.mediaTime, int64(ar.Read4S()))
 		}
-		integerPart := r.Read2S()
-		fractionPart := r.Read2()
-		edts.mediaRate = append(edts.mediaRate, float32(integerPart)+float32(fractionPart)/100)
+		// media_rate is 16.16 fixed-point
+		integerPart := ar.Read2S()
+		fractionPart := ar.Read2()
+		edts.mediaRate = append(edts.mediaRate, float64(integerPart)+float64(fractionPart)/(1<<16))
 	}
 	p.edts = edts
 }
 
-// parse google spatial media. Extra
-func parseUuid(p *MovieInfo, r *atomReader) error {
+// parse tref box, each sub box is a TrackReferenceTypeBox whose type is the reference type
+func (p *boxTrak) parseTref(r *atomReader) {
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			return
+		}
+		ref := TrackReference{Type: TrackReferenceType(int2String(ar.TypeCC()))}
+		for ar.Len() >= 4 {
+			ref.TrackIDs = append(ref.TrackIDs, ar.Read4())
+		}
+		p.tref = append(p.tref, ref)
+	}
+}
+
+// parse trgr box, each sub box is a TrackGroupTypeBox whose type is the track group type
+func (p *boxTrak) parseTrgr(r *atomReader) {
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			return
+		}
+		if ar.Size() < 8 {
+			continue
+		}
+		_, _ = ar.ReadVersionFlags()
+		group := TrackGroup{Type: int2String(ar.TypeCC()), ID: ar.Read4()}
+		// StereoVideoGroupBox: bit(31) reserved, bit(1) left_view_flag
+		if ar.TypeCC() == fourCCster && ar.Len() >= 4 {
+			group.LeftView = ar.Read4()&1 != 0
+		}
+		p.trgr = append(p.trgr, group)
+	}
+}
+
+// parse google spatial media (spherical video V1) of "uuid" box. Extra
+func (p *boxTrak) parseUuid(r *atomReader) {
 	// check if is spatial-media ref: https://github.com/google/spatial-media
-	if r.a.Size() < 16 {
-		return nil
+	if r.Size() < 16 {
+		return
 	}
-	sphericalMedia := r.Read4() == 0xffcc8263 && r.Read4() == 0xf8554a93 && r.Read4() == 0x8814587a && r.Read4() == 0x02521fdd
-	if sphericalMedia {
-		logD.Println("This movie is a spatial media")
-		rdfData := make([]byte, r.a.Size()-16)
-		_, _ = r.ReadBytes(rdfData)
-		logD.Println(string(rdfData))
+	usertype := make([]byte, 16)
+	_, _ = r.ReadBytes(usertype)
+	if !bytes.Equal(usertype, sphericalUUID) {
+		return
 	}
-	return nil
+	rdfData := make([]byte, r.Len())
+	_, _ = r.ReadBytes(rdfData)
+	spatial, err := parseSphericalXML(rdfData)
+	if err != nil {
+		logD.Println("invalid spherical video metadata: ", string(rdfData))
+		return
+	}
+	p.spatial = spatial
 }
 
 // parse moov/trak/mdia box
@@ -125,7 +170,7 @@ func (p *boxTrak) parseMdhd(r *atomReader) {
 
 // parse trak/mdia/hdlr box
 func (p *boxTrak) parseHdlr(r *atomReader) TrackType {
-	_ = r.Move(8) // Version + flags
+	_ = r.Move(4) // Version + flags
 	_ = r.Move(4) // pre_defined 0
 	handlerType := r.Read4()
 	switch handlerType {
@@ -133,8 +178,11 @@ func (p *boxTrak) parseHdlr(r *atomReader) TrackType {
 		return VideoTrack
 	case string2int("soun"):
 		return AudioTrack
-	case string2int("subt"):
+	case string2int("subt"), string2int("text"), string2int("sbtl"):
+		// "text" and "sbtl" are the text and subtitle media of QuickTime
 		return SubtitleTrack
+	case string2int("tmcd"):
+		return TimecodeTrack
 	default:
 		/*
 			there are some other type of handler type, such as,
@@ -156,7 +204,7 @@ func (p *boxTrak) parseElng(r *atomReader) {
 }
 
 // parse trak/mdia/minf box
-// Notice: dinf box and media header box(vmhd/smhd/nmhd/sthd) are omitted
+// Notice: media header box(vmhd/smhd/nmhd/sthd) are omitted
 func (p *boxTrak) parseMinf(reader *atomReader) error {
 	for {
 		itemReader, err := reader.GetSubAtom()
@@ -167,12 +215,16 @@ func (p *boxTrak) parseMinf(reader *atomReader) error {
 				return err
 			}
 		}
-		if itemReader.TypeCC() != fourCCstbl {
-			continue
-		}
-		err = p.parseStbl(itemReader)
-		if err != nil {
-			return err
+		switch itemReader.TypeCC() {
+		case fourCCdinf:
+			p.parseDinf(itemReader)
+		case fourCCgmhd:
+			p.parseGmhd(itemReader)
+		case fourCCstbl:
+			err = p.parseStbl(itemReader)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -232,13 +284,17 @@ func (p *boxTrak) parseStbl(reader *atomReader) (err error) {
 			p.parseSdtp(itemReader)
 
 		case fourCCsbgp:
-			p.sbgp = parseSbgp(itemReader)
+			if sbgp := parseSbgp(itemReader); sbgp != nil {
+				p.sbgp = append(p.sbgp, sbgp)
+			}
 
 		case fourCCsgpd:
-			p.sgpd, _ = parseSgpd(itemReader)
+			if sgpd, _ := parseSgpd(itemReader); sgpd != nil {
+				p.sgpd = append(p.sgpd, sgpd)
+			}
 
 		case fourCCsubs:
-			p.subs = parseSubs(itemReader)
+			p.subs = append(p.subs, parseSubs(itemReader))
 
 		case fourCCsaiz:
 			if p.encrypted {
@@ -255,8 +311,9 @@ func (p *boxTrak) parseStbl(reader *atomReader) (err error) {
 
 		}
 	}
-	if sencAtomReader != nil && p.sbgp != nil && p.sgpd != nil && len(p.protection) > 0 {
-		p.senc, _ = parseSenc(sencAtomReader, p.sbgp, p.sgpd, p.protection[0].DefaultPerSampleIVSize)
+	sbgp, sgpd := findSbgp(p.sbgp, fourCCseig), findSgpd(p.sgpd, fourCCseig)
+	if sencAtomReader != nil && sbgp != nil && sgpd != nil && len(p.protection) > 0 {
+		p.senc, _ = parseSenc(sencAtomReader, sbgp, sgpd, p.protection[0].DefaultPerSampleIVSize)
 	}
 	return nil
 }
@@ -283,6 +340,7 @@ func (p *boxTrak) parseStsd(r *atomReader) (err error) {
 				return err
 			}
 		}
+		entryCount := len(p.sampleEntries)
 		trackType := getTrackType(itemReader.TypeCC())
 		switch trackType {
 		case AudioTrack:
@@ -295,6 +353,13 @@ func (p *boxTrak) parseStsd(r *atomReader) (err error) {
 			// TODO. parse subtitle sample entry
 			// err = p.parseSubtitleSampleEntry(ar)
 			break
+		case TimecodeTrack:
+			err = p.parseTimecodeSampleEntry(itemReader)
+			break
+		}
+		// keep the place of the entry, so that the sample description index is still valid
+		if len(p.sampleEntries) == entryCount {
+			p.sampleEntries = append(p.sampleEntries, &sampleEntry{format: itemReader.TypeCC()})
 		}
 		if err != nil {
 			break
@@ -422,13 +487,15 @@ func (p *boxTrak) parseCslg(r *atomReader) {
 func (p *boxTrak) parseStss(r *atomReader) {
 	_, _ = r.ReadVersionFlags()
 	entries := r.Read4()
+	p.stss = &boxStss{entryCount: entries}
 	if entries <= 0 {
 		return
 	}
-	p.syncSamples = make([]uint32, entries)
+	p.syncSamples = make([]uint32, 0, entries)
 	for i := uint32(0); i < entries; i++ {
 		p.syncSamples = append(p.syncSamples, r.Read4())
 	}
+	p.stss.sampleNumber = p.syncSamples
 }
 
 func (p *boxTrak) parseStsh(r *atomReader) {
@@ -559,57 +626,47 @@ func parseSbgp(r *atomReader) *boxSbgp {
 	return sbgp
 }
 
-// parse sgpd box
+// parse sgpd box. The entries are decoded by sampleGroupEntryParsers, the entries of the other
+// grouping types are kept as raw bytes if the length of the entries is known.
 func parseSgpd(r *atomReader) (*boxSgpd, error) {
 	sgpd := new(boxSgpd)
 	version, _ := r.ReadVersionFlags()
 	sgpd.groupingType = r.Read4()
-	if int2String(sgpd.groupingType) != "seig" {
-		return nil, ErrUnsupportedSampleGroupType
-	}
 	if version == 1 {
 		sgpd.defaultLength = new(uint32)
 		*sgpd.defaultLength = r.Read4()
-		if *sgpd.defaultLength == 0 || *sgpd.defaultLength > 20 {
-			return nil, ErrUnsupportedVariableSampleGroupLength
-		}
 	} else if version >= 2 {
 		sgpd.defaultSampleDescriptionIndex = new(uint32)
 		*sgpd.defaultSampleDescriptionIndex = r.Read4()
 	}
 	sgpd.entryCount = r.Read4()
+	parse, ok := sampleGroupEntryParsers[sgpd.groupingType]
 	for i := uint32(0); i < sgpd.entryCount; i++ {
+		entryReader := r
 		if version == 1 {
-			if *sgpd.defaultLength == 0 {
-				*sgpd.descriptionLength = r.Read4()
-				if *sgpd.descriptionLength > 16 {
-					return nil, ErrInvalidLengthOfSampleGroup
-				}
+			length := *sgpd.defaultLength
+			if length == 0 {
+				length = r.Read4() // description_length
 			}
-		}
-		// CencSampleEncryptionInformationGroupEntry
-		// only support "cenc" scheme currently
-		cencGroupEntry := new(cencSampleEncryptionInformationGroupEntry)
-		_ = r.Move(1) // reversed 1 byte
-		byteT := r.ReadUnsignedByte()
-		cencGroupEntry.cryptByteBlock = byteT >> 4
-		cencGroupEntry.skipByteBlock = byteT & 0x0F
-		cencGroupEntry.isProtected = r.ReadUnsignedByte() != 0
-		cencGroupEntry.perSampleIVSize = r.ReadUnsignedByte()
-		if cencGroupEntry.perSampleIVSize != 8 && cencGroupEntry.perSampleIVSize != 16 {
-			return nil, ErrInvalidLengthOfIVInSampleGroup
-		}
-		cencGroupEntry.kID = make([]byte, 16)
-		_, _ = r.ReadBytes(cencGroupEntry.kID)
-		if cencGroupEntry.isProtected && cencGroupEntry.perSampleIVSize == 0 {
-			constIVSize := r.ReadUnsignedByte()
-			if constIVSize != 8 && constIVSize != 16 {
-				return nil, ErrInvalidLengthOfIVInSampleGroup
+			if int(length) > r.Len() {
+				return sgpd, ErrInvalidLengthOfSampleGroup
 			}
-			cencGroupEntry.constantIV = make([]byte, constIVSize)
-			_, _ = r.ReadBytes(cencGroupEntry.constantIV)
+			b := make([]byte, length)
+			_, _ = r.ReadBytes(b)
+			if !ok {
+				sgpd.entries = append(sgpd.entries, b)
+				continue
+			}
+			entryReader = newAtomReader(b, &atom{atomType: sgpd.groupingType, bodySize: int64(length)})
+		} else if !ok {
+			// the length of the entries is unknown
+			return sgpd, ErrUnsupportedSampleGroupType
+		}
+		entry, err := parse(entryReader)
+		if err != nil {
+			return sgpd, err
 		}
-		sgpd.cencGroupEntries = append(sgpd.cencGroupEntries, cencGroupEntry)
+		sgpd.entries = append(sgpd.entries, entry)
 	}
 	return sgpd, nil
 }
@@ -674,11 +731,8 @@ func parseSenc(r *atomReader, sbgp *boxSbgp, sgpd *boxSgpd, defaultPerSampleIVSi
 				if index > 65536 {
 					index -= 65536
 				}
-				if index < uint32(len(sgpd.cencGroupEntries)) {
-					cencSampleEncryptionInformationEntry := sgpd.cencGroupEntries[index-1]
-					if cencSampleEncryptionInformationEntry != nil {
-						iVSize = cencSampleEncryptionInformationEntry.perSampleIVSize
-					}
+				if entry, ok := sgpd.entry(index).(*cencSampleEncryptionInformationGroupEntry); ok {
+					iVSize = entry.perSampleIVSize
 				}
 			}
 		}
@@ -716,21 +770,20 @@ func parseSubs(r *atomReader) *boxSubs {
 		sampleEntry := new(subSampleEntry)
 		sampleEntry.sampleDelta = r.Read4()
 		sampleEntry.subSampleCount = r.Read2()
-		if sampleEntry.subSampleCount > 0 {
-			for j := uint16(0); j < sampleEntry.subSampleCount; j++ {
-				subSample := new(subSampleInfo)
-				if version == 1 {
-					subSample.subSampleSize = r.Read4()
-				} else {
-					subSample.subSampleSize = uint32(r.Read2())
-				}
-				subSample.subSamplePriority = r.ReadUnsignedByte()
-				subSample.discardable = r.ReadUnsignedByte()
-				subSample.codecSpecificParameters = r.Read4()
-				sampleEntry.subSamples = append(sampleEntry.subSamples, subSample)
+		for j := uint16(0); j < sampleEntry.subSampleCount; j++ {
+			subSample := new(subSampleInfo)
+			if version == 1 {
+				subSample.subSampleSize = r.Read4()
+			} else {
+				subSample.subSampleSize = uint32(r.Read2())
 			}
-			subs.entries = append(subs.entries, sampleEntry)
+			subSample.subSamplePriority = r.ReadUnsignedByte()
+			subSample.discardable = r.ReadUnsignedByte()
+			subSample.codecSpecificParameters = r.Read4()
+			sampleEntry.subSamples = append(sampleEntry.subSamples, subSample)
 		}
+		// the entry without sub-samples is kept for sample_delta
+		subs.entries = append(subs.entries, sampleEntry)
 	}
 	return subs
 }
@@ -768,10 +821,14 @@ func (p *movieFragment) parseTraf(r *atomReader) (err error) {
 			fragment.saiz = parseSaiz(ar)
 
 		case fourCCsbgp:
-			fragment.sbgp = parseSbgp(ar)
+			if sbgp := parseSbgp(ar); sbgp != nil {
+				fragment.sbgp = append(fragment.sbgp, sbgp)
+			}
 
 		case fourCCsgpd:
-			fragment.sgpd, _ = parseSgpd(ar)
+			if sgpd, _ := parseSgpd(ar); sgpd != nil {
+				fragment.sgpd = append(fragment.sgpd, sgpd)
+			}
 
 		case fourCCsubs:
 			fragment.subs = append(fragment.subs, parseSubs(ar))
@@ -781,8 +838,9 @@ func (p *movieFragment) parseTraf(r *atomReader) (err error) {
 
 		}
 	}
-	if fragment.sgpd != nil && fragment.sbgp != nil && fragment.trackInfo() != nil && len(fragment.trackInfo().protection) != 0 {
-		fragment.senc, err = parseSenc(sencAtomReader, fragment.sbgp, fragment.sgpd, fragment.trackInfo().protection[0].DefaultPerSampleIVSize)
+	sbgp, sgpd := findSbgp(fragment.sbgp, fourCCseig), findSgpd(fragment.sgpd, fourCCseig)
+	if sencAtomReader != nil && sgpd != nil && sbgp != nil && fragment.trackInfo() != nil && len(fragment.trackInfo().protection) != 0 {
+		fragment.senc, err = parseSenc(sencAtomReader, sbgp, sgpd, fragment.trackInfo().protection[0].DefaultPerSampleIVSize)
 	}
 	p.fragment = append(p.fragment, fragment)
 	return err
@@ -843,26 +901,31 @@ func (p *trackFragment) parseTfdt(r *atomReader) {
 	}
 }
 
-// parseTfhd will parse track fragment decode time
+// parseTfhd will parse track fragment header
 func (p *trackFragment) parseTfhd(r *atomReader) {
 	p.flags = r.Read4() & 0x00FFFFFF
 	p.trackID = r.Read4()
 	if p.flags&0x000001 != 0 {
 		p.baseDataOffset = new(uint64)
 		*p.baseDataOffset = r.Read8()
-	} else if p.flags&0x000002 != 0 {
+	}
+	if p.flags&0x000002 != 0 {
 		p.sampleDescriptionIndex = new(uint32)
 		*p.sampleDescriptionIndex = r.Read4()
-	} else if p.flags&0x000008 != 0 {
+	}
+	if p.flags&0x000008 != 0 {
 		p.defaultSampleDuration = new(uint32)
 		*p.defaultSampleDuration = r.Read4()
-	} else if p.flags&0x000010 != 0 {
+	}
+	if p.flags&0x000010 != 0 {
 		p.defaultSampleSize = new(uint32)
 		*p.defaultSampleSize = r.Read4()
-	} else if p.flags&0x000020 != 0 {
+	}
+	if p.flags&0x000020 != 0 {
 		p.defaultSampleFlags = new(uint32)
 		*p.defaultSampleFlags = r.Read4()
-	} else if p.flags&0x000001 == 0 && p.flags&0x020000 != 0 {
+	}
+	if p.flags&0x000001 == 0 && p.flags&0x020000 != 0 {
 		p.defaultBaseIsMoof = true
 	}
 }
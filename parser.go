@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"fmt"
@@ -6,11 +6,15 @@ import (
 )
 
 type mediaInfo struct {
-	r     *mp4Reader
-	movie *MovieInfo
-	moof  *movieFragment // current
+	r         *mp4Reader
+	movie     *MovieInfo
+	moof      *movieFragment   // current
+	fragments []*movieFragment // all the parsed fragments in file order
 
-	dataPos int64
+	dataPos  int64
+	initEnd  int64 // end position of "moov", [0, initEnd) is the initialization segment
+	stypPos  int64 // position of the last "styp" which doesn't belong to a fragment yet, or -1
+	fileSize int64 // end position of the last atom, it's valid when the parsing finished
 
 	// for internal usage
 	currentState parsingState
@@ -20,6 +24,7 @@ type mediaInfo struct {
 func newMediaInfo(r io.ReadSeeker) *mediaInfo {
 	return &mediaInfo{r: newMp4Reader(r),
 		currentState: stateParsingIDLE,
+		stypPos:      -1,
 	}
 }
 
@@ -53,7 +58,11 @@ func (p *mediaInfo) parseInternal() (err error) {
 				logE.Println(e)
 				return e
 			}
-			_ = parseFtyp(p.movie, ftypReader)
+			if ftypReader.TypeCC() == fourCCstyp {
+				p.stypPos = p.r.GetAtomPosition()
+			} else {
+				_ = parseFtyp(p.movie, ftypReader)
+			}
 			p.currentState = stateParsingIDLE
 			break
 		case stateParsingMOOV:
@@ -66,6 +75,7 @@ func (p *mediaInfo) parseInternal() (err error) {
 			if e != nil {
 				return e
 			}
+			p.initEnd = p.r.GetAtomPosition() + movieReader.AtomSize()
 			p.currentState = stateParsingIDLE
 			break
 		case stateParsingMOOF:
@@ -74,10 +84,18 @@ func (p *mediaInfo) parseInternal() (err error) {
 				return e
 			}
 			p.moof = newMovieFragment(p.movie)
+			p.moof.offset = p.r.GetAtomPosition()
+			p.moof.size = moofReader.AtomSize()
+			p.moof.segmentOffset = p.moof.offset
+			if p.stypPos >= 0 {
+				p.moof.segmentOffset = p.stypPos
+				p.stypPos = -1
+			}
 			e = parseMoof(p.moof, moofReader)
 			if e != nil {
 				return e
 			}
+			p.fragments = append(p.fragments, p.moof)
 			p.currentState = stateParsingIDLE
 			break
 		case stateParsingSIDX:
@@ -85,7 +103,7 @@ func (p *mediaInfo) parseInternal() (err error) {
 			if e != nil {
 				return e
 			}
-			parseSidx(p.movie, sidxReader)
+			parseSidx(p.movie, sidxReader, p.r.GetAtomPosition())
 			p.currentState = stateParsingIDLE
 			break
 		case stateParsingSSIX:
@@ -117,6 +135,7 @@ func (p *mediaInfo) checkStatus() (*atom, error) {
 		if e != nil {
 			if e == io.EOF {
 				p.currentState = stateParsingEnd
+				p.fileSize = p.r.getReaderPosition()
 			}
 			return nil, e
 		}
@@ -0,0 +1,344 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// The atoms of QuickTime File Format which aren't in ISO/IEC 14496-12, refer to
+// https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/QTFFChap2/qtff2.html
+
+// QuickTime is the QuickTime specific information of a track.
+type QuickTime struct {
+	ClipRegion    *QuickTimeRegion // "clip/crgn", the region of the track to be displayed
+	Matte         *QuickTimeMatte  // "matt/kmat", the matte to be applied to the track
+	BaseMediaInfo *BaseMediaInfo   // "gmhd/gmin" of the tracks other than video and sound, e.g. text and timecode
+	TimecodeInfo  *TimecodeInfo    // "gmhd/tmcd/tcmi" of timecode tracks
+}
+
+// QuickTimeRect is a rectangle of QuickDraw in pixels.
+type QuickTimeRect struct {
+	Top    int16
+	Left   int16
+	Bottom int16
+	Right  int16
+}
+
+// QuickTimeRegion is a QuickDraw region, the data is the region definition other than the bounds.
+type QuickTimeRegion struct {
+	Bounds QuickTimeRect
+	Data   []byte
+}
+
+// QuickTimeMatte is the compressed matte of "kmat": an image description of the matte and
+// the matte data.
+type QuickTimeMatte struct {
+	Format      string // data format of the image description, e.g. "raw "
+	Width       uint16
+	Height      uint16
+	Depth       uint16
+	Description []byte // the whole image description
+	Data        []byte // the matte data
+}
+
+// BaseMediaInfo is "gmin", the graphics mode and the sound balance of the base media.
+type BaseMediaInfo struct {
+	GraphicsMode uint16    // transfer mode, e.g. 0x40 is dither copy
+	OpColor      [3]uint16 // red, green and blue for the transfer mode
+	Balance      int16     // 8.8 fixed-point, 0 is the center
+}
+
+// TimecodeInfo is "tcmi", the text style of displaying the timecode.
+type TimecodeInfo struct {
+	TextFont        uint16
+	TextFace        uint16
+	TextSize        uint16
+	TextColor       [3]uint16
+	BackgroundColor [3]uint16
+	FontName        string
+}
+
+// DataReference is an entry of "dinf/dref", it declares where the media data of the track is.
+type DataReference struct {
+	Type          string       // "url ", "urn " or "alis"
+	SelfContained bool         // the media data is in the same file as "moov"
+	Name          string       // name of "urn "
+	Location      string       // location of "url " and "urn "
+	Alias         *AliasRecord // Macintosh alias of "alis", nil if the entry is self-contained
+}
+
+// AliasRecord is the Macintosh alias of the file which contains the media data.
+type AliasRecord struct {
+	VolumeName       string
+	FileName         string
+	Directory        string // the name of the parent directory
+	Path             string // the absolute path in HFS style, i.e. separated by ':'
+	POSIXPath        string // the POSIX path of the file relative to the volume
+	VolumeMountPoint string // the POSIX path of the volume
+}
+
+// AudioChannelLayout is "chan" of a QuickTime sound description, the AudioChannelLayout
+// structure of Core Audio.
+type AudioChannelLayout struct {
+	LayoutTag     uint32                    // mChannelLayoutTag, the low 16 bits are the number of channels
+	ChannelBitmap uint32                    // mChannelBitmap, used if LayoutTag is ChannelLayoutTagUseChannelBitmap
+	Descriptions  []AudioChannelDescription // used if LayoutTag is ChannelLayoutTagUseChannelDescriptions
+}
+
+// AudioChannelDescription describes a channel of AudioChannelLayout.
+type AudioChannelDescription struct {
+	Label       uint32 // mChannelLabel, e.g. 1 is left and 2 is right
+	Flags       uint32 // mChannelFlags, the coordinates are rectangular or spherical
+	Coordinates [3]float32
+}
+
+// the special values of AudioChannelLayout.LayoutTag
+const (
+	ChannelLayoutTagUseChannelDescriptions uint32 = 0
+	ChannelLayoutTagUseChannelBitmap       uint32 = 1 << 16
+)
+
+// ChannelCount returns the number of channels of the layout.
+func (p *AudioChannelLayout) ChannelCount() uint16 {
+	switch p.LayoutTag {
+	case ChannelLayoutTagUseChannelDescriptions:
+		return uint16(len(p.Descriptions))
+	case ChannelLayoutTagUseChannelBitmap:
+		return uint16(bits.OnesCount32(p.ChannelBitmap))
+	}
+	return uint16(p.LayoutTag & 0xFFFF)
+}
+
+// quickTimeInfo returns QuickTime of the track, it's created by the first QuickTime atom.
+func (p *boxTrak) quickTimeInfo() *QuickTime {
+	if p.quickTime == nil {
+		p.quickTime = new(QuickTime)
+	}
+	return p.quickTime
+}
+
+// parse trak/clip box, only "crgn" is in it
+func (p *boxTrak) parseClip(r *atomReader) {
+	crgn, err := r.FindSubAtom(fourCCcrgn)
+	if err != nil || crgn == nil || crgn.Size() < 10 {
+		return
+	}
+	size := int(crgn.Read2())
+	if size < 10 {
+		return
+	}
+	region := new(QuickTimeRegion)
+	region.Bounds = QuickTimeRect{Top: crgn.Read2S(), Left: crgn.Read2S(), Bottom: crgn.Read2S(), Right: crgn.Read2S()}
+	if size > crgn.Size() {
+		size = crgn.Size()
+	}
+	region.Data = make([]byte, size-10)
+	_, _ = crgn.ReadBytes(region.Data)
+	p.quickTimeInfo().ClipRegion = region
+}
+
+// parse trak/matt box, only "kmat" is in it
+func (p *boxTrak) parseMatt(r *atomReader) {
+	kmat, err := r.FindSubAtom(fourCCkmat)
+	if err != nil || kmat == nil || kmat.Size() < 4+86 {
+		return
+	}
+	_ = kmat.Move(4) // version + flags
+	data := make([]byte, kmat.Len())
+	_, _ = kmat.ReadBytes(data)
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 86 || size > len(data) {
+		return
+	}
+	p.quickTimeInfo().Matte = &QuickTimeMatte{
+		Format:      int2String(binary.BigEndian.Uint32(data[4:])),
+		Width:       binary.BigEndian.Uint16(data[32:]),
+		Height:      binary.BigEndian.Uint16(data[34:]),
+		Depth:       binary.BigEndian.Uint16(data[82:]),
+		Description: data[:size],
+		Data:        data[size:],
+	}
+}
+
+// parse trak/mdia/minf/gmhd box, the base media information header of QuickTime
+func (p *boxTrak) parseGmhd(r *atomReader) {
+	if gmin, err := r.FindSubAtom(fourCCgmin); err == nil && gmin != nil && gmin.Size() >= 14 {
+		_ = gmin.Move(4) // version + flags
+		info := new(BaseMediaInfo)
+		info.GraphicsMode = gmin.Read2()
+		for i := range info.OpColor {
+			info.OpColor[i] = gmin.Read2()
+		}
+		info.Balance = gmin.Read2S()
+		p.quickTimeInfo().BaseMediaInfo = info
+	}
+	tmcd, err := r.FindSubAtom(fourCCtmcd)
+	if err != nil || tmcd == nil {
+		return
+	}
+	tcmi, err := tmcd.FindSubAtom(fourCCtcmi)
+	if err != nil || tcmi == nil || tcmi.Size() < 24 {
+		return
+	}
+	_ = tcmi.Move(4) // version + flags
+	info := new(TimecodeInfo)
+	info.TextFont = tcmi.Read2()
+	info.TextFace = tcmi.Read2()
+	info.TextSize = tcmi.Read2()
+	_ = tcmi.Move(2) // reserved
+	for i := range info.TextColor {
+		info.TextColor[i] = tcmi.Read2()
+	}
+	for i := range info.BackgroundColor {
+		info.BackgroundColor[i] = tcmi.Read2()
+	}
+	if tcmi.Len() > 0 {
+		name := make([]byte, tcmi.Len())
+		_, _ = tcmi.ReadBytes(name)
+		info.FontName = pascalString(name)
+	}
+	p.quickTimeInfo().TimecodeInfo = info
+}
+
+// parse trak/mdia/minf/dinf box, the entries of "dref" in order
+func (p *boxTrak) parseDinf(r *atomReader) {
+	dref, err := r.FindSubAtom(fourCCdref)
+	if err != nil || dref == nil {
+		return
+	}
+	_ = dref.Move(4) // version + flags
+	entryCount := dref.Read4()
+	for i := uint32(0); i < entryCount; i++ {
+		ar, err := dref.GetSubAtom()
+		if err != nil {
+			return
+		}
+		_, flags := ar.ReadVersionFlags()
+		ref := DataReference{Type: int2String(ar.TypeCC()), SelfContained: flags&0x000001 != 0}
+		data := make([]byte, ar.Len())
+		_, _ = ar.ReadBytes(data)
+		switch ar.TypeCC() {
+		case fourCCurl:
+			ref.Location = cString(data)
+		case fourCCurn:
+			ref.Name = cString(data)
+			if len(ref.Name) < len(data) {
+				ref.Location = cString(data[len(ref.Name)+1:])
+			}
+		case fourCCalis:
+			if !ref.SelfContained {
+				ref.Alias = parseAliasRecord(data)
+			}
+		}
+		p.dref = append(p.dref, ref)
+	}
+}
+
+// parseAliasRecord parses the Macintosh alias record of version 2: the fixed part of 150
+// bytes, followed by the variable length tagged data, refer to the implementation of ffmpeg.
+func parseAliasRecord(b []byte) *AliasRecord {
+	if len(b) < 150 {
+		return nil
+	}
+	alias := &AliasRecord{
+		VolumeName: pascalString(b[10:38]),
+		FileName:   pascalString(b[50:114]),
+	}
+	for pos := 150; pos+4 <= len(b); {
+		tag := int16(binary.BigEndian.Uint16(b[pos:]))
+		size := int(binary.BigEndian.Uint16(b[pos+2:]))
+		pos += 4
+		if tag == -1 || pos+size > len(b) {
+			break
+		}
+		value := string(b[pos : pos+size])
+		switch tag {
+		case 0:
+			alias.Directory = value
+		case 2:
+			alias.Path = value
+		case 18:
+			alias.POSIXPath = strings.TrimRight(value, "\x00")
+		case 19:
+			alias.VolumeMountPoint = strings.TrimRight(value, "\x00")
+		}
+		pos += size + size&1 // the data is padded to even length
+	}
+	return alias
+}
+
+// parseChan parses "chan" of the sound description
+func parseChan(r *atomReader) *AudioChannelLayout {
+	if r.Size() < 16 {
+		return nil
+	}
+	_ = r.Move(4) // version + flags
+	layout := new(AudioChannelLayout)
+	layout.LayoutTag = r.Read4()
+	layout.ChannelBitmap = r.Read4()
+	descriptionCount := r.Read4()
+	for i := uint32(0); i < descriptionCount && r.Len() >= 20; i++ {
+		var description AudioChannelDescription
+		description.Label = r.Read4()
+		description.Flags = r.Read4()
+		for j := range description.Coordinates {
+			description.Coordinates[j] = math.Float32frombits(r.Read4())
+		}
+		layout.Descriptions = append(layout.Descriptions, description)
+	}
+	return layout
+}
+
+// parseWave parses "wave" (siDecompressionParam) of the sound description. It contains
+// the codec specific atoms, e.g. "esds" of "mp4a" and "alac", and may contain "chan".
+func (p *boxTrak) parseWave(audioEntry *audioSampleEntry, r *atomReader) {
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			return
+		}
+		switch ar.TypeCC() {
+		case fourCCesds:
+			esds := new(EsDescriptor)
+			_ = esds.parseDescriptor(ar)
+			audioEntry.channelCount = esds.ChannelCount
+			audioEntry.sampleRate = esds.SampleRate
+			audioEntry.codec = esds.AudioCodec
+			audioEntry.descriptorsRawData[audioEntry.codec] = esds.DecoderSpecificInfo
+			audioEntry.decoderDescriptors[audioEntry.codec] = esds
+			logD.Printf("parsing moov.trak.mdia.stbl.stsd.audioSampleEntries, sample descriptor: wave/esds channel_count is %d sampleRate is %d", audioEntry.channelCount, audioEntry.sampleRate)
+		case fourCCalac:
+			alac := new(AlacDescriptor)
+			alac.parseDescriptor(ar)
+			audioEntry.channelCount = uint16(alac.NumChannels)
+			audioEntry.sampleRate = alac.SampleRate
+			audioEntry.codec = AudioCodecALAC
+			audioEntry.descriptorsRawData[audioEntry.codec] = alac.DecoderSpecificInfo
+			audioEntry.decoderDescriptors[audioEntry.codec] = alac
+		case fourCCchan:
+			audioEntry.channelLayout = parseChan(ar)
+		}
+	}
+}
+
+// pascalString returns the string of the length-prefixed bytes, the length is limited by b.
+func pascalString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	n := int(b[0])
+	if n > len(b)-1 {
+		n = len(b) - 1
+	}
+	return string(b[1 : 1+n])
+}
+
+// cString returns the null-terminated string at the beginning of b.
+func cString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b)
+}
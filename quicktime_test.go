@@ -0,0 +1,141 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// mkQuickTimeTrak builds a "trak" of the handler type, the boxes are the sub boxes of
+// "trak" before "mdia", minf is the sub boxes of "minf".
+func mkQuickTimeTrak(id uint32, handler string, boxes [][]byte, minf ...[]byte) []byte {
+	return mkQuickTimeTrakOf(id, 1000, handler, boxes, minf...)
+}
+
+func mkQuickTimeTrakOf(id, timeScale uint32, handler string, boxes [][]byte, minf ...[]byte) []byte {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(id), u32(0), u32(1000), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(timeScale), u32(1000), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, []byte("mhlr"), []byte(handler), make([]byte, 12), []byte{0})
+	mdia := mkBox("mdia", mdhd, hdlr, mkBox("minf", minf...))
+	return mkBox("trak", append(append([][]byte{tkhd}, boxes...), mdia)...)
+}
+
+func mkAliasRecord() []byte {
+	b := make([]byte, 150)
+	b[10] = 4
+	copy(b[11:], "Work")
+	b[50] = 8
+	copy(b[51:], "clip.mov")
+	tag := func(tag uint16, value string) []byte {
+		v := []byte(value)
+		if len(v)%2 == 1 {
+			v = append(v, 0)
+		}
+		return append(append(u16(tag), u16(uint16(len(value)))...), v...)
+	}
+	return bytes.Join([][]byte{b, tag(0, "Media"), tag(2, "Work:Media:clip.mov"), tag(18, "/Media/clip.mov\x00"),
+		u16(0xFFFF), u16(0)}, nil)
+}
+
+func TestQuickTime(t *testing.T) {
+	// version 2 sound description of 6 channels of 24-bit little-endian PCM at 48000 Hz
+	chan51 := mkFullBox("chan", 0, 0, u32(121<<16|6), u32(0), u32(0))
+	lpcm := mkBox("lpcm", make([]byte, 6), u16(1), u16(2), u16(0), u32(0),
+		u16(3), u16(16), u16(0xFFFE), u16(0), u32(65536), u32(72), u64(math.Float64bits(48000)),
+		u32(6), u32(0x7F000000), u32(24), u32(0x4|0x8), u32(18), u32(1), chan51)
+	clip := mkBox("clip", mkBox("crgn", u16(12), u16(0), u16(0), u16(1080), u16(1920), u16(0xAB)))
+	description := bytes.Join([][]byte{u32(86), []byte("raw "), make([]byte, 24), u16(1920), u16(1080),
+		make([]byte, 46), u16(8), u16(0xFFFF)}, nil)
+	matt := mkBox("matt", mkFullBox("kmat", 0, 0, description, []byte{1, 2, 3}))
+	dref := mkFullBox("dref", 0, 0, u32(3), mkFullBox("alis", 0, 1), mkFullBox("alis", 0, 0, mkAliasRecord()),
+		mkFullBox("url ", 0, 0, []byte("http://example.com/a.mov\x00")))
+	pcmTrak := mkQuickTimeTrak(1, "soun", [][]byte{clip, matt}, mkBox("dinf", dref),
+		mkBox("stbl", mkFullBox("stsd", 0, 0, u32(1), lpcm)))
+
+	// version 1 sound description of ALAC, "alac" and "chan" are in "wave"
+	alacConfig := mkBox("alac", u32(4096), u8(0), u8(16), u8(40), u8(10), u8(14), u8(2), u16(255), u32(0), u32(0), u32(44100))
+	wave := mkBox("wave", mkBox("frma", []byte("alac")), alacConfig,
+		mkFullBox("chan", 0, 0, u32(ChannelLayoutTagUseChannelBitmap), u32(0x3), u32(0)), mkBox("\x00\x00\x00\x00"))
+	alac := mkBox("alac", make([]byte, 6), u16(1), u16(1), u16(0), u32(0), u16(2), u16(16), u16(0xFFFE), u16(0),
+		u16(44100), u16(0), u32(4096), u32(0), u32(0), u32(0), wave)
+	alacTrak := mkQuickTimeTrak(2, "soun", nil, mkBox("stbl", mkFullBox("stsd", 0, 0, u32(1), alac)))
+
+	gmhd := mkBox("gmhd", mkFullBox("gmin", 0, 0, u16(0x40), u16(0x8000), u16(0x8000), u16(0x8000), u16(0), u16(0)),
+		mkBox("tmcd", mkFullBox("tcmi", 0, 0, u16(0), u16(1), u16(12), u16(0), u16(0xFFFF), u16(0xFFFF), u16(0xFFFF),
+			u16(0), u16(0), u16(0), []byte("\x07Courier"))))
+	tmcdTrak := mkQuickTimeTrak(3, "tmcd", nil, gmhd)
+
+	// the list of the atoms of the sample description ends with a 32-bit zero
+	proresTrak := mkQuickTimeTrak(4, "vide", nil,
+		mkBox("stbl", mkFullBox("stsd", 0, 0, u32(1), mkVideoEntry("apcn", 1920, 1080, u32(0)))))
+
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(1000), u32(1000), make([]byte, 80)),
+		pcmTrak, alacTrak, tmcdTrak, proresTrak)
+	tracks := newTestParser(t, moov).GetTracks()
+	if len(tracks) != 4 {
+		t.Fatalf("got %d tracks, want 4", len(tracks))
+	}
+
+	pcm := tracks[0]
+	if pcm.SampleRate != 48000 || pcm.ChannelCount != 6 || pcm.audioEntry.lpcmCodec != pcmS24LE {
+		t.Errorf("lpcm = %d Hz %d channels %v, want 48000 Hz 6 channels pcmS24LE", pcm.SampleRate, pcm.ChannelCount, pcm.audioEntry.lpcmCodec)
+	}
+	if pcm.ChannelLayout == nil || pcm.ChannelLayout.ChannelCount() != 6 || pcm.SampleEntries[0].ChannelLayout != pcm.ChannelLayout {
+		t.Errorf("ChannelLayout = %+v", pcm.ChannelLayout)
+	}
+	wantQuickTime := &QuickTime{
+		ClipRegion: &QuickTimeRegion{Bounds: QuickTimeRect{Bottom: 1080, Right: 1920}, Data: []byte{0, 0xAB}},
+		Matte: &QuickTimeMatte{Format: "raw ", Width: 1920, Height: 1080, Depth: 8, Description: description,
+			Data: []byte{1, 2, 3}},
+	}
+	if !reflect.DeepEqual(pcm.QuickTime, wantQuickTime) {
+		t.Errorf("QuickTime = %+v, want %+v", pcm.QuickTime, wantQuickTime)
+	}
+	wantRefs := []DataReference{
+		{Type: "alis", SelfContained: true},
+		{Type: "alis", Alias: &AliasRecord{VolumeName: "Work", FileName: "clip.mov", Directory: "Media",
+			Path: "Work:Media:clip.mov", POSIXPath: "/Media/clip.mov"}},
+		{Type: "url ", Location: "http://example.com/a.mov"},
+	}
+	if !reflect.DeepEqual(pcm.DataReferences, wantRefs) {
+		t.Errorf("DataReferences = %+v, want %+v", pcm.DataReferences, wantRefs)
+	}
+
+	alacTrack := tracks[1]
+	if alacTrack.Codec != AudioCodecALAC || alacTrack.SampleRate != 44100 || alacTrack.ChannelCount != 2 {
+		t.Errorf("alac = %v %d Hz %d channels", alacTrack.Codec, alacTrack.SampleRate, alacTrack.ChannelCount)
+	}
+	if alacTrack.ChannelLayout == nil || alacTrack.ChannelLayout.ChannelCount() != 2 {
+		t.Errorf("ChannelLayout of wave = %+v", alacTrack.ChannelLayout)
+	}
+
+	timecode := tracks[2]
+	if timecode.Type != TimecodeTrack || timecode.QuickTime == nil {
+		t.Fatalf("timecode track = %v %+v", timecode.Type, timecode.QuickTime)
+	}
+	wantBase := &BaseMediaInfo{GraphicsMode: 0x40, OpColor: [3]uint16{0x8000, 0x8000, 0x8000}}
+	wantTimecode := &TimecodeInfo{TextFace: 1, TextSize: 12, TextColor: [3]uint16{0xFFFF, 0xFFFF, 0xFFFF}, FontName: "Courier"}
+	if !reflect.DeepEqual(timecode.QuickTime.BaseMediaInfo, wantBase) || !reflect.DeepEqual(timecode.QuickTime.TimecodeInfo, wantTimecode) {
+		t.Errorf("gmhd = %+v %+v", timecode.QuickTime.BaseMediaInfo, timecode.QuickTime.TimecodeInfo)
+	}
+
+	prores := tracks[3]
+	if prores.Codec != VideoCodecProRes || prores.Width != 1920 || prores.CodecString() != "apcn" {
+		t.Errorf("prores = %v %d %s", prores.Codec, prores.Width, prores.CodecString())
+	}
+}
+
+func TestQuickTime_ShortClipRegion(t *testing.T) {
+	// the region size of "crgn" is less than the size of itself and the bounds
+	clip := mkBox("clip", mkBox("crgn", u16(0), u16(0), u16(0), u16(1080), u16(1920)))
+	trak := mkQuickTimeTrak(1, "vide", [][]byte{clip})
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(1000), u32(1000), make([]byte, 80)), trak)
+	tracks := newTestParser(t, moov).GetTracks()
+	if len(tracks) != 1 {
+		t.Fatalf("got %d tracks, want 1", len(tracks))
+	}
+	if tracks[0].QuickTime != nil && tracks[0].QuickTime.ClipRegion != nil {
+		t.Errorf("ClipRegion = %+v, want nil", tracks[0].QuickTime.ClipRegion)
+	}
+}
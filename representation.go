@@ -0,0 +1,209 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/garden4hu/fmp4parser-go/manifest"
+)
+
+// Representations converts the parsed tracks into the representations of the
+// manifest package, one representation for each track. uri is the location of
+// the parsed file which is used as the BaseURL of DASH and the URI of HLS.
+// It must be called after Parse.
+// The segments come from the "sidx" of the track if exists. Otherwise, they come
+// from the movie fragments: the decode time from "tfdt" and the duration from "trun".
+func (p *Parser) Representations(uri string) ([]*manifest.Representation, error) {
+	if p.m.movie == nil || len(p.m.movie.trak) == 0 {
+		return nil, ErrNotFoundTrack
+	}
+	var reps []*manifest.Representation
+	for _, trak := range p.m.movie.trak {
+		rep := &manifest.Representation{
+			ID:        strconv.FormatUint(uint64(trak.id), 10),
+			Codecs:    rfc6381CodecString(trak.format, trak.audioEntry, trak.videoEntry),
+			Language:  trak.languageCode(),
+			URI:       uri,
+			TimeScale: trak.timeScale,
+		}
+		switch trak.trackType {
+		case VideoTrack:
+			rep.ContentType, rep.MimeType = "video", "video/mp4"
+			if trak.videoEntry != nil {
+				rep.Width, rep.Height = uint32(trak.videoEntry.width), uint32(trak.videoEntry.height)
+			}
+		case AudioTrack:
+			rep.ContentType, rep.MimeType = "audio", "audio/mp4"
+			if trak.audioEntry != nil {
+				rep.SampleRate, rep.Channels = trak.audioEntry.sampleRate, trak.audioEntry.channelCount
+			}
+		case SubtitleTrack:
+			rep.ContentType, rep.MimeType = "text", "application/mp4"
+		default:
+			continue
+		}
+		if p.m.initEnd > 0 {
+			rep.Init = &manifest.ByteRange{Offset: 0, Size: uint64(p.m.initEnd)}
+		}
+		if index := p.segmentIndexOf(trak.id); index != nil {
+			rep.Index = &manifest.ByteRange{Offset: index.Offset, Size: index.Size}
+			if index.TimeScale != 0 {
+				rep.TimeScale = index.TimeScale
+			}
+			for _, ref := range index.Subsegments() {
+				rep.Segments = append(rep.Segments, manifest.Segment{
+					Range:    &manifest.ByteRange{Offset: ref.Offset, Size: uint64(ref.Size)},
+					Time:     ref.StartTime,
+					Duration: uint64(ref.Duration),
+				})
+			}
+		} else {
+			rep.Segments = p.fragmentSegments(trak.id)
+		}
+		if rep.ContentType == "video" {
+			rep.FrameRate = p.frameRate(trak)
+		}
+		rep.Protection = trak.manifestProtection(p.m.movie.pssh)
+		reps = append(reps, rep)
+	}
+	return reps, nil
+}
+
+// segmentIndexOf returns the top level segment index of the track. If there is only
+// one top level "sidx", it's used for all the tracks. nil if not found.
+func (p *Parser) segmentIndexOf(trackID uint32) *SegmentIndex {
+	roots := rootSegmentIndexes(p.m.movie.sidx)
+	for _, sidx := range roots {
+		if sidx.referenceID == trackID {
+			return sidx.resolve(p.m.movie.sidx, 0)
+		}
+	}
+	if len(roots) == 1 && len(p.m.movie.trak) == 1 {
+		return roots[0].resolve(p.m.movie.sidx, 0)
+	}
+	return nil
+}
+
+// fragmentSegments returns the segments of the track from the movie fragments. The
+// segment starts from the "styp" or "moof" and ends at the start of the next segment.
+func (p *Parser) fragmentSegments(trackID uint32) []manifest.Segment {
+	var segments []manifest.Segment
+	decodeTime := uint64(0)
+	for i, moof := range p.m.fragments {
+		traf := moof.trackFragment(trackID)
+		if traf == nil {
+			continue
+		}
+		end := p.m.fileSize
+		if i+1 < len(p.m.fragments) {
+			end = p.m.fragments[i+1].segmentOffset
+		}
+		if traf.baseMediaDecodeTime != nil {
+			decodeTime = *traf.baseMediaDecodeTime
+		}
+		duration := traf.duration()
+		segment := manifest.Segment{Time: decodeTime, Duration: duration}
+		if end > moof.segmentOffset {
+			segment.Range = &manifest.ByteRange{Offset: uint64(moof.segmentOffset), Size: uint64(end - moof.segmentOffset)}
+		}
+		segments = append(segments, segment)
+		decodeTime += duration
+	}
+	return segments
+}
+
+// frameRate returns the average frame rate of the track, from the sample table or the fragments.
+// The frame rate of the VUI of SPS is used if there is no sample.
+func (p *Parser) frameRate(trak *boxTrak) float64 {
+	var samples, duration uint64
+	if trak.stts != nil {
+		for i := range trak.stts.sampleCount {
+			samples += uint64(trak.stts.sampleCount[i])
+			duration += uint64(trak.stts.sampleCount[i]) * uint64(trak.stts.sampleDelta[i])
+		}
+	}
+	if samples == 0 {
+		for _, moof := range p.m.fragments {
+			if traf := moof.trackFragment(trak.id); traf != nil {
+				samples += traf.sampleCount()
+				duration += traf.duration()
+			}
+		}
+	}
+	if samples == 0 || duration == 0 || trak.timeScale == 0 {
+		if sps := trak.videoEntry.sequenceParameterSet(); sps != nil {
+			return sps.FrameRate
+		}
+		return 0
+	}
+	return float64(trak.timeScale) * float64(samples) / float64(duration)
+}
+
+// languageCode returns the ISO-639-2/T language code of the track, "" if undetermined.
+// The extended language tag of "elng" is preferred.
+func (p *boxTrak) languageCode() string {
+	if p.extLanguage != "" {
+		return p.extLanguage
+	}
+	if p.language == 0 {
+		return ""
+	}
+	// each character is packed as the difference between its ASCII value and 0x60
+	code := string([]byte{
+		byte(p.language>>10&0x1F) + 0x60,
+		byte(p.language>>5&0x1F) + 0x60,
+		byte(p.language&0x1F) + 0x60,
+	})
+	if code == "und" {
+		return ""
+	}
+	return code
+}
+
+// manifestProtection converts the "tenc" of the track and the "pssh" of the movie.
+func (p *boxTrak) manifestProtection(psshs []*PSSH) *manifest.Protection {
+	if len(p.protection) == 0 || p.protection[0] == nil {
+		return nil
+	}
+	info := p.protection[0]
+	protection := &manifest.Protection{
+		Scheme:     int2String(info.SchemeType),
+		DefaultKID: info.DefaultKID,
+	}
+	for _, pssh := range psshs {
+		protection.Systems = append(protection.Systems, manifest.ProtectionSystem{
+			SystemID: pssh.SystemId,
+			PSSH:     pssh.box(),
+		})
+	}
+	return protection
+}
+
+// box serializes the PSSH into a full "pssh" box. The version is 1 if there are KIDs.
+func (p *PSSH) box() []byte {
+	version := uint32(0)
+	size := 8 + 4 + 16 + 4 + len(p.Data)
+	if len(p.KId) > 0 {
+		version = 1
+		size += 4 + 16*len(p.KId)
+	}
+	b := make([]byte, 12, size)
+	binary.BigEndian.PutUint32(b[0:], uint32(size))
+	binary.BigEndian.PutUint32(b[4:], fourCCpssh)
+	binary.BigEndian.PutUint32(b[8:], version<<24)
+	b = append(b, p.SystemId...)
+	if version == 1 {
+		b = append(b, uint32Bytes(uint32(len(p.KId)))...)
+		for _, kid := range p.KId {
+			b = append(b, kid[:]...)
+		}
+	}
+	b = append(b, uint32Bytes(uint32(len(p.Data)))...)
+	return append(b, p.Data...)
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
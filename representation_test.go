@@ -0,0 +1,78 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/garden4hu/fmp4parser-go/manifest"
+)
+
+// mkFragmentedFile builds a fragmented file of one video track with two fragments,
+// the second one is started by "styp".
+func mkFragmentedFile() (file []byte, initSize int, fragmentSize int) {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(4000), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(4000), u16(0x15C7), u16(0)) // "eng"
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr))
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(1), u32(40), u32(0), u32(0))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak, mkBox("mvex", trex))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+
+	fragment := func(decodeTime uint32) []byte {
+		tfhd := mkFullBox("tfhd", 0, 0x020000, u32(1))
+		tfdt := mkFullBox("tfdt", 0, 0, u32(decodeTime))
+		trun := mkFullBox("trun", 0, 0x000200, u32(2), u32(100), u32(100))
+		moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), mkBox("traf", tfhd, tfdt, trun))
+		return append(moof, mkBox("mdat", make([]byte, 200))...)
+	}
+	styp := mkBox("styp", []byte("msdh"), u32(0), []byte("msdh"))
+	init := append(ftyp, moov...)
+	first := fragment(0)
+	second := append(styp, fragment(80)...)
+	file = append(append(append([]byte{}, init...), first...), second...)
+	return file, len(init), len(first)
+}
+
+func TestParser_Representations(t *testing.T) {
+	file, initSize, fragmentSize := mkFragmentedFile()
+	p := newTestParser(t, file)
+	reps, err := p.Representations("video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 {
+		t.Fatalf("got %d representations, want 1", len(reps))
+	}
+	rep := reps[0]
+	if rep.ContentType != "video" || rep.Language != "eng" || rep.TimeScale != 1000 || rep.FrameRate != 25 {
+		t.Errorf("unexpected representation %+v", rep)
+	}
+	if rep.Init == nil || *rep.Init != (manifest.ByteRange{Offset: 0, Size: uint64(initSize)}) {
+		t.Errorf("init range = %v, want [0, %d)", rep.Init, initSize)
+	}
+	want := []manifest.Segment{
+		{Range: &manifest.ByteRange{Offset: uint64(initSize), Size: uint64(fragmentSize)}, Time: 0, Duration: 80},
+		{Range: &manifest.ByteRange{Offset: uint64(initSize + fragmentSize), Size: uint64(len(file) - initSize - fragmentSize)}, Time: 80, Duration: 80},
+	}
+	if len(rep.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(rep.Segments), len(want))
+	}
+	for i := range want {
+		got := rep.Segments[i]
+		if got.Time != want[i].Time || got.Duration != want[i].Duration || got.Range == nil || *got.Range != *want[i].Range {
+			t.Errorf("segment %d = %+v %v, want %+v %v", i, got, got.Range, want[i], want[i].Range)
+		}
+	}
+	var b bytes.Buffer
+	if err := manifest.WriteMediaPlaylist(&b, rep); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPSSH_box(t *testing.T) {
+	pssh := &PSSH{SystemId: bytes.Repeat([]byte{1}, 16), KId: [][16]byte{{2}}, Data: []byte{3, 4}}
+	want := mkFullBox("pssh", 1, 0, bytes.Repeat([]byte{1}, 16), u32(1), append([]byte{2}, make([]byte, 15)...), u32(2), []byte{3, 4})
+	if got := pssh.box(); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package fmp4parser
+
+// SampleEntry is a sample description of a track, i.e. an entry of "stsd".
+// A track may have more than one sample description, e.g. the codec or the
+// resolution switches in the middle of the stream. Packet.DescriptorIndex
+// refers to SampleEntry.Index.
+type SampleEntry struct {
+	Index      int       // 1-based index in "stsd"
+	Format     string    // fourCC of the sample entry, e.g. "avc1", "encv"
+	CodingName string    // the original format. It's different from Format if the entry is encrypted
+	Codec      CodecType // CodecUNKNOW if the entry isn't supported yet
+
+	// for audio
+	ChannelCount uint16
+	SampleSize   uint16
+	SampleRate   uint32
+
+	ChannelLayout *AudioChannelLayout // "chan" of QuickTime, nil if not present
+
+	// for video
+	Width  uint16
+	Height uint16
+
+	SequenceParameterSet *SequenceParameterSet // the first SPS of H.264/HEVC
+	HDRInfo              *HDRInfo              // nil if the video is SDR
+
+	ExtraRawData         map[CodecType][]byte  // audio descriptor OR video codec configuration record
+	EncryptedInformation *ProtectedInformation // nil if the entry isn't encrypted
+
+	codingName uint32
+	audioEntry *audioSampleEntry
+	videoEntry *videoSampleEntry
+}
+
+// CodecString returns the RFC 6381 codecs parameter of the sample description.
+func (p *SampleEntry) CodecString() string {
+	return rfc6381CodecString(p.codingName, p.audioEntry, p.videoEntry)
+}
+
+func newSampleEntry(index int, entry *sampleEntry) SampleEntry {
+	e := SampleEntry{
+		Index:      index,
+		Format:     int2String(entry.format),
+		codingName: entry.format,
+		audioEntry: entry.audio,
+		videoEntry: entry.video,
+	}
+	if entry.audio != nil {
+		e.codingName = entry.audio.originalFormat
+		e.Codec = entry.audio.codec
+		e.ChannelCount = entry.audio.channelCount
+		e.SampleSize = entry.audio.sampleSize
+		e.SampleRate = entry.audio.sampleRate
+		e.ChannelLayout = entry.audio.channelLayout
+		e.ExtraRawData = entry.audio.descriptorsRawData
+		e.EncryptedInformation = entry.audio.protectedInfo
+	}
+	if entry.video != nil {
+		e.codingName = entry.video.originalFormat
+		e.Codec = entry.video.codec
+		e.Width = entry.video.width
+		e.Height = entry.video.height
+		e.SequenceParameterSet = entry.video.sequenceParameterSet()
+		e.HDRInfo = entry.video.hdrInfo()
+		e.ExtraRawData = entry.video.configurationRecordsRawData
+		e.EncryptedInformation = entry.video.protectedInfo
+	}
+	e.CodingName = int2String(e.codingName)
+	return e
+}
+
+// SampleEntry returns the sample description of the index (Packet.DescriptorIndex). nil if not found.
+func (p *Track) SampleEntry(index int) *SampleEntry {
+	if index < 1 || index > len(p.SampleEntries) {
+		return nil
+	}
+	return &p.SampleEntries[index-1]
+}
+
+// sampleDescriptionIndexOf returns the sample description index of the samples in the
+// track fragment: "tfhd" first, then "trex". The default value is 1.
+func (p *trackFragment) sampleDescriptionIndexOf() uint32 {
+	if p.sampleDescriptionIndex != nil {
+		return *p.sampleDescriptionIndex
+	}
+	if trex := p.trex(); trex != nil && trex.defaultSampleDescriptionIndex != 0 {
+		return trex.defaultSampleDescriptionIndex
+	}
+	return 1
+}
+
+// FragmentDescriptorIndexes returns the sample description index of each movie fragment
+// of the track in file order, i.e. the SampleEntry used by the samples of the fragment.
+// It must be called after Parse.
+func (p *Parser) FragmentDescriptorIndexes(trackID uint32) []int {
+	var indexes []int
+	for _, moof := range p.m.fragments {
+		if traf := moof.trackFragment(trackID); traf != nil {
+			indexes = append(indexes, int(traf.sampleDescriptionIndexOf()))
+		}
+	}
+	return indexes
+}
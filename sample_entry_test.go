@@ -0,0 +1,146 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// mkVideoEntry builds a VisualSampleEntry with the child boxes.
+func mkVideoEntry(format string, width, height uint16, boxes ...[]byte) []byte {
+	payloads := [][]byte{make([]byte, 6), u16(1), make([]byte, 16), u16(width), u16(height),
+		make([]byte, 46), u16(0x18), u16(0xFFFF)}
+	return mkBox(format, append(payloads, boxes...)...)
+}
+
+func mkAvc1(width, height uint16, level uint8) []byte {
+	return mkVideoEntry("avc1", width, height, mkBox("avcC", []byte{1, 0x64, 0, level, 0xFF, 0xE0, 0}))
+}
+
+// mkVideoInit builds "ftyp" and "moov" of a fragmented file with one video track of the sample entries.
+func mkVideoInit(entries ...[]byte) []byte {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(4000), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(4000), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+	stsd := mkFullBox("stsd", 0, 0, append([][]byte{u32(uint32(len(entries)))}, entries...)...)
+	minf := mkBox("minf", mkBox("stbl", stsd))
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, minf))
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(2), u32(40), u32(0), u32(0))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak, mkBox("mvex", trex))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	return append(ftyp, moov...)
+}
+
+// mkAudioEntry builds an AudioSampleEntry of ISO/IEC 14496-12 with the boxes.
+func mkAudioEntry(format string, channelCount uint16, sampleRate uint16, boxes ...[]byte) []byte {
+	payloads := [][]byte{make([]byte, 6), u16(1), make([]byte, 8), u16(channelCount), u16(16),
+		make([]byte, 4), u16(sampleRate), u16(0)}
+	return mkBox(format, append(payloads, boxes...)...)
+}
+
+// mkAudioInit builds "ftyp" and "moov" of a file with one audio track of the sample entries.
+func mkAudioInit(entries ...[]byte) []byte {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(4096), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(48000), u32(4096), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("soun"), make([]byte, 12), []byte("audio\x00"))
+	stsd := mkFullBox("stsd", 0, 0, append([][]byte{u32(uint32(len(entries)))}, entries...)...)
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, mkBox("minf", mkBox("stbl", stsd))))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(48000), u32(4096), make([]byte, 80)), trak)
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	return append(ftyp, moov...)
+}
+
+func TestTrack_MpegH(t *testing.T) {
+	mhaC := mkBox("mhaC", []byte{1, 0x0D, 6}, u16(3), []byte{0xAA, 0xBB, 0xCC})
+	mhaP := mkBox("mhaP", []byte{2, 0x0B, 0x0C})
+	p := newTestParser(t, mkAudioInit(mkAudioEntry("mha1", 0, 48000, mhaC, mhaP), mkAudioEntry("mhm1", 0, 48000)))
+	track := p.GetTracks()[0]
+	if track.Type != AudioTrack || track.Codec != AudioCodecMPEGH || track.ChannelCount != 6 {
+		t.Fatalf("track = %v %v %d channels, want MPEG-H audio of 6 channels", track.Type, track.Codec, track.ChannelCount)
+	}
+	mha, ok := track.audioEntry.decoderDescriptors[AudioCodecMPEGH].(*MhaDescriptor)
+	if !ok {
+		t.Fatal("no MhaDescriptor")
+	}
+	if mha.ProfileLevelIndication != 0x0D || mha.ReferenceChannelLayout != 6 ||
+		!bytes.Equal(mha.Mpegh3daConfig, []byte{0xAA, 0xBB, 0xCC}) || !bytes.Equal(mha.CompatibleSets, []byte{0x0B, 0x0C}) {
+		t.Errorf("MhaDescriptor = %+v", mha)
+	}
+	if got := track.CodecString(); got != "mha1.0x0D" {
+		t.Errorf("CodecString() = %v, want mha1.0x0D", got)
+	}
+	// the configuration of "mhm1" may be in the MHAS packets only
+	if e := track.SampleEntry(2); e.Codec != AudioCodecMPEGH || e.CodecString() != "mhm1" {
+		t.Errorf("sample entry 2 = %v %s", e.Codec, e.CodecString())
+	}
+}
+
+func TestTrack_DtsUhd(t *testing.T) {
+	tag := []byte("0123456789abcdef")
+	udts := (&bitWriter{}).write(0, 6).write(1, 2).write(2, 3).write(1, 5).write(0x0000801F, 32).
+		write(1, 1).write(1, 2).write(0, 3).write(0, 3).write(0, 1).write(0, 1).write(1, 1).align()
+	for _, b := range tag {
+		udts.write(uint64(b), 8)
+	}
+	p := newTestParser(t, mkAudioInit(mkAudioEntry("dtsx", 2, 48000, mkBox("udts", udts.b))))
+	track := p.GetTracks()[0]
+	if track.Codec != AudioCodecDTSUHD || track.ChannelCount != 6 || track.SampleRate != 96000 || track.CodecString() != "dtsx" {
+		t.Fatalf("track = %v %d channels %d Hz %s", track.Codec, track.ChannelCount, track.SampleRate, track.CodecString())
+	}
+	want := &DtsUhdDescriptor{DecoderProfile: 2, FrameDuration: 1024, MaxPayload: 8192, NumPresentations: 2,
+		ChannelMask: 0x0000801F, BaseSamplingFrequency: 48000, SampleRate: 96000, PresentationIDTags: [][]byte{nil, tag},
+		DecoderSpecificInfo: udts.b}
+	if got := track.audioEntry.decoderDescriptors[AudioCodecDTSUHD]; !reflect.DeepEqual(got, want) {
+		t.Errorf("DtsUhdDescriptor = %+v, want %+v", got, want)
+	}
+
+	// a truncated "udts" keeps the channel count and the sample rate of the sample entry
+	track = newTestParser(t, mkAudioInit(mkAudioEntry("dtsx", 2, 48000, mkBox("udts", udts.b[:4])))).GetTracks()[0]
+	if track.Codec != AudioCodecDTSUHD || track.ChannelCount != 2 || track.SampleRate != 48000 {
+		t.Errorf("truncated udts: track = %v %d channels %d Hz", track.Codec, track.ChannelCount, track.SampleRate)
+	}
+}
+
+func TestTrack_SampleEntries(t *testing.T) {
+	fragment := func(flags uint32, payloads ...[]byte) []byte {
+		tfhd := mkFullBox("tfhd", 0, flags, append([][]byte{u32(1)}, payloads...)...)
+		trun := mkFullBox("trun", 0, 0x000200, u32(1), u32(100))
+		moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), mkBox("traf", tfhd, trun))
+		return append(moof, mkBox("mdat", make([]byte, 100))...)
+	}
+	file := mkVideoInit(mkAvc1(1280, 720, 0x1f), mkAvc1(1920, 1080, 0x28))
+	file = append(file, fragment(0x020002, u32(1))...) // sample_description_index of tfhd
+	file = append(file, fragment(0x020000)...)         // default_sample_description_index of trex
+
+	p := newTestParser(t, file)
+	tracks := p.GetTracks()
+	if len(tracks) != 1 {
+		t.Fatalf("got %d tracks, want 1", len(tracks))
+	}
+	track := tracks[0]
+	if len(track.SampleEntries) != 2 {
+		t.Fatalf("got %d sample entries, want 2", len(track.SampleEntries))
+	}
+	for i, want := range []struct {
+		width, height uint16
+		codecs        string
+	}{{1280, 720, "avc1.64001f"}, {1920, 1080, "avc1.640028"}} {
+		e := track.SampleEntry(i + 1)
+		if e == nil || e.Index != i+1 || e.Format != "avc1" || e.Codec != VideoCodecH264 {
+			t.Fatalf("sample entry %d = %+v", i+1, e)
+		}
+		if e.Width != want.width || e.Height != want.height || e.CodecString() != want.codecs {
+			t.Errorf("sample entry %d = %dx%d %s, want %dx%d %s", i+1, e.Width, e.Height, e.CodecString(), want.width, want.height, want.codecs)
+		}
+	}
+	if track.Width != 1280 || track.CodecString() != "avc1.64001f" {
+		t.Errorf("track = %d %s, want the first sample entry", track.Width, track.CodecString())
+	}
+	if track.SampleEntry(0) != nil || track.SampleEntry(3) != nil {
+		t.Error("out of range sample entry should be nil")
+	}
+	indexes := p.FragmentDescriptorIndexes(1)
+	if len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 2 {
+		t.Errorf("fragment descriptor indexes = %v, want [1 2]", indexes)
+	}
+}
@@ -0,0 +1,94 @@
+package fmp4parser
+
+import "sort"
+
+// SampleFlags is the dependency and the sync information of a sample, refer to ISO/IEC 14496-12 8.8.3.1.
+// It's from "sdtp", "stss" and "stdp" of a progressive file, or from the sample flags of
+// "trun", "tfhd" and "trex" of a fragmented file.
+type SampleFlags struct {
+	IsLeading           uint8 // 0: unknown, 1: leading sample with a dependency before the referenced I-picture, 2: not a leading sample, 3: leading sample without such a dependency
+	DependsOn           uint8 // 0: unknown, 1: depends on others (not an I-picture), 2: doesn't depend on others (I-picture)
+	IsDependedOn        uint8 // 0: unknown, 1: others may depend on it, 2: no other depends on it (disposable)
+	HasRedundancy       uint8 // 0: unknown, 1: there is redundant coding, 2: there is no redundant coding
+	PaddingValue        uint8
+	IsNonSync           bool // of "av01" samples without "stss" or sample flags, it's detected by Parser.ReadPacket
+	DegradationPriority uint16
+}
+
+// IsDisposable returns whether no other sample depends on the sample, i.e. it can be dropped
+// without affecting the decoding of the others.
+func (p SampleFlags) IsDisposable() bool {
+	return p.IsDependedOn == 2
+}
+
+// newSampleFlags decodes the sample flags of "trun", "tfhd" and "trex":
+//
+//	bit(4) reserved
+//	unsigned int(2) is_leading
+//	unsigned int(2) sample_depends_on
+//	unsigned int(2) sample_is_depended_on
+//	unsigned int(2) sample_has_redundancy
+//	bit(3) sample_padding_value
+//	bit(1) sample_is_non_sync_sample
+//	unsigned int(16) sample_degradation_priority
+func newSampleFlags(flags uint32) SampleFlags {
+	return SampleFlags{
+		IsLeading:           uint8(flags >> 26 & 0x03),
+		DependsOn:           uint8(flags >> 24 & 0x03),
+		IsDependedOn:        uint8(flags >> 22 & 0x03),
+		HasRedundancy:       uint8(flags >> 20 & 0x03),
+		PaddingValue:        uint8(flags >> 17 & 0x07),
+		IsNonSync:           flags&0x10000 != 0,
+		DegradationPriority: uint16(flags),
+	}
+}
+
+// sampleFlagsOf returns the flags of the 0-based sample in "stbl". All the samples are sync
+// samples if there is no "stss".
+func (p *boxTrak) sampleFlagsOf(sample int) SampleFlags {
+	var flags SampleFlags
+	if sdtp := p.sampleDependency; sdtp != nil && sample < len(sdtp.isLeading) {
+		flags.IsLeading = sdtp.isLeading[sample]
+		flags.DependsOn = sdtp.sampleDependsOn[sample]
+		flags.IsDependedOn = sdtp.sampleIsDependedOn[sample]
+		flags.HasRedundancy = sdtp.sampleHasRedundancy[sample]
+	}
+	if p.stss != nil {
+		number := uint32(sample + 1)
+		i := sort.Search(len(p.syncSamples), func(i int) bool { return p.syncSamples[i] >= number })
+		flags.IsNonSync = i == len(p.syncSamples) || p.syncSamples[i] != number
+	}
+	if sample < len(p.samplePriority) {
+		flags.DegradationPriority = p.samplePriority[sample]
+	}
+	return flags
+}
+
+// hasSampleFlags returns whether the i-th sample of the "trun" has sample flags other than
+// the zero default of "trex".
+func (p *trackFragment) hasSampleFlags(trun *boxTrun, i int) bool {
+	if (i < len(trun.samples) && trun.samples[i].sampleFlags != nil) || (i == 0 && trun.firstSampleFlags != nil) ||
+		p.defaultSampleFlags != nil {
+		return true
+	}
+	trex := p.trex()
+	return trex != nil && trex.defaultSampleFlags != 0
+}
+
+// sampleFlagsOf returns the raw flags of the i-th sample of the "trun": the flags of the sample
+// first, then first_sample_flags of the "trun" for the first sample, then "tfhd" and "trex".
+func (p *trackFragment) sampleFlagsOf(trun *boxTrun, i int) uint32 {
+	if i < len(trun.samples) && trun.samples[i].sampleFlags != nil {
+		return *trun.samples[i].sampleFlags
+	}
+	if i == 0 && trun.firstSampleFlags != nil {
+		return *trun.firstSampleFlags
+	}
+	if p.defaultSampleFlags != nil {
+		return *p.defaultSampleFlags
+	}
+	if trex := p.trex(); trex != nil {
+		return trex.defaultSampleFlags
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package fmp4parser
+
+import "testing"
+
+func TestNewSampleFlags(t *testing.T) {
+	flags := newSampleFlags(0x0A810005) // not leading, I-picture, disposable, non-sync, priority 5
+	want := SampleFlags{IsLeading: 2, DependsOn: 2, IsDependedOn: 2, IsNonSync: true, DegradationPriority: 5}
+	if flags != want {
+		t.Errorf("newSampleFlags() = %+v, want %+v", flags, want)
+	}
+	if !flags.IsDisposable() {
+		t.Errorf("IsDisposable() = false, want true")
+	}
+}
+
+func TestParser_PacketSampleFlags(t *testing.T) {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(0), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+	stbl := mkBox("stbl",
+		mkFullBox("stsd", 0, 0, u32(0)),
+		mkFullBox("stts", 0, 0, u32(1), u32(3), u32(40)),
+		mkFullBox("stss", 0, 0, u32(1), u32(1)),
+		mkFullBox("sdtp", 0, 0, u8(0x24), u8(0x18), u8(0x18)),
+		mkFullBox("stdp", 0, 0, u16(0), u16(1), u16(2)))
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl)))
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(1), u32(40), u32(0), u32(0x01010000))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak, mkBox("mvex", trex))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	traf := mkBox("traf",
+		mkFullBox("tfhd", 0, 0x020000, u32(1)),
+		mkFullBox("trun", 0, 0x000204, u32(2), u32(0x02000000), u32(100), u32(100)))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf)
+	file := append(append(append(ftyp, moov...), moof...), mkBox("mdat", make([]byte, 200))...)
+
+	p := newTestParser(t, file)
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SampleFlags{
+		{DependsOn: 2, IsDependedOn: 1},
+		{DependsOn: 1, IsDependedOn: 2, IsNonSync: true, DegradationPriority: 1},
+		{DependsOn: 1, IsDependedOn: 2, IsNonSync: true, DegradationPriority: 2},
+		{DependsOn: 2},                  // first_sample_flags of "trun"
+		{DependsOn: 1, IsNonSync: true}, // default_sample_flags of "trex"
+	}
+	if len(packets) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(packets), len(want))
+	}
+	for i := range want {
+		if packets[i].SampleFlags != want[i] {
+			t.Errorf("packet %d: SampleFlags = %+v, want %+v", i, packets[i].SampleFlags, want[i])
+		}
+	}
+}
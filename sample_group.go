@@ -0,0 +1,424 @@
+package fmp4parser
+
+/*
+Sample groups, refer to ISO/IEC 14496-12 10 and ISO/IEC 14496-15 9.
+
+"sbgp" maps the samples to the entries of "sgpd" of the same grouping type. A track and a
+track fragment may have several "sbgp" and "sgpd" of different grouping types. In a track
+fragment, the group_description_index greater than 0x10000 refers to the entry of the "sgpd"
+in the fragment, the others refer to the entries of "sgpd" in the track.
+*/
+
+// fourCC of the grouping types
+var (
+	fourCCseig uint32 = 0x73656967 // "seig"
+	fourCCrap  uint32 = 0x72617020 // "rap "
+	fourCCsync uint32 = 0x73796e63 // "sync"
+	fourCCtele uint32 = 0x74656c65 // "tele"
+	fourCCalst uint32 = 0x616c7374 // "alst"
+	fourCCsap  uint32 = 0x73617020 // "sap "
+	fourCCtscl uint32 = 0x7473636c // "tscl"
+	fourCCoinf uint32 = 0x6f696e66 // "oinf"
+	fourCClinf uint32 = 0x6c696e66 // "linf"
+)
+
+// RollRecoveryEntry is VisualRollRecoveryEntry / AudioRollRecoveryEntry of "roll", or
+// AudioPreRollEntry of "prol".
+type RollRecoveryEntry struct {
+	RollDistance int16
+}
+
+// RandomAccessPointEntry is VisualRandomAccessEntry of "rap ".
+type RandomAccessPointEntry struct {
+	NumLeadingSamplesKnown bool
+	NumLeadingSamples      uint8
+}
+
+// SyncSampleEntry is SyncSampleEntry of "sync", the NAL unit type of the sync sample.
+type SyncSampleEntry struct {
+	NalUnitType uint8
+}
+
+// TemporalLevelEntry is TemporalLevelEntry of "tele".
+type TemporalLevelEntry struct {
+	LevelIndependentlyDecodable bool
+}
+
+// AlternativeStartupEntry is VisualAlternativeStartupEntry of "alst".
+type AlternativeStartupEntry struct {
+	RollCount         uint16
+	FirstOutputSample uint16
+	SampleOffsets     []uint32 // len(SampleOffsets) == RollCount
+	NumOutputSamples  []uint16
+	NumTotalSamples   []uint16 // len(NumTotalSamples) == len(NumOutputSamples)
+}
+
+// SAPEntry is SAPEntry of "sap ", the stream access point type of the samples.
+type SAPEntry struct {
+	DependentFlag bool
+	SAPType       uint8
+}
+
+// TemporalSubLayerEntry is TemporalSubLayerEntry of "tscl" of HEVC.
+type TemporalSubLayerEntry struct {
+	TemporalLayerID           uint8
+	ProfileSpace              uint8
+	TierFlag                  bool
+	ProfileIdc                uint8
+	ProfileCompatibilityFlags uint32
+	ConstraintIndicatorFlags  uint64 // 48 bits
+	LevelIdc                  uint8
+	MaxBitRate                uint16
+	AvgBitRate                uint16
+	ConstantFrameRate         uint8
+	AvgFrameRate              uint16 // in frames/(256 seconds)
+}
+
+// OperatingPointsEntry is OperatingPointsInformation of "oinf" of layered HEVC.
+type OperatingPointsEntry struct {
+	ScalabilityMask   uint16
+	ProfileTierLevels []OperatingPointProfileTierLevel
+	OperatingPoints   []OperatingPoint
+	Layers            []OperatingPointLayer
+}
+
+// OperatingPointProfileTierLevel is the profile, tier and level of "oinf".
+type OperatingPointProfileTierLevel struct {
+	GeneralProfileSpace              uint8
+	GeneralTierFlag                  bool
+	GeneralProfileIdc                uint8
+	GeneralProfileCompatibilityFlags uint32
+	GeneralConstraintIndicatorFlags  uint64 // 48 bits
+	GeneralLevelIdc                  uint8
+}
+
+// OperatingPoint is an operating point of "oinf".
+type OperatingPoint struct {
+	OutputLayerSetIdx uint16
+	MaxTemporalID     uint8
+	Layers            []OperatingPointLayerRef
+	MinPicWidth       uint16
+	MinPicHeight      uint16
+	MaxPicWidth       uint16
+	MaxPicHeight      uint16
+	MaxChromaFormat   uint8
+	MaxBitDepth       uint8
+	AvgFrameRate      uint16 // present if FrameRateInfo
+	ConstantFrameRate uint8
+	MaxBitRate        uint32 // present if BitRateInfo
+	AvgBitRate        uint32
+	FrameRateInfo     bool
+	BitRateInfo       bool
+}
+
+// OperatingPointLayerRef is a layer of an operating point of "oinf".
+type OperatingPointLayerRef struct {
+	PtlIdx                 uint8
+	LayerID                uint8
+	IsOutputLayer          bool
+	IsAlternateOutputLayer bool
+}
+
+// OperatingPointLayer is the dependency of a layer of "oinf".
+type OperatingPointLayer struct {
+	LayerID             uint8
+	DirectRefLayerIDs   []uint8
+	DimensionIdentifier []uint8 // one for each bit set in ScalabilityMask from the least significant bit
+}
+
+// LayerInformationEntry is LayerInfoGroupEntry of "linf" of layered HEVC.
+type LayerInformationEntry struct {
+	Layers []LayerInformation
+}
+
+// LayerInformation is a layer of "linf".
+type LayerInformation struct {
+	LayerID               uint8
+	MinTemporalID         uint8
+	MaxTemporalID         uint8
+	SubLayerPresenceFlags uint8
+}
+
+// sampleGroupEntryParsers is the registry of the decoders of the sample group entries by the
+// grouping type. r is limited to the entry if the length of the entry is known. The entries
+// of the grouping types not in the registry are kept as raw bytes.
+var sampleGroupEntryParsers = map[uint32]func(r *atomReader) (interface{}, error){
+	fourCCseig: parseSeigEntry,
+	fourCCroll: parseRollEntry,
+	fourCCprol: parseRollEntry,
+	fourCCrap:  parseRapEntry,
+	fourCCsync: parseSyncEntry,
+	fourCCtele: parseTeleEntry,
+	fourCCalst: parseAlstEntry,
+	fourCCsap:  parseSapEntry,
+	fourCCtscl: parseTsclEntry,
+	fourCCoinf: parseOinfEntry,
+	fourCClinf: parseLinfEntry,
+}
+
+// parseSeigEntry parses CencSampleEncryptionInformationGroupEntry.
+func parseSeigEntry(r *atomReader) (interface{}, error) {
+	entry := new(cencSampleEncryptionInformationGroupEntry)
+	_ = r.Move(1) // reserved
+	b := r.ReadUnsignedByte()
+	entry.cryptByteBlock = b >> 4
+	entry.skipByteBlock = b & 0x0F
+	entry.isProtected = r.ReadUnsignedByte() != 0
+	entry.perSampleIVSize = r.ReadUnsignedByte()
+	if entry.perSampleIVSize != 0 && entry.perSampleIVSize != 8 && entry.perSampleIVSize != 16 {
+		return nil, ErrInvalidLengthOfIVInSampleGroup
+	}
+	entry.kID = make([]byte, 16)
+	_, _ = r.ReadBytes(entry.kID)
+	if entry.isProtected && entry.perSampleIVSize == 0 {
+		constIVSize := r.ReadUnsignedByte()
+		if constIVSize != 8 && constIVSize != 16 {
+			return nil, ErrInvalidLengthOfIVInSampleGroup
+		}
+		entry.constantIV = make([]byte, constIVSize)
+		_, _ = r.ReadBytes(entry.constantIV)
+	}
+	return entry, nil
+}
+
+func parseRollEntry(r *atomReader) (interface{}, error) {
+	return &RollRecoveryEntry{RollDistance: r.Read2S()}, nil
+}
+
+func parseRapEntry(r *atomReader) (interface{}, error) {
+	b := r.ReadUnsignedByte()
+	return &RandomAccessPointEntry{NumLeadingSamplesKnown: b&0x80 != 0, NumLeadingSamples: b & 0x7F}, nil
+}
+
+func parseSyncEntry(r *atomReader) (interface{}, error) {
+	return &SyncSampleEntry{NalUnitType: r.ReadUnsignedByte() & 0x3F}, nil
+}
+
+func parseTeleEntry(r *atomReader) (interface{}, error) {
+	return &TemporalLevelEntry{LevelIndependentlyDecodable: r.ReadUnsignedByte()&0x80 != 0}, nil
+}
+
+// parseAlstEntry parses VisualAlternativeStartupEntry, the pairs of num_output_samples and
+// num_total_samples last to the end of the entry.
+func parseAlstEntry(r *atomReader) (interface{}, error) {
+	entry := &AlternativeStartupEntry{RollCount: r.Read2(), FirstOutputSample: r.Read2()}
+	for i := uint16(0); i < entry.RollCount; i++ {
+		entry.SampleOffsets = append(entry.SampleOffsets, r.Read4())
+	}
+	for r.Len() >= 4 {
+		entry.NumOutputSamples = append(entry.NumOutputSamples, r.Read2())
+		entry.NumTotalSamples = append(entry.NumTotalSamples, r.Read2())
+	}
+	return entry, nil
+}
+
+func parseSapEntry(r *atomReader) (interface{}, error) {
+	b := r.ReadUnsignedByte()
+	return &SAPEntry{DependentFlag: b&0x80 != 0, SAPType: b & 0x0F}, nil
+}
+
+func parseTsclEntry(r *atomReader) (interface{}, error) {
+	entry := &TemporalSubLayerEntry{TemporalLayerID: r.ReadUnsignedByte()}
+	b := r.ReadUnsignedByte()
+	entry.ProfileSpace, entry.TierFlag, entry.ProfileIdc = b>>6, b&0x20 != 0, b&0x1F
+	entry.ProfileCompatibilityFlags = r.Read4()
+	entry.ConstraintIndicatorFlags = uint64(r.Read2())<<32 | uint64(r.Read4())
+	entry.LevelIdc = r.ReadUnsignedByte()
+	entry.MaxBitRate = r.Read2()
+	entry.AvgBitRate = r.Read2()
+	entry.ConstantFrameRate = r.ReadUnsignedByte()
+	entry.AvgFrameRate = r.Read2()
+	return entry, nil
+}
+
+// parseOinfEntry parses OperatingPointsInformation, i.e. OperatingPointsRecord.
+func parseOinfEntry(r *atomReader) (interface{}, error) {
+	entry := &OperatingPointsEntry{ScalabilityMask: r.Read2()}
+	numProfileTierLevel := int(r.ReadUnsignedByte() & 0x3F)
+	for i := 0; i < numProfileTierLevel; i++ {
+		var ptl OperatingPointProfileTierLevel
+		b := r.ReadUnsignedByte()
+		ptl.GeneralProfileSpace, ptl.GeneralTierFlag, ptl.GeneralProfileIdc = b>>6, b&0x20 != 0, b&0x1F
+		ptl.GeneralProfileCompatibilityFlags = r.Read4()
+		ptl.GeneralConstraintIndicatorFlags = uint64(r.Read2())<<32 | uint64(r.Read4())
+		ptl.GeneralLevelIdc = r.ReadUnsignedByte()
+		entry.ProfileTierLevels = append(entry.ProfileTierLevels, ptl)
+	}
+	numOperatingPoints := int(r.Read2())
+	for i := 0; i < numOperatingPoints; i++ {
+		op := OperatingPoint{OutputLayerSetIdx: r.Read2(), MaxTemporalID: r.ReadUnsignedByte()}
+		layerCount := int(r.ReadUnsignedByte())
+		for j := 0; j < layerCount; j++ {
+			ref := OperatingPointLayerRef{PtlIdx: r.ReadUnsignedByte()}
+			b := r.ReadUnsignedByte()
+			ref.LayerID, ref.IsOutputLayer, ref.IsAlternateOutputLayer = b>>2, b&0x02 != 0, b&0x01 != 0
+			op.Layers = append(op.Layers, ref)
+		}
+		op.MinPicWidth, op.MinPicHeight = r.Read2(), r.Read2()
+		op.MaxPicWidth, op.MaxPicHeight = r.Read2(), r.Read2()
+		b := r.ReadUnsignedByte()
+		op.MaxChromaFormat, op.MaxBitDepth = b>>6, (b>>3&0x07)+8
+		op.FrameRateInfo, op.BitRateInfo = b&0x02 != 0, b&0x01 != 0
+		if op.FrameRateInfo {
+			op.AvgFrameRate = r.Read2()
+			op.ConstantFrameRate = r.ReadUnsignedByte() & 0x03
+		}
+		if op.BitRateInfo {
+			op.MaxBitRate, op.AvgBitRate = r.Read4(), r.Read4()
+		}
+		entry.OperatingPoints = append(entry.OperatingPoints, op)
+	}
+	maxLayerCount := int(r.ReadUnsignedByte())
+	for i := 0; i < maxLayerCount; i++ {
+		layer := OperatingPointLayer{LayerID: r.ReadUnsignedByte()}
+		numDirectRefLayers := int(r.ReadUnsignedByte())
+		for j := 0; j < numDirectRefLayers; j++ {
+			layer.DirectRefLayerIDs = append(layer.DirectRefLayerIDs, r.ReadUnsignedByte())
+		}
+		for j := uint(0); j < 16; j++ {
+			if entry.ScalabilityMask&(1<<j) != 0 {
+				layer.DimensionIdentifier = append(layer.DimensionIdentifier, r.ReadUnsignedByte())
+			}
+		}
+		entry.Layers = append(entry.Layers, layer)
+	}
+	return entry, nil
+}
+
+func parseLinfEntry(r *atomReader) (interface{}, error) {
+	entry := new(LayerInformationEntry)
+	numLayers := int(r.ReadUnsignedByte() & 0x3F)
+	for i := 0; i < numLayers; i++ {
+		// reserved(4) layer_id(6) min_TemporalId(3) max_TemporalId(3) reserved(1) sub_layer_presence_flags(7)
+		v := uint32(r.ReadUnsignedByte())<<16 | uint32(r.Read2())
+		entry.Layers = append(entry.Layers, LayerInformation{
+			LayerID:               uint8(v >> 14 & 0x3F),
+			MinTemporalID:         uint8(v >> 11 & 0x07),
+			MaxTemporalID:         uint8(v >> 8 & 0x07),
+			SubLayerPresenceFlags: uint8(v & 0x7F),
+		})
+	}
+	return entry, nil
+}
+
+// findSbgp returns the "sbgp" of the grouping type. nil if not found.
+func findSbgp(sbgps []*boxSbgp, groupingType uint32) *boxSbgp {
+	for _, sbgp := range sbgps {
+		if sbgp.groupingType == groupingType {
+			return sbgp
+		}
+	}
+	return nil
+}
+
+// findSgpd returns the "sgpd" of the grouping type. nil if not found.
+func findSgpd(sgpds []*boxSgpd, groupingType uint32) *boxSgpd {
+	for _, sgpd := range sgpds {
+		if sgpd.groupingType == groupingType {
+			return sgpd
+		}
+	}
+	return nil
+}
+
+// entry returns the entry of the 1-based index. nil if it's out of range.
+func (p *boxSgpd) entry(index uint32) interface{} {
+	if p == nil || index == 0 || index > uint32(len(p.entries)) {
+		return nil
+	}
+	return p.entries[index-1]
+}
+
+// groupDescriptionIndexOf returns the group_description_index of the 0-based sample. 0 if the
+// sample isn't a member of any group of the grouping type.
+func (p *boxSbgp) groupDescriptionIndexOf(sample uint64) uint32 {
+	count := uint64(0)
+	for i := uint32(0); i < p.entryCount; i++ {
+		count += uint64(p.sampleCount[i])
+		if sample < count {
+			return p.groupDescriptionIndex[i]
+		}
+	}
+	return 0
+}
+
+// SampleGroup is a sample group of which a sample is a member.
+type SampleGroup struct {
+	GroupingType          string
+	GroupingTypeParameter uint32 // of "sbgp" version 1
+	DescriptionIndex      uint32 // group_description_index, greater than 0x10000 for the "sgpd" of the fragment
+
+	// the decoded entry of "sgpd": *RollRecoveryEntry, *RandomAccessPointEntry, *SyncSampleEntry,
+	// *TemporalLevelEntry, *AlternativeStartupEntry, *SAPEntry, *TemporalSubLayerEntry,
+	// *OperatingPointsEntry, *LayerInformationEntry, or []byte for the other grouping types.
+	// nil if the entry isn't found.
+	Entry interface{}
+}
+
+// sampleGroups returns the groups of the 0-based sample. sbgps is where the sample is, sgpds
+// is the "sgpd" of the track and fragmentSgpds is the "sgpd" of the fragment.
+func sampleGroups(sample uint64, sbgps []*boxSbgp, sgpds, fragmentSgpds []*boxSgpd) []SampleGroup {
+	var groups []SampleGroup
+	mapped := make(map[uint32]bool)
+	for _, sbgp := range sbgps {
+		index := sbgp.groupDescriptionIndexOf(sample)
+		if index == 0 {
+			continue
+		}
+		mapped[sbgp.groupingType] = true
+		group := SampleGroup{GroupingType: int2String(sbgp.groupingType), DescriptionIndex: index}
+		if sbgp.groupingTypeParameter != nil {
+			group.GroupingTypeParameter = *sbgp.groupingTypeParameter
+		}
+		if index > 0x10000 {
+			group.Entry = findSgpd(fragmentSgpds, sbgp.groupingType).entry(index - 0x10000)
+		} else {
+			group.Entry = findSgpd(sgpds, sbgp.groupingType).entry(index)
+		}
+		groups = append(groups, group)
+	}
+	// the samples not mapped by "sbgp" are the members of the default group of "sgpd" version 2
+	for _, sgpd := range append(append([]*boxSgpd{}, fragmentSgpds...), sgpds...) {
+		if sgpd.defaultSampleDescriptionIndex == nil || *sgpd.defaultSampleDescriptionIndex == 0 || mapped[sgpd.groupingType] {
+			continue
+		}
+		mapped[sgpd.groupingType] = true
+		index := *sgpd.defaultSampleDescriptionIndex
+		groups = append(groups, SampleGroup{GroupingType: int2String(sgpd.groupingType), DescriptionIndex: index,
+			Entry: sgpd.entry(index)})
+	}
+	return groups
+}
+
+// GroupsForSample returns the sample groups of which the sample is a member. n is the 1-based
+// sample number, the samples of the movie fragments follow the samples of "stbl". It must be
+// called after Parse.
+func (p *Parser) GroupsForSample(trackID uint32, n uint64) ([]SampleGroup, error) {
+	if p.m.movie == nil {
+		return nil, ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return nil, ErrNotFoundTrack
+	}
+	if n == 0 {
+		return nil, ErrOutOfRange
+	}
+	sample := n - 1
+	if sample < trak.sampleNumber {
+		return sampleGroups(sample, trak.sbgp, trak.sgpd, nil), nil
+	}
+	sample -= trak.sampleNumber
+	for _, moof := range p.m.fragments {
+		traf := moof.trackFragment(trackID)
+		if traf == nil {
+			continue
+		}
+		if count := traf.sampleCount(); sample >= count {
+			sample -= count
+			continue
+		}
+		return sampleGroups(sample, traf.sbgp, trak.sgpd, traf.sgpd), nil
+	}
+	return nil, ErrOutOfRange
+}
@@ -0,0 +1,115 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseTestSgpd(t *testing.T, box []byte) (*boxSgpd, error) {
+	t.Helper()
+	r := newAtomReader(box[8:], &atom{atomType: fourCCsgpd, headerSize: 8, bodySize: int64(len(box) - 8)})
+	return parseSgpd(r)
+}
+
+func TestParseSgpd(t *testing.T) {
+	// variable length entries
+	alst := mkFullBox("sgpd", 1, 0, []byte("alst"), u32(0), u32(2),
+		u32(8), u16(1), u16(0), u32(5),
+		u32(8), u16(0), u16(1), u16(3), u16(4))
+	sgpd, err := parseTestSgpd(t, alst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		&AlternativeStartupEntry{RollCount: 1, SampleOffsets: []uint32{5}},
+		&AlternativeStartupEntry{FirstOutputSample: 1, NumOutputSamples: []uint16{3}, NumTotalSamples: []uint16{4}},
+	}
+	if !reflect.DeepEqual(sgpd.entries, want) {
+		t.Errorf("alst entries = %+v, want %+v", sgpd.entries, want)
+	}
+
+	// unknown grouping types are kept as raw bytes if the length is known
+	sgpd, err = parseTestSgpd(t, mkFullBox("sgpd", 1, 0, []byte("abcd"), u32(3), u32(1), []byte{1, 2, 3}))
+	if err != nil || !reflect.DeepEqual(sgpd.entries, []interface{}{[]byte{1, 2, 3}}) {
+		t.Errorf("abcd entries = %v, %v", sgpd.entries, err)
+	}
+	if _, err = parseTestSgpd(t, mkFullBox("sgpd", 0, 0, []byte("abcd"), u32(1), []byte{1, 2, 3})); err != ErrUnsupportedSampleGroupType {
+		t.Errorf("got error %v, want ErrUnsupportedSampleGroupType", err)
+	}
+
+	linf := mkFullBox("sgpd", 1, 0, []byte("linf"), u32(4), u32(1), u8(1), u8(0), u16(2<<14|1<<8|1))
+	if sgpd, err = parseTestSgpd(t, linf); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&LayerInformationEntry{Layers: []LayerInformation{{LayerID: 2, MaxTemporalID: 1, SubLayerPresenceFlags: 1}}}); !reflect.DeepEqual(sgpd.entry(1), want) {
+		t.Errorf("linf entry = %+v, want %+v", sgpd.entry(1), want)
+	}
+
+	oinf := mkFullBox("sgpd", 1, 0, []byte("oinf"), u32(0), u32(1), u32(45),
+		u16(0x0002), u8(1),
+		u8(0x01), u32(0x60000000), u16(0x9000), u32(0), u8(93), // profile, tier and level
+		u16(1), u16(1), u8(0), u8(1), u8(0), u8(1<<2|0x02), // operating point of a layer
+		u16(1280), u16(720), u16(1920), u16(1080), u8(1<<6|2<<3|0x01), u32(5000000), u32(4000000),
+		u8(1), u8(1), u8(1), u8(0), u8(5)) // layer 1 depends on layer 0
+	if sgpd, err = parseTestSgpd(t, oinf); err != nil {
+		t.Fatal(err)
+	}
+	wantOinf := &OperatingPointsEntry{
+		ScalabilityMask: 2,
+		ProfileTierLevels: []OperatingPointProfileTierLevel{{GeneralProfileIdc: 1, GeneralProfileCompatibilityFlags: 0x60000000,
+			GeneralConstraintIndicatorFlags: 0x9000 << 32, GeneralLevelIdc: 93}},
+		OperatingPoints: []OperatingPoint{{OutputLayerSetIdx: 1, Layers: []OperatingPointLayerRef{{LayerID: 1, IsOutputLayer: true}},
+			MinPicWidth: 1280, MinPicHeight: 720, MaxPicWidth: 1920, MaxPicHeight: 1080, MaxChromaFormat: 1, MaxBitDepth: 10,
+			BitRateInfo: true, MaxBitRate: 5000000, AvgBitRate: 4000000}},
+		Layers: []OperatingPointLayer{{LayerID: 1, DirectRefLayerIDs: []uint8{0}, DimensionIdentifier: []uint8{5}}},
+	}
+	if !reflect.DeepEqual(sgpd.entry(1), wantOinf) {
+		t.Errorf("oinf entry = %+v, want %+v", sgpd.entry(1), wantOinf)
+	}
+}
+
+func TestParser_GroupsForSample(t *testing.T) {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(0), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+	stbl := mkBox("stbl",
+		mkFullBox("stsd", 0, 0, u32(0)),
+		mkFullBox("stts", 0, 0, u32(0)),
+		mkFullBox("sgpd", 1, 0, []byte("roll"), u32(2), u32(1), u16(0xFFFF)),
+		mkFullBox("sgpd", 2, 0, []byte("sap "), u32(1), u32(1), u8(3)))
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl)))
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(1), u32(40), u32(0), u32(0))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak, mkBox("mvex", trex))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	traf := mkBox("traf",
+		mkFullBox("tfhd", 0, 0x020000, u32(1)),
+		mkFullBox("tfdt", 0, 0, u32(0)),
+		mkFullBox("trun", 0, 0x000200, u32(3), u32(100), u32(100), u32(100)),
+		mkFullBox("sbgp", 0, 0, []byte("roll"), u32(3), u32(1), u32(1), u32(1), u32(0x10001), u32(1), u32(0)),
+		mkFullBox("sgpd", 1, 0, []byte("roll"), u32(2), u32(1), u16(0xFFFE)))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf)
+	file := append(append(append(ftyp, moov...), moof...), mkBox("mdat", make([]byte, 300))...)
+
+	p := newTestParser(t, file)
+	sap := SampleGroup{GroupingType: "sap ", DescriptionIndex: 1, Entry: &SAPEntry{SAPType: 3}}
+	tests := []struct {
+		n    uint64
+		want []SampleGroup
+	}{
+		{1, []SampleGroup{{GroupingType: "roll", DescriptionIndex: 1, Entry: &RollRecoveryEntry{RollDistance: -1}}, sap}},
+		{2, []SampleGroup{{GroupingType: "roll", DescriptionIndex: 0x10001, Entry: &RollRecoveryEntry{RollDistance: -2}}, sap}},
+		{3, []SampleGroup{sap}},
+	}
+	for _, tt := range tests {
+		groups, err := p.GroupsForSample(1, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(groups, tt.want) {
+			t.Errorf("GroupsForSample(1, %d) = %+v, want %+v", tt.n, groups, tt.want)
+		}
+	}
+	if _, err := p.GroupsForSample(1, 4); err != ErrOutOfRange {
+		t.Errorf("got error %v, want ErrOutOfRange", err)
+	}
+}
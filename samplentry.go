@@ -1,8 +1,9 @@
-package main
+package fmp4parser
 
 import (
 	"errors"
 	"math"
+	"math/bits"
 )
 
 // parseConfig AudioSampleEntry
@@ -39,17 +40,18 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 		}
 	} else if audioEntry.quickTimeVersion == 2 {
 		_ = r.Move(16) // it always [3,16,Minus2,0,65536], sizeOfStructOnly
-		tmpSampleRate := r.Read8()
-		audioEntry.sampleRate = uint32(math.Round(float64(tmpSampleRate)))
-		audioEntry.channelCount = r.Read2()   // 2 bytes
+		// audioSampleRate is a 64-bit float, numAudioChannels is 4 bytes
+		audioEntry.sampleRate = uint32(math.Round(math.Float64frombits(r.Read8())))
+		audioEntry.channelCount = uint16(r.Read4())
 		_ = r.Move(4)                         // always 0x7F000000
 		constBitsPerChannel := int(r.Read4()) //	constBitsPerChannel 4 bytes
 		flags := int(r.Read4())
 		_ = r.Move(8) //	constBytesPerAudioPacket(32-bit) + constLPCMFramesPerAudioPacket(32-bit)
+		audioEntry.sampleSize = uint16(constBitsPerChannel)
 		if entryType == lpcmSampleEntry {
 			// The way to deal with "lpcm" comes from ffmpeg. Very thanks
-			bitsPerSample := p.processAudioEntryLPCM(constBitsPerChannel, flags)
-			if bitsPerSample != 0 {
+			audioEntry.lpcmCodec = p.processAudioEntryLPCM(constBitsPerChannel, flags)
+			if bitsPerSample := audioEntry.lpcmCodec.bitsPerSample(); bitsPerSample != 0 {
 				audioEntry.qttfBytesPerSample = bitsPerSample
 			}
 		}
@@ -63,7 +65,8 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 		if err != nil {
 			return errors.New("not find valid protection box in encrypted track")
 		}
-		p.processEncryptedSampleEntry(sinf)
+		audioEntry.protectedInfo = p.processEncryptedSampleEntry(sinf)
+		audioEntry.originalFormat = audioEntry.protectedInfo.DataFormat
 	}
 	audioEntry.descriptorsRawData = make(map[CodecType][]byte)
 	audioEntry.decoderDescriptors = make(map[CodecType]interface{})
@@ -83,18 +86,12 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 				if !p.quickTimeFormat {
 					break
 				}
-				esdsR, err := ar.FindSubAtom(fourCCesds)
-				if err != nil {
-					break
-				}
-				esds := new(EsDescriptor)
-				_ = esds.parseDescriptor(esdsR)
-				audioEntry.channelCount = esds.ChannelCount
-				audioEntry.sampleRate = esds.SampleRate
-				audioEntry.codec = esds.AudioCodec
-				audioEntry.descriptorsRawData[audioEntry.codec] = esds.DecoderSpecificInfo
-				audioEntry.decoderDescriptors[audioEntry.codec] = esds
-				logD.Printf("parsing moov.trak.mdia.stbl.stsd.audioSampleEntries, sample descriptor: wave/esds channel_count is %d sampleRate is %d", audioEntry.channelCount, audioEntry.sampleRate)
+				p.parseWave(audioEntry, ar)
+				break
+			}
+		case fourCCchan:
+			{
+				audioEntry.channelLayout = parseChan(ar)
 				break
 			}
 		case fourCCesds:
@@ -152,7 +149,7 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 		case fourCCdec3:
 			{
 				eac3 := new(Ac3Descriptor)
-				_ = eac3.parseDescriptor(ar)
+				_ = eac3.parseEac3Descriptor(ar)
 				audioEntry.sampleRate = eac3.SampleRate
 				audioEntry.channelCount = eac3.ChannelCount
 				audioEntry.codec = AudioCodecEAC3
@@ -169,6 +166,19 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 				audioEntry.decoderDescriptors[audioEntry.codec] = dts
 				break
 			}
+		case fourCCudts:
+			{
+				udts := new(DtsUhdDescriptor)
+				// a truncated "udts" keeps the channel count and the sample rate of the sample entry
+				if err := udts.parseDescriptor(ar); err == nil && udts.ChannelMask != 0 {
+					audioEntry.channelCount = uint16(bits.OnesCount32(udts.ChannelMask))
+					audioEntry.sampleRate = udts.SampleRate
+				}
+				audioEntry.codec = AudioCodecDTSUHD
+				audioEntry.descriptorsRawData[audioEntry.codec] = udts.DecoderSpecificInfo
+				audioEntry.decoderDescriptors[audioEntry.codec] = udts
+				break
+			}
 		case fourCCdac4:
 			{
 				ac4 := new(Ac4Descriptor)
@@ -179,6 +189,31 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 				audioEntry.decoderDescriptors[audioEntry.codec] = ac4
 				break
 			}
+		case fourCCmhaC:
+			{
+				mha := audioEntry.mhaDescriptor()
+				_ = mha.parseDescriptor(ar)
+				if mha.ChannelCount != 0 {
+					audioEntry.channelCount = mha.ChannelCount
+				}
+				audioEntry.codec = AudioCodecMPEGH
+				audioEntry.descriptorsRawData[audioEntry.codec] = mha.DecoderSpecificInfo
+				break
+			}
+		case fourCCmhaP:
+			{
+				audioEntry.mhaDescriptor().parseCompatibleSets(ar)
+				break
+			}
+		case fourCCiacb:
+			{
+				iamf := new(IamfDescriptor)
+				_ = iamf.parseDescriptor(ar)
+				audioEntry.codec = AudioCodecIAMF
+				audioEntry.descriptorsRawData[audioEntry.codec] = iamf.DecoderSpecificInfo
+				audioEntry.decoderDescriptors[audioEntry.codec] = iamf
+				break
+			}
 		case fourCCdmlp:
 			{
 				mlpa := new(MlpaDescriptor)
@@ -187,27 +222,42 @@ func (p *boxTrak) parseAudioSampleEntry(r *atomReader) error {
 				audioEntry.decoderDescriptors[audioEntry.codec] = mlpa
 				break
 			}
-		default:
-			{
-				if entryType == alawSampleEntry {
-					audioEntry.codec = AudioCodecALAW
-				} else if entryType == ulawSampleEntry {
-					audioEntry.codec = AudioCodecMULAW
-				} else if entryType == dtshSampleEntry || entryType == dtslSampleEntry {
-					audioEntry.codec = AudioCodecDTSHD
-				} else if entryType == dtseSampleEntry {
-					audioEntry.codec = AudioCodecDTSEXPRESS
-				} else if entryType == lpcmSampleEntry || entryType == sowtSampleEntry || entryType == twosSampleEntry {
-					audioEntry.codec = AudioCodecRAW
-				} else if entryType == samrSampleEntry {
-					audioEntry.codec = AudioCodecAMRNB
-				} else if entryType == sawbSampleEntry {
-					audioEntry.codec = AudioCodecAMRWB
-				}
-			}
 		}
 	}
-	p.audioEntry = audioEntry
+	// the sample entries without codec specific box, e.g. PCM
+	if audioEntry.codec == CodecUNKNOW {
+		if entryType == alawSampleEntry {
+			audioEntry.codec = AudioCodecALAW
+		} else if entryType == ulawSampleEntry {
+			audioEntry.codec = AudioCodecMULAW
+		} else if entryType == dtshSampleEntry || entryType == dtslSampleEntry {
+			audioEntry.codec = AudioCodecDTSHD
+		} else if entryType == dtseSampleEntry {
+			audioEntry.codec = AudioCodecDTSEXPRESS
+		} else if entryType == lpcmSampleEntry || entryType == sowtSampleEntry || entryType == twosSampleEntry {
+			audioEntry.codec = AudioCodecRAW
+		} else if entryType == samrSampleEntry {
+			audioEntry.codec = AudioCodecAMRNB
+		} else if entryType == sawbSampleEntry {
+			audioEntry.codec = AudioCodecAMRWB
+		} else if entryType == mha1SampleEntry || entryType == mha2SampleEntry ||
+			entryType == mhm1SampleEntry || entryType == mhm2SampleEntry {
+			// "mhm1" and "mhm2" may carry the configuration in the MHAS packets only
+			audioEntry.codec = AudioCodecMPEGH
+		} else if entryType == iamfSampleEntry {
+			audioEntry.codec = AudioCodecIAMF
+		} else if entryType == dtsxSampleEntry || entryType == dtsySampleEntry {
+			audioEntry.codec = AudioCodecDTSUHD
+		}
+	}
+	if audioEntry.lpcmCodec == None {
+		audioEntry.lpcmCodec = lpcmCodecOfSampleEntry(entryType, audioEntry.sampleSize)
+	}
+	// the first sample description is the default one of the track
+	if p.audioEntry == nil {
+		p.audioEntry = audioEntry
+	}
+	p.sampleEntries = append(p.sampleEntries, &sampleEntry{format: entryType, audio: audioEntry})
 	return err
 }
 
@@ -234,7 +284,8 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 		if err != nil {
 			return errors.New("not find valid protection box in encrypted track")
 		}
-		p.processEncryptedSampleEntry(sinf)
+		videoEntry.protectedInfo = p.processEncryptedSampleEntry(sinf)
+		videoEntry.originalFormat = videoEntry.protectedInfo.DataFormat
 	}
 	videoEntry.configurationRecordsRawData = make(map[CodecType][]byte)
 	videoEntry.decoderConfigurationRecords = make(map[CodecType]interface{})
@@ -248,7 +299,8 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 		}
 		switch ar.a.atomType {
 		case fourCCavcC:
-			if entryType != avc1SampleEntry && entryType != avc3SampleEntry && entryType != encvSampleEntry {
+			if entryType != avc1SampleEntry && entryType != avc3SampleEntry && entryType != encvSampleEntry &&
+				entryType != dvavSampleEntry && entryType != dva1SampleEntry {
 				return errors.New("invalid video sample entry")
 			}
 			avc := new(AvcConfig)
@@ -258,7 +310,8 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 			videoEntry.decoderConfigurationRecords[videoEntry.codec] = avc
 
 		case fourCChvcC:
-			if entryType != hev1SampleEntry && entryType != hvc1SampleEntry && entryType != hVC1SampleEntry && entryType != encvSampleEntry {
+			if entryType != hev1SampleEntry && entryType != hvc1SampleEntry && entryType != hVC1SampleEntry && entryType != encvSampleEntry &&
+				entryType != dvheSampleEntry && entryType != dvh1SampleEntry {
 				return errors.New("invalid video sample entry")
 			}
 			hevc := new(HevcConfig)
@@ -267,8 +320,18 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 			videoEntry.configurationRecordsRawData[videoEntry.codec] = hevc.DecoderSpecificInfo
 			videoEntry.decoderConfigurationRecords[videoEntry.codec] = hevc
 
+		case fourCCvvcC:
+			if entryType != vvc1SampleEntry && entryType != vvi1SampleEntry && entryType != encvSampleEntry {
+				return errors.New("invalid video sample entry")
+			}
+			vvc := new(VvcConfig)
+			_ = vvc.parseConfig(ar)
+			videoEntry.codec = VideoCodecVVC
+			videoEntry.configurationRecordsRawData[videoEntry.codec] = vvc.DecoderSpecificInfo
+			videoEntry.decoderConfigurationRecords[videoEntry.codec] = vvc
+
 		case fourCCav1c:
-			if entryType != av01SampleEntry {
+			if entryType != av01SampleEntry && entryType != dav1SampleEntry && entryType != encvSampleEntry {
 				return errors.New("invalid video sample entry")
 			}
 			av1c := new(Av1cConfig)
@@ -292,9 +355,7 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 			videoEntry.decoderConfigurationRecords[videoEntry.codec] = vpc
 
 			// Dolby Vision configuration box should be parsed after by avcC/hvcC box
-		case fourCCdvcC:
-			fallthrough
-		case fourCCdvvC:
+		case fourCCdvcC, fourCCdvvC, fourCCdvwC:
 			dvc := new(DvcConfig)
 			_ = dvc.parseConfig(ar)
 			videoEntry.codec = VideoCodecDolbyVision
@@ -310,16 +371,90 @@ func (p *boxTrak) parseVideoSampleEntry(r *atomReader) error {
 		case fourCCclap:
 			p.parseClap(videoEntry, ar)
 
+		case fourCCmdcv:
+			videoEntry.masteringDisplay = parseMdcv(ar)
+
+		case fourCCSmDm:
+			videoEntry.masteringDisplay = parseSmDm(ar)
+
+		case fourCCclli:
+			videoEntry.contentLight = parseClli(ar, false)
+
+		case fourCCCoLL:
+			videoEntry.contentLight = parseClli(ar, true)
+
+		case fourCCst3d:
+			p.parseSt3d(videoEntry, ar)
+
+		case fourCCsv3d:
+			p.parseSv3d(videoEntry, ar)
+
 		default:
 			logD.Print("atom type in sample descriptor is not parsed yet, ", ar.a)
 
 		}
 	}
-	p.videoEntry = videoEntry
+	// the sample entries without codec configuration box
+	if videoEntry.codec == CodecUNKNOW {
+		switch entryType {
+		case apchSampleEntry, apcnSampleEntry, apcsSampleEntry, apcoSampleEntry, ap4hSampleEntry, ap4xSampleEntry:
+			videoEntry.codec = VideoCodecProRes
+		}
+	}
+	// the first sample description is the default one of the track
+	if p.videoEntry == nil {
+		p.videoEntry = videoEntry
+	}
+	p.sampleEntries = append(p.sampleEntries, &sampleEntry{format: entryType, video: videoEntry})
 	return err
 }
 
-func (p *boxTrak) processAudioEntryLPCM(constBitsPerChannel, flags int) uint32 {
+// lpcmCodecOfSampleEntry returns the PCM format of "sowt", "twos", "alaw" and "ulaw" by
+// the sample size of the sample entry, refer to QTFF Sound Sample Description (Version 0).
+func lpcmCodecOfSampleEntry(entryType uint32, sampleSize uint16) lpcmCodecId {
+	switch entryType {
+	case alawSampleEntry:
+		return pcmALaw
+	case ulawSampleEntry:
+		return pcmMULaw
+	case sowtSampleEntry:
+		switch sampleSize {
+		case 8:
+			return pcmS8
+		case 24:
+			return pcmS24LE
+		case 32:
+			return pcmS32LE
+		}
+		return pcmS16LE
+	case twosSampleEntry:
+		switch sampleSize {
+		case 8:
+			return pcmS8
+		case 24:
+			return pcmS24BE
+		case 32:
+			return pcmS32BE
+		}
+		return pcmS16BE
+	}
+	return None
+}
+
+// mhaDescriptor returns the MhaDescriptor of the entry, it's created by the first of "mhaC"
+// and "mhaP".
+func (p *audioSampleEntry) mhaDescriptor() *MhaDescriptor {
+	if mha, ok := p.decoderDescriptors[AudioCodecMPEGH].(*MhaDescriptor); ok {
+		return mha
+	}
+	mha := new(MhaDescriptor)
+	p.decoderDescriptors[AudioCodecMPEGH] = mha
+	return mha
+}
+
+// processAudioEntryLPCM returns the PCM format of the "lpcm" sample entry by
+// constBitsPerChannel and formatSpecificFlags of the version 2 of quicktime.
+func (p *boxTrak) processAudioEntryLPCM(constBitsPerChannel, flags int) lpcmCodecId {
 	codec := func(bps int, flags int) lpcmCodecId {
 		flt := flags & 1
 		be := flags & 2
@@ -428,80 +563,83 @@ func (p *boxTrak) processAudioEntryLPCM(constBitsPerChannel, flags int) uint32 {
 		}
 	default:
 	}
-	return func(codec lpcmCodecId) uint32 {
-		switch codec {
-		case pcmALaw:
-			fallthrough
-		case pcmMULaw:
-			fallthrough
-		case pcmVIDC:
-			fallthrough
-		case pcmS8:
-			fallthrough
-		case pcmS8Planar:
-			fallthrough
-		case pcmU8:
-			fallthrough
-		case pcmZORK:
-			return 8
-
-		case pcmS16BE:
-			fallthrough
-		case pcmS16BEPlanar:
-			fallthrough
-		case pcmS16LE:
-			fallthrough
-		case pcmS16LEPlanar:
-			fallthrough
-		case pcmU16BE:
-			fallthrough
-		case pcmU16LE:
-			return 16
-		case pcmS24DAUD:
-			fallthrough
-		case pcmS24BE:
-			fallthrough
-		case pcmS24LE:
-			fallthrough
-		case pcmS24LEPlanar:
-			fallthrough
-		case pcmU24BE:
-			fallthrough
-		case pcmU24LE:
-			return 24
-		case pcmS32BE:
-			fallthrough
-		case pcmS32LE:
-			fallthrough
-		case pcmS32LEPlanar:
-			fallthrough
-		case pcmU32BE:
-			fallthrough
-		case pcmU32LE:
-			fallthrough
-		case pcmF32BE:
-			fallthrough
-		case pcmF32LE:
-			fallthrough
-		case pcmF24LE:
-			fallthrough
-		case pcmF16LE:
-			return 32
-		case pcmF64BE:
-			fallthrough
-		case pcmF64LE:
-			fallthrough
-		case pcmS64BE:
-			fallthrough
-		case pcmS64LE:
-			return 64
-		default:
-			return 0
-		}
-	}(codec)
+	return codec
 }
 
-func (p *boxTrak) processEncryptedSampleEntry(r *atomReader) {
+// bitsPerSample returns the bits of a sample of a channel, 0 if unknown.
+func (codec lpcmCodecId) bitsPerSample() uint32 {
+	switch codec {
+	case pcmALaw:
+		fallthrough
+	case pcmMULaw:
+		fallthrough
+	case pcmVIDC:
+		fallthrough
+	case pcmS8:
+		fallthrough
+	case pcmS8Planar:
+		fallthrough
+	case pcmU8:
+		fallthrough
+	case pcmZORK:
+		return 8
+
+	case pcmS16BE:
+		fallthrough
+	case pcmS16BEPlanar:
+		fallthrough
+	case pcmS16LE:
+		fallthrough
+	case pcmS16LEPlanar:
+		fallthrough
+	case pcmU16BE:
+		fallthrough
+	case pcmU16LE:
+		return 16
+	case pcmS24DAUD:
+		fallthrough
+	case pcmS24BE:
+		fallthrough
+	case pcmS24LE:
+		fallthrough
+	case pcmS24LEPlanar:
+		fallthrough
+	case pcmU24BE:
+		fallthrough
+	case pcmU24LE:
+		return 24
+	case pcmS32BE:
+		fallthrough
+	case pcmS32LE:
+		fallthrough
+	case pcmS32LEPlanar:
+		fallthrough
+	case pcmU32BE:
+		fallthrough
+	case pcmU32LE:
+		fallthrough
+	case pcmF32BE:
+		fallthrough
+	case pcmF32LE:
+		fallthrough
+	case pcmF24LE:
+		fallthrough
+	case pcmF16LE:
+		return 32
+	case pcmF64BE:
+		fallthrough
+	case pcmF64LE:
+		fallthrough
+	case pcmS64BE:
+		fallthrough
+	case pcmS64LE:
+		return 64
+	default:
+		return 0
+	}
+}
+
+func (p *boxTrak) processEncryptedSampleEntry(r *atomReader) *ProtectedInformation {
 	protection := new(ProtectedInformation)
 	for {
 		a, err := r.GetSubAtom()
@@ -510,32 +648,35 @@ func (p *boxTrak) processEncryptedSampleEntry(r *atomReader) {
 		}
 		switch a.a.atomType {
 		case fourCCfrma: // Original Format
-			p.format = a.Read4() // data_format , coding name
+			protection.DataFormat = a.Read4() // data_format , coding name
+			if p.format == 0 {
+				p.format = protection.DataFormat
+			}
 
 		case fourCCschm: // Scheme type
-			_ = r.Move(4) // version + flags
-			protection.SchemeType = r.Read4()
-			protection.SchemeVersion = r.Read4()
+			_ = a.Move(4) // version + flags
+			protection.SchemeType = a.Read4()
+			protection.SchemeVersion = a.Read4()
 
 		case fourCCschi: // Scheme Information
-			_ = r.ReadAtomHeader() // "tenc" header
-			v, _ := r.ReadVersionFlags()
-			_ = r.Move(1)
+			_ = a.ReadAtomHeader() // "tenc" header
+			v, _ := a.ReadVersionFlags()
+			_ = a.Move(1)
 			if v == 0 {
-				_ = r.Move(1)
+				_ = a.Move(1)
 			} else {
-				defaultByteBlock := r.ReadUnsignedByte()
+				defaultByteBlock := a.ReadUnsignedByte()
 				protection.DefaultCryptByteBlock = (defaultByteBlock & 0xF0) >> 4
 				protection.DefaultSkipByteBlock = defaultByteBlock & 0x0F
 			}
-			protection.DefaultIsProtected = r.ReadUnsignedByte()
-			protection.DefaultPerSampleIVSize = r.ReadUnsignedByte()
+			protection.DefaultIsProtected = a.ReadUnsignedByte()
+			protection.DefaultPerSampleIVSize = a.ReadUnsignedByte()
 			protection.DefaultKID = make([]byte, 16)
-			_, _ = r.ReadBytes(protection.DefaultKID)
+			_, _ = a.ReadBytes(protection.DefaultKID)
 			if protection.DefaultIsProtected == 1 && protection.DefaultPerSampleIVSize == 0 {
-				protection.DefaultConstantIVSize = r.ReadUnsignedByte()
+				protection.DefaultConstantIVSize = a.ReadUnsignedByte()
 				protection.DefaultConstantIV = make([]byte, protection.DefaultConstantIVSize)
-				_, _ = r.ReadBytes(protection.DefaultConstantIV)
+				_, _ = a.ReadBytes(protection.DefaultConstantIV)
 			}
 
 		}
@@ -544,12 +685,16 @@ func (p *boxTrak) processEncryptedSampleEntry(r *atomReader) {
 	if p.protection[0].DefaultIsProtected != 0 {
 		p.encrypted = true
 	}
+	return protection
 }
 
+// colour_type of "colr", on-screen colours
+const colourTypeNCLX uint32 = 0x6e636c78 // "nclx"
+
 func (p *boxTrak) parseColr(v *videoSampleEntry, r *atomReader) {
 	colourType := r.Read4()
 	v.colourType = colourType
-	if colourType == 0x6e636c78 { // "nclx"
+	if colourType == colourTypeNCLX {
 		v.colorPrimaries = r.Read2()
 		v.transferCharacteristics = r.Read2()
 		v.matrixCoefficients = r.Read2()
@@ -0,0 +1,146 @@
+package fmp4parser
+
+// SegmentIndex is the resolved form of a "sidx" box (ISO/IEC 14496-12 8.16.3).
+// All the byte ranges are absolute positions in the file, so that they can be
+// used directly as the byte ranges of DASH SegmentBase.
+type SegmentIndex struct {
+	ReferenceID              uint32 // the stream ID for the reference stream
+	TimeScale                uint32 // the timescale of the times in this index
+	EarliestPresentationTime uint64 // the earliest presentation time of the first subsegment
+	FirstOffset              uint64 // distance from the end of the "sidx" box to the first referenced byte
+	Offset                   uint64 // absolute position of the "sidx" box
+	Size                     uint64 // full size of the "sidx" box. [Offset, Offset+Size) is the "indexRange" in DASH
+	References               []*SegmentReference
+}
+
+// SegmentReference is one entry of a SegmentIndex. If ReferenceType is 1, the
+// reference points to another "sidx" box which is resolved in Index.
+type SegmentReference struct {
+	ReferenceType uint8  // 0: media (moof+mdat...); 1: segment index ("sidx")
+	Offset        uint64 // absolute position of the first byte of the referenced material
+	Size          uint32 // size of the referenced material in bytes
+	StartTime     uint64 // presentation start time in TimeScale of the index
+	EndTime       uint64 // presentation end time (exclusive) in TimeScale of the index
+	Duration      uint32 // subsegment duration in TimeScale of the index
+	StartsWithSAP bool
+	SAPType       uint8
+	SAPDeltaTime  uint32        // offset of the first SAP from StartTime
+	Index         *SegmentIndex // if ReferenceType == 1, the referenced "sidx". nil if not found
+}
+
+// maximum depth of hierarchical "sidx", it prevents the loop caused by broken files.
+const maxSegmentIndexDepth = 8
+
+// ByteRange returns the first and the last byte (inclusive) of the referenced material.
+func (p *SegmentReference) ByteRange() (first uint64, last uint64) {
+	return p.Offset, p.Offset + uint64(p.Size) - 1
+}
+
+// Subsegments returns all the media references of the index in presentation order.
+// The references of the hierarchical "sidx" are expanded.
+func (p *SegmentIndex) Subsegments() []*SegmentReference {
+	var refs []*SegmentReference
+	for _, ref := range p.References {
+		if ref.ReferenceType == 1 {
+			if ref.Index != nil {
+				refs = append(refs, ref.Index.Subsegments()...)
+			}
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// SegmentIndex returns the top level segment index of the file. The reference of
+// hierarchical "sidx" (reference_type == 1) is resolved recursively.
+// It must be called after Parse. If there are more than one top level "sidx",
+// the first one is returned.
+func (p *Parser) SegmentIndex() (*SegmentIndex, error) {
+	if p.m.movie == nil || len(p.m.movie.sidx) == 0 {
+		return nil, ErrNoSegmentIndex
+	}
+	root := rootSegmentIndex(p.m.movie.sidx)
+	if root == nil {
+		return nil, ErrNoSegmentIndex
+	}
+	return root.resolve(p.m.movie.sidx, 0), nil
+}
+
+// rootSegmentIndex find the first "sidx" which isn't referenced by other "sidx".
+func rootSegmentIndex(list []*boxSidx) *boxSidx {
+	if roots := rootSegmentIndexes(list); len(roots) > 0 {
+		return roots[0]
+	}
+	return nil
+}
+
+// rootSegmentIndexes find all the "sidx" which aren't referenced by other "sidx", e.g.
+// one for each track in a multiplexed file.
+func rootSegmentIndexes(list []*boxSidx) []*boxSidx {
+	referenced := make(map[int64]bool)
+	for _, sidx := range list {
+		pos := sidx.offset + sidx.size + int64(sidx.firstTime)
+		for _, ref := range sidx.reference {
+			if ref.referenceType == 1 {
+				referenced[pos] = true
+			}
+			pos += int64(ref.referenceSize)
+		}
+	}
+	var roots []*boxSidx
+	for _, sidx := range list {
+		if !referenced[sidx.offset] {
+			roots = append(roots, sidx)
+		}
+	}
+	return roots
+}
+
+func findSegmentIndexAt(list []*boxSidx, offset uint64) *boxSidx {
+	for _, sidx := range list {
+		if uint64(sidx.offset) == offset {
+			return sidx
+		}
+	}
+	return nil
+}
+
+// resolve converts the "sidx" into SegmentIndex. The anchor point of the byte ranges
+// is the first byte following the "sidx" box.
+func (p *boxSidx) resolve(list []*boxSidx, depth int) *SegmentIndex {
+	index := &SegmentIndex{
+		ReferenceID:              p.referenceID,
+		TimeScale:                p.timeScale,
+		EarliestPresentationTime: p.earlistPresentationTime,
+		FirstOffset:              p.firstTime,
+		Offset:                   uint64(p.offset),
+		Size:                     uint64(p.size),
+	}
+	pos := uint64(p.offset+p.size) + p.firstTime
+	startTime := p.earlistPresentationTime
+	for _, ref := range p.reference {
+		sr := &SegmentReference{
+			ReferenceType: ref.referenceType,
+			Offset:        pos,
+			Size:          ref.referenceSize,
+			StartTime:     startTime,
+			EndTime:       startTime + uint64(ref.subSegmentDuration),
+			Duration:      ref.subSegmentDuration,
+			StartsWithSAP: ref.startWithSAP == 1,
+			SAPType:       ref.sapType,
+			SAPDeltaTime:  ref.sapDeltaTime,
+		}
+		if ref.referenceType == 1 {
+			if child := findSegmentIndexAt(list, pos); child != nil && depth < maxSegmentIndexDepth {
+				sr.Index = child.resolve(list, depth+1)
+			} else {
+				logW.Printf("sidx reference at %d is not resolved", pos)
+			}
+		}
+		index.References = append(index.References, sr)
+		pos += uint64(ref.referenceSize)
+		startTime += uint64(ref.subSegmentDuration)
+	}
+	return index
+}
@@ -0,0 +1,116 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+// mkBox builds a box with the type and the concatenated payloads.
+func mkBox(boxType string, payloads ...[]byte) []byte {
+	body := bytes.Join(payloads, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], boxType)
+	return append(b, body...)
+}
+
+// mkFullBox builds a full box with version and flags.
+func mkFullBox(boxType string, version uint8, flags uint32, payloads ...[]byte) []byte {
+	return mkBox(boxType, append([][]byte{u32(uint32(version)<<24 | flags&0xFFFFFF)}, payloads...)...)
+}
+
+func u8(v uint8) []byte { return []byte{v} }
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+type testSidxRef struct {
+	refType  uint8
+	size     uint32
+	duration uint32
+	sapType  uint8
+}
+
+func mkSidx(ept uint64, firstOffset uint32, refs ...testSidxRef) []byte {
+	payload := [][]byte{u32(1), u32(1000), u32(uint32(ept)), u32(firstOffset), u16(0), u16(uint16(len(refs)))}
+	for _, ref := range refs {
+		payload = append(payload, u32(uint32(ref.refType)<<31|ref.size), u32(ref.duration),
+			u32(1<<31|uint32(ref.sapType)<<28))
+	}
+	return mkFullBox("sidx", 0, 0, payload...)
+}
+
+func newTestParser(t *testing.T, file []byte) *Parser {
+	newLog(ioutil.Discard)
+	p := &Parser{m: newMediaInfo(bytes.NewReader(file))}
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParser_SegmentIndex(t *testing.T) {
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	mdat1 := mkBox("mdat", make([]byte, 100))
+	mdat2 := mkBox("mdat", make([]byte, 50))
+	mdat3 := mkBox("mdat", make([]byte, 70))
+	childA := mkSidx(0, 0,
+		testSidxRef{size: uint32(len(mdat1)), duration: 2000, sapType: 1},
+		testSidxRef{size: uint32(len(mdat2)), duration: 1000, sapType: 2})
+	childB := mkSidx(3000, 0, testSidxRef{size: uint32(len(mdat3)), duration: 500, sapType: 1})
+	root := mkSidx(0, 0,
+		testSidxRef{refType: 1, size: uint32(len(childA) + len(mdat1) + len(mdat2)), duration: 3000},
+		testSidxRef{refType: 1, size: uint32(len(childB) + len(mdat3)), duration: 500})
+	file := bytes.Join([][]byte{ftyp, root, childA, mdat1, mdat2, childB, mdat3}, nil)
+
+	index, err := newTestParser(t, file).SegmentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Offset != uint64(len(ftyp)) || index.Size != uint64(len(root)) {
+		t.Fatalf("unexpected index range [%d, %d)", index.Offset, index.Offset+index.Size)
+	}
+	if len(index.References) != 2 || index.References[0].Index == nil || index.References[1].Index == nil {
+		t.Fatal("hierarchical sidx is not resolved")
+	}
+	subsegments := index.Subsegments()
+	if len(subsegments) != 3 {
+		t.Fatalf("expect 3 subsegments, got %d", len(subsegments))
+	}
+	mdat1Pos := uint64(len(ftyp) + len(root) + len(childA))
+	mdat3Pos := mdat1Pos + uint64(len(mdat1)+len(mdat2)+len(childB))
+	expected := []struct {
+		offset, start, end uint64
+		sap                uint8
+	}{
+		{mdat1Pos, 0, 2000, 1},
+		{mdat1Pos + uint64(len(mdat1)), 2000, 3000, 2},
+		{mdat3Pos, 3000, 3500, 1},
+	}
+	for i, e := range expected {
+		s := subsegments[i]
+		if s.Offset != e.offset || s.StartTime != e.start || s.EndTime != e.end || s.SAPType != e.sap || !s.StartsWithSAP {
+			t.Errorf("subsegment %d: got offset:%d start:%d end:%d sap:%d", i, s.Offset, s.StartTime, s.EndTime, s.SAPType)
+		}
+	}
+	if first, last := subsegments[2].ByteRange(); first != mdat3Pos || last != uint64(len(file)-1) {
+		t.Errorf("unexpected byte range of the last subsegment %d-%d", first, last)
+	}
+}
@@ -0,0 +1,252 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+Spherical video metadata, refer to https://github.com/google/spatial-media/tree/master/docs
+
+V1 is a "uuid" box of the track with the RDF/XML:
+
+	<rdf:SphericalVideo xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	    xmlns:GSpherical="http://ns.google.com/videos/1.0/spherical/">
+	  <GSpherical:Spherical>true</GSpherical:Spherical>
+	  <GSpherical:Stitched>true</GSpherical:Stitched>
+	  <GSpherical:ProjectionType>equirectangular</GSpherical:ProjectionType>
+	  <GSpherical:StereoMode>top-bottom</GSpherical:StereoMode>
+	  ...
+	</rdf:SphericalVideo>
+
+V2 is the boxes of the video sample entry:
+
+	st3d: unsigned int(8) stereo_mode
+	sv3d
+	  svhd: string metadata_source
+	  proj
+	    prhd: int(32) pose_yaw_degrees, pose_pitch_degrees, pose_roll_degrees (16.16 fixed-point)
+	    equi: unsigned int(32) projection_bounds_top, bottom, left, right (0.32 fixed-point)
+	    cbmp: unsigned int(32) layout, padding
+	    mshp: mesh projection
+*/
+
+// StereoMode is the stereoscopic 3D mode of the video.
+type StereoMode uint8
+
+const (
+	StereoMono StereoMode = iota
+	StereoTopBottom
+	StereoLeftRight
+	StereoCustom    // the stereo mode is defined by the mesh projection
+	StereoRightLeft // not defined by V1
+)
+
+// ProjectionType is the projection of the spherical video.
+type ProjectionType uint8
+
+const (
+	ProjectionNone ProjectionType = iota
+	ProjectionEquirectangular
+	ProjectionCubemap
+	ProjectionMesh
+)
+
+// Spatial is the spherical (360°) and stereoscopic 3D metadata of a video track.
+type Spatial struct {
+	Version    int // 1 for the RDF/XML of "uuid", 2 for "st3d" and "sv3d"
+	Spherical  bool
+	Stitched   bool
+	Stereo     StereoMode
+	Projection ProjectionType
+
+	// V1 only
+	StitchingSoftware  string
+	SourceCount        int
+	InitialViewHeading float64 // in degrees
+	InitialViewPitch   float64
+	InitialViewRoll    float64
+	FullPanoWidth      int // in pixels
+	FullPanoHeight     int
+	CroppedAreaWidth   int
+	CroppedAreaHeight  int
+	CroppedAreaLeft    int
+	CroppedAreaTop     int
+
+	// V2 only
+	MetadataSource string
+	PoseYaw        float64 // in degrees, "prhd"
+	PosePitch      float64
+	PoseRoll       float64
+	// the bounds of "equi" in 0.32 fixed-point, the amount to crop from each edge of the frame
+	BoundsTop    uint32
+	BoundsBottom uint32
+	BoundsLeft   uint32
+	BoundsRight  uint32
+	// "cbmp"
+	CubemapLayout  uint32
+	CubemapPadding uint32
+}
+
+var stereoModeNames = map[string]StereoMode{
+	"mono":       StereoMono,
+	"top-bottom": StereoTopBottom,
+	"left-right": StereoLeftRight,
+}
+
+var errNotSpherical = errors.New("not spherical video metadata")
+
+// sphericalUUID is the usertype of the "uuid" box of the spherical video V1.
+var sphericalUUID = []byte{0xff, 0xcc, 0x82, 0x63, 0xf8, 0x55, 0x4a, 0x93, 0x88, 0x14, 0x58, 0x7a, 0x02, 0x52, 0x1f, 0xdd}
+
+// parseSphericalXML parses the RDF/XML of the spherical video V1. The elements are matched by
+// the local name, the namespace prefix is ignored.
+func parseSphericalXML(data []byte) (*Spatial, error) {
+	spatial := &Spatial{Version: 1}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var name string
+	for {
+		token, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+		case xml.EndElement:
+			name = ""
+		case xml.CharData:
+			if name != "" {
+				spatial.setV1Field(name, strings.TrimSpace(string(t)))
+			}
+		}
+	}
+	if !spatial.Spherical {
+		return nil, errNotSpherical
+	}
+	return spatial, nil
+}
+
+func (p *Spatial) setV1Field(name, value string) {
+	integer := func() int {
+		n, _ := strconv.Atoi(value)
+		return n
+	}
+	float := func() float64 {
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	switch name {
+	case "Spherical":
+		p.Spherical = value == "true"
+	case "Stitched":
+		p.Stitched = value == "true"
+	case "StitchingSoftware":
+		p.StitchingSoftware = value
+	case "ProjectionType":
+		if value == "equirectangular" {
+			p.Projection = ProjectionEquirectangular
+		}
+	case "StereoMode":
+		p.Stereo = stereoModeNames[value]
+	case "SourceCount":
+		p.SourceCount = integer()
+	case "InitialViewHeadingDegrees":
+		p.InitialViewHeading = float()
+	case "InitialViewPitchDegrees":
+		p.InitialViewPitch = float()
+	case "InitialViewRollDegrees":
+		p.InitialViewRoll = float()
+	case "FullPanoWidthPixels":
+		p.FullPanoWidth = integer()
+	case "FullPanoHeightPixels":
+		p.FullPanoHeight = integer()
+	case "CroppedAreaImageWidthPixels":
+		p.CroppedAreaWidth = integer()
+	case "CroppedAreaImageHeightPixels":
+		p.CroppedAreaHeight = integer()
+	case "CroppedAreaLeftPixels":
+		p.CroppedAreaLeft = integer()
+	case "CroppedAreaTopPixels":
+		p.CroppedAreaTop = integer()
+	}
+}
+
+// spatialV2 returns the spatial metadata of the sample entry, it's created by the first V2 box.
+func (v *videoSampleEntry) spatialV2() *Spatial {
+	if v.spatial == nil {
+		v.spatial = &Spatial{Version: 2}
+	}
+	return v.spatial
+}
+
+// parseSt3d parses Stereoscopic3D box.
+func (p *boxTrak) parseSt3d(v *videoSampleEntry, r *atomReader) {
+	_, _ = r.ReadVersionFlags()
+	v.spatialV2().Stereo = StereoMode(r.ReadUnsignedByte())
+}
+
+// parseSv3d parses SphericalVideoBox and the boxes in it.
+func (p *boxTrak) parseSv3d(v *videoSampleEntry, r *atomReader) {
+	spatial := v.spatialV2()
+	spatial.Spherical = true
+	spatial.Stitched = true
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			break
+		}
+		switch ar.TypeCC() {
+		case fourCCsvhd:
+			_, _ = ar.ReadVersionFlags()
+			source := make([]byte, ar.Len())
+			_, _ = ar.ReadBytes(source)
+			spatial.MetadataSource = strings.TrimRight(string(source), "\x00")
+		case fourCCproj:
+			spatial.parseProj(ar)
+		}
+	}
+}
+
+// parseProj parses Projection box.
+func (p *Spatial) parseProj(r *atomReader) {
+	for {
+		ar, err := r.GetSubAtom()
+		if err != nil {
+			break
+		}
+		switch ar.TypeCC() {
+		case fourCCprhd:
+			_, _ = ar.ReadVersionFlags()
+			p.PoseYaw = float64(ar.Read4S()) / (1 << 16)
+			p.PosePitch = float64(ar.Read4S()) / (1 << 16)
+			p.PoseRoll = float64(ar.Read4S()) / (1 << 16)
+		case fourCCequi:
+			_, _ = ar.ReadVersionFlags()
+			p.Projection = ProjectionEquirectangular
+			p.BoundsTop = ar.Read4()
+			p.BoundsBottom = ar.Read4()
+			p.BoundsLeft = ar.Read4()
+			p.BoundsRight = ar.Read4()
+		case fourCCcbmp:
+			_, _ = ar.ReadVersionFlags()
+			p.Projection = ProjectionCubemap
+			p.CubemapLayout = ar.Read4()
+			p.CubemapPadding = ar.Read4()
+		case fourCCmshp:
+			p.Projection = ProjectionMesh
+		}
+	}
+}
+
+// spatialOf returns the spatial metadata of the track, the V2 boxes of the sample entry are
+// preferred to the V1 "uuid" box.
+func (p *boxTrak) spatialOf(v *videoSampleEntry) *Spatial {
+	if v != nil && v.spatial != nil {
+		return v.spatial
+	}
+	return p.spatial
+}
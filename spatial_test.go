@@ -0,0 +1,75 @@
+package fmp4parser
+
+import (
+	"testing"
+)
+
+func TestBoxTrak_parseUuid(t *testing.T) {
+	xml := `<?xml version="1.0"?><rdf:SphericalVideo
+xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+xmlns:GSpherical="http://ns.google.com/videos/1.0/spherical/">
+<GSpherical:Spherical>true</GSpherical:Spherical>
+<GSpherical:Stitched>true</GSpherical:Stitched>
+<GSpherical:StitchingSoftware>Spherical Metadata Tool</GSpherical:StitchingSoftware>
+<GSpherical:ProjectionType>equirectangular</GSpherical:ProjectionType>
+<GSpherical:StereoMode>top-bottom</GSpherical:StereoMode>
+<GSpherical:InitialViewHeadingDegrees>90</GSpherical:InitialViewHeadingDegrees>
+<GSpherical:FullPanoWidthPixels>3840</GSpherical:FullPanoWidthPixels>
+<GSpherical:FullPanoHeightPixels>2160</GSpherical:FullPanoHeightPixels>
+</rdf:SphericalVideo>`
+	body := append(append([]byte{}, sphericalUUID...), xml...)
+	trak := new(boxTrak)
+	trak.parseUuid(newAtomReader(body, &atom{atomType: fourCCuuid, headerSize: 8, bodySize: int64(len(body))}))
+	s := trak.spatialOf(nil)
+	if s == nil {
+		t.Fatal("spatial metadata is not parsed")
+	}
+	if s.Version != 1 || !s.Spherical || !s.Stitched || s.Projection != ProjectionEquirectangular || s.Stereo != StereoTopBottom {
+		t.Errorf("unexpected spatial %+v", s)
+	}
+	if s.StitchingSoftware != "Spherical Metadata Tool" || s.InitialViewHeading != 90 || s.FullPanoWidth != 3840 || s.FullPanoHeight != 2160 {
+		t.Errorf("unexpected spatial %+v", s)
+	}
+
+	other := new(boxTrak)
+	body[0] = 0
+	other.parseUuid(newAtomReader(body, &atom{atomType: fourCCuuid, headerSize: 8, bodySize: int64(len(body))}))
+	if other.spatial != nil {
+		t.Error("unknown uuid should be ignored")
+	}
+}
+
+func TestTrack_Spatial(t *testing.T) {
+	st3d := mkFullBox("st3d", 0, 0, []byte{byte(StereoLeftRight)})
+	prhd := mkFullBox("prhd", 0, 0, u32(90<<16), u32(0), u32(0xFFFF0000)) // yaw 90, roll -1
+	tests := []struct {
+		name       string
+		proj       []byte
+		projection ProjectionType
+	}{
+		{"equirectangular", mkFullBox("equi", 0, 0, u32(0), u32(0), u32(1<<30), u32(1<<30)), ProjectionEquirectangular},
+		{"cubemap", mkFullBox("cbmp", 0, 0, u32(0), u32(16)), ProjectionCubemap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sv3d := mkBox("sv3d", mkFullBox("svhd", 0, 0, []byte("Spherical Metadata Tool\x00")), mkBox("proj", prhd, tt.proj))
+			entry := mkVideoEntry("avc1", 3840, 2160, mkBox("avcC", []byte{1, 0x64, 0, 0x33, 0xFF, 0xE0, 0}), st3d, sv3d)
+			s := newTestParser(t, mkVideoInit(entry)).GetTracks()[0].Spatial
+			if s == nil {
+				t.Fatal("spatial metadata is not parsed")
+			}
+			if s.Version != 2 || !s.Spherical || s.Stereo != StereoLeftRight || s.Projection != tt.projection {
+				t.Errorf("unexpected spatial %+v", s)
+			}
+			if s.MetadataSource != "Spherical Metadata Tool" || s.PoseYaw != 90 || s.PoseRoll != -1 {
+				t.Errorf("unexpected spatial %+v", s)
+			}
+			if tt.projection == ProjectionEquirectangular && s.BoundsLeft != 1<<30 {
+				t.Errorf("bounds = %d, want %d", s.BoundsLeft, 1<<30)
+			}
+			if tt.projection == ProjectionCubemap && s.CubemapPadding != 16 {
+				t.Errorf("padding = %d, want 16", s.CubemapPadding)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package fmp4parser
+
+// SubSampleKind is what a sub-sample of "subs" consists of. It's decided by the codec and
+// the flags of "subs", refer to ISO/IEC 14496-15.
+type SubSampleKind uint8
+
+const (
+	SubSampleBytes        SubSampleKind = iota // a byte range of the other codecs and flags, e.g. VP9 and AV1, Flags and CodecSpecificParameters are kept raw
+	SubSampleNalUnits                          // one or more contiguous NAL units, AVC and HEVC of flags 0
+	SubSampleDecodingUnit                      // exactly one decoding unit, HEVC of flags 1
+	SubSampleTile                              // one tile and the associated non-VCL NAL units, HEVC of flags 2
+	SubSampleCTURow                            // one CTU row of a tile and the associated non-VCL NAL units, HEVC of flags 3
+	SubSampleSlice                             // one slice and the associated non-VCL NAL units, HEVC of flags 4
+)
+
+// SubSample is a sub-sample of a packet from "subs". The sub-samples of a "subs" of a packet
+// are contiguous and in order, the sum of their sizes is the size of the packet.
+type SubSample struct {
+	Size                    uint32
+	Priority                uint8
+	Discardable             bool   // the sub-sample isn't required to decode the current sample
+	CodecSpecificParameters uint32 // raw codec_specific_parameters
+	Flags                   uint32 // flags of "subs", a track may have a "subs" for each flags
+	Kind                    SubSampleKind
+
+	// HEVC of flags 0, decoded from CodecSpecificParameters:
+	//	unsigned int(1) DiscardableFlag
+	//	unsigned int(1) NoInterLayerPredFlag
+	//	unsigned int(6) LayerId
+	//	unsigned int(3) TempId
+	DiscardableFlag      bool
+	NoInterLayerPredFlag bool
+	LayerID              uint8
+	TemporalID           uint8
+}
+
+// newSubSamples converts the sub-samples of "subs" of the codec.
+func newSubSamples(entry *subSampleEntry, flags uint32, codec CodecType) []SubSample {
+	kind := SubSampleBytes
+	switch codec {
+	case VideoCodecH264:
+		if flags == 0 {
+			kind = SubSampleNalUnits
+		}
+	case VideoCodecHEVC:
+		if flags <= 4 {
+			kind = []SubSampleKind{SubSampleNalUnits, SubSampleDecodingUnit, SubSampleTile, SubSampleCTURow, SubSampleSlice}[flags]
+		}
+	}
+	subSamples := make([]SubSample, 0, len(entry.subSamples))
+	for _, info := range entry.subSamples {
+		subSample := SubSample{
+			Size:                    info.subSampleSize,
+			Priority:                info.subSamplePriority,
+			Discardable:             info.discardable != 0,
+			CodecSpecificParameters: info.codecSpecificParameters,
+			Flags:                   flags,
+			Kind:                    kind,
+		}
+		if codec == VideoCodecHEVC && flags == 0 {
+			v := info.codecSpecificParameters
+			subSample.DiscardableFlag = v&0x80000000 != 0
+			subSample.NoInterLayerPredFlag = v&0x40000000 != 0
+			subSample.LayerID = uint8(v >> 24 & 0x3F)
+			subSample.TemporalID = uint8(v >> 21 & 0x07)
+		}
+		subSamples = append(subSamples, subSample)
+	}
+	return subSamples
+}
+
+// setSubSamples sets Packet.SubSamples and Packet.SubSamplesByFlags of the packets by "subs".
+// Each "subs" divides the samples in its own way, so they aren't merged. Packet.SubSamples is
+// the division of the lowest flags present for the sample, whichever "subs" comes first. The sample_delta of
+// the entries is the difference of the sample numbers, the first one is the sample number.
+func setSubSamples(packets []Packet, subs []*boxSubs, codec CodecType) {
+	for _, box := range subs {
+		sample := uint32(0)
+		for _, entry := range box.entries {
+			sample += entry.sampleDelta
+			if sample == 0 || int(sample) > len(packets) || len(entry.subSamples) == 0 {
+				continue
+			}
+			packet := &packets[sample-1]
+			if _, ok := packet.SubSamplesByFlags[box.flags]; ok {
+				continue // only one "subs" of the same flags is allowed
+			}
+			subSamples := newSubSamples(entry, box.flags, codec)
+			lowest := true
+			for flags := range packet.SubSamplesByFlags {
+				lowest = lowest && box.flags < flags
+			}
+			if lowest {
+				packet.SubSamples = subSamples
+			}
+			if packet.SubSamplesByFlags == nil {
+				packet.SubSamplesByFlags = make(map[uint32][]SubSample)
+			}
+			packet.SubSamplesByFlags[box.flags] = subSamples
+		}
+	}
+}
+
+// codec returns the codec of the track. CodecUNKNOW if it's neither audio nor video.
+func (p *boxTrak) codec() CodecType {
+	switch {
+	case p.videoEntry != nil:
+		return p.videoEntry.codec
+	case p.audioEntry != nil:
+		return p.audioEntry.codec
+	}
+	return CodecUNKNOW
+}
@@ -0,0 +1,70 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSubSamples(t *testing.T) {
+	entry := &subSampleEntry{subSampleCount: 1, subSamples: []*subSampleInfo{
+		{subSampleSize: 100, subSamplePriority: 2, discardable: 1, codecSpecificParameters: 0x80000000 | 1<<24 | 2<<21},
+	}}
+	got := newSubSamples(entry, 0, VideoCodecHEVC)
+	want := []SubSample{{Size: 100, Priority: 2, Discardable: true, CodecSpecificParameters: 0x81400000,
+		Kind: SubSampleNalUnits, DiscardableFlag: true, LayerID: 1, TemporalID: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newSubSamples() = %+v, want %+v", got, want)
+	}
+	if got := newSubSamples(entry, 2, VideoCodecHEVC); got[0].Kind != SubSampleTile || got[0].LayerID != 0 {
+		t.Errorf("unexpected tile-based sub-sample %+v", got[0])
+	}
+	if got := newSubSamples(entry, 0, VideoCodecAV1); got[0].Kind != SubSampleBytes {
+		t.Errorf("Kind = %d, want SubSampleBytes", got[0].Kind)
+	}
+}
+
+func TestParser_PacketSubSamples(t *testing.T) {
+	init := mkVideoInit(mkAvc1(1280, 720, 31))
+	// the first sample has 2 sub-samples, the second one has none and the third one has 1
+	subs := mkFullBox("subs", 0, 0, u32(3),
+		u32(1), u16(2), u16(60), u8(0), u8(0), u32(0), u16(40), u8(1), u8(1), u32(0),
+		u32(1), u16(0),
+		u32(1), u16(1), u16(100), u8(0), u8(0), u32(0))
+	// another division of the first sample, it isn't merged into SubSamples which is of the
+	// lowest flags even if the "subs" comes first
+	subs1 := mkFullBox("subs", 0, 1, u32(1), u32(1), u16(1), u16(100), u8(0), u8(0), u32(0))
+	// the only division of the second sample
+	subs2 := mkFullBox("subs", 0, 2, u32(1), u32(2), u16(1), u16(100), u8(0), u8(0), u32(0))
+	traf := mkBox("traf",
+		mkFullBox("tfhd", 0, 0x020000, u32(1)),
+		mkFullBox("trun", 0, 0x000200, u32(3), u32(100), u32(100), u32(100)),
+		subs1, subs, subs2)
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), traf)
+	file := append(append(init, moof...), mkBox("mdat", make([]byte, 300))...)
+
+	p := newTestParser(t, file)
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("got %d packets, want 3", len(packets))
+	}
+	want := [][]SubSample{
+		{{Size: 60, Kind: SubSampleNalUnits}, {Size: 40, Priority: 1, Discardable: true, Kind: SubSampleNalUnits}},
+		{{Size: 100, Flags: 2}},
+		{{Size: 100, Kind: SubSampleNalUnits}},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(packets[i].SubSamples, want[i]) {
+			t.Errorf("packet %d: SubSamples = %+v, want %+v", i, packets[i].SubSamples, want[i])
+		}
+	}
+	wantByFlags := map[uint32][]SubSample{0: want[0], 1: {{Size: 100, Flags: 1}}}
+	if !reflect.DeepEqual(packets[0].SubSamplesByFlags, wantByFlags) {
+		t.Errorf("SubSamplesByFlags = %+v, want %+v", packets[0].SubSamplesByFlags, wantByFlags)
+	}
+	if wantByFlags = map[uint32][]SubSample{2: want[1]}; !reflect.DeepEqual(packets[1].SubSamplesByFlags, wantByFlags) {
+		t.Errorf("SubSamplesByFlags of packet 1 = %+v, want %+v", packets[1].SubSamplesByFlags, wantByFlags)
+	}
+}
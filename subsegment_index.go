@@ -0,0 +1,144 @@
+package fmp4parser
+
+// LevelAssignment is an entry of "leva" box (ISO/IEC 14496-12 8.8.13).
+// It describes which samples are assigned to a level.
+type LevelAssignment struct {
+	Level                 uint8  // level number, the j-th entry of "leva" is the level j (starting from 1)
+	TrackID               uint32 // the track assigned to this level
+	PaddingFlag           bool   // if true, the level can be padded by zeros to form a conforming fraction
+	AssignmentType        uint8  // 0: sample group; 1: sample group with parameter; 2, 3: track; 4: sub-track
+	GroupingType          uint32 // if AssignmentType is 0 or 1
+	GroupingTypeParameter uint32 // if AssignmentType is 1
+	SubTrackID            uint32 // if AssignmentType is 4
+
+	Track *Track // the track of TrackID, nil if it isn't in the file
+	// the decoded entries of the "sgpd" of GroupingType in the track if AssignmentType is 0 or 1,
+	// see SampleGroup.Entry. nil if the track has no "sgpd" of the grouping type, e.g. the
+	// sample groups are described in the movie fragments only.
+	GroupDescriptions []interface{}
+}
+
+// LevelRange is a partial subsegment of the "ssix" box, i.e. a byte range which
+// contains the data of one level.
+type LevelRange struct {
+	Level  uint8
+	Offset uint64 // absolute position of the first byte
+	Size   uint32
+}
+
+// SubsegmentLevels contains the partial subsegments of a subsegment.
+type SubsegmentLevels struct {
+	Subsegment *SegmentReference
+	Ranges     []LevelRange
+}
+
+// SubsegmentIndex is the resolved form of a "ssix" box (ISO/IEC 14496-12 8.16.4). It maps
+// the subsegments of its "sidx" to the byte ranges of the levels defined in "leva".
+type SubsegmentIndex struct {
+	Index       *SegmentIndex     // the segment index which the "ssix" pertains to
+	Levels      []LevelAssignment // level assignments from "leva", empty if there is no "leva"
+	Subsegments []*SubsegmentLevels
+}
+
+// LevelAssignment returns the assignment of the level. nil if not found.
+func (p *SubsegmentIndex) LevelAssignment(level uint8) *LevelAssignment {
+	for i := range p.Levels {
+		if p.Levels[i].Level == level {
+			return &p.Levels[i]
+		}
+	}
+	return nil
+}
+
+// LevelByteRanges returns the byte ranges needed to access the level. As the data of
+// a level depends on the lower levels only, all the ranges whose level is not greater
+// than the level are returned. Adjacent ranges are merged.
+// E.g. if level 1 is assigned to the I-frames, LevelByteRanges(1) are the ranges for trick-play.
+func (p *SubsegmentIndex) LevelByteRanges(level uint8) []LevelRange {
+	var ranges []LevelRange
+	for _, sub := range p.Subsegments {
+		for _, r := range sub.Ranges {
+			if r.Level > level || r.Size == 0 {
+				continue
+			}
+			if n := len(ranges); n > 0 && ranges[n-1].Offset+uint64(ranges[n-1].Size) == r.Offset {
+				ranges[n-1].Size += r.Size
+				continue
+			}
+			ranges = append(ranges, LevelRange{Level: level, Offset: r.Offset, Size: r.Size})
+		}
+	}
+	return ranges
+}
+
+// SubsegmentIndexes returns all the "ssix" in the file resolved with its "sidx" and "leva".
+// It must be called after Parse.
+func (p *Parser) SubsegmentIndexes() ([]*SubsegmentIndex, error) {
+	movie := p.m.movie
+	if movie == nil || len(movie.ssix) == 0 {
+		return nil, ErrNoSubsegmentIndex
+	}
+	var levels []LevelAssignment
+	if movie.mvex != nil && movie.mvex.leva != nil {
+		for i, l := range movie.mvex.leva.levels {
+			level := LevelAssignment{
+				Level:                 uint8(i + 1),
+				TrackID:               l.trackId,
+				PaddingFlag:           l.paddingFlag == 1,
+				AssignmentType:        l.assignmentType,
+				GroupingType:          l.groupingType,
+				GroupingTypeParameter: l.groupingTypeParameter,
+				SubTrackID:            l.subTrackId,
+			}
+			if trak := movie.trakOf(l.trackId); trak != nil {
+				track := trak.newTrack()
+				level.Track = &track
+				if sgpd := findSgpd(trak.sgpd, l.groupingType); sgpd != nil && l.assignmentType <= 1 {
+					level.GroupDescriptions = sgpd.entries
+				}
+			}
+			levels = append(levels, level)
+		}
+	}
+	var indexes []*SubsegmentIndex
+	for _, ssix := range movie.ssix {
+		if ssix.sidx == nil {
+			logW.Println("ssix box without sidx box is ignored")
+			continue
+		}
+		index := &SubsegmentIndex{Index: ssix.sidx.resolve(movie.sidx, 0), Levels: levels}
+		if int(ssix.subSegmentCount) != len(index.Index.References) {
+			logW.Printf("subsegment count of ssix is %d, but the reference count of sidx is %d",
+				ssix.subSegmentCount, len(index.Index.References))
+		}
+		for i := 0; i < len(ssix.ranges) && i < len(index.Index.References); i++ {
+			ref := index.Index.References[i]
+			sub := &SubsegmentLevels{Subsegment: ref}
+			offset := ref.Offset
+			for _, r := range ssix.ranges[i].rangeSize {
+				sub.Ranges = append(sub.Ranges, LevelRange{Level: r.level, Offset: offset, Size: r.size})
+				offset += uint64(r.size)
+			}
+			index.Subsegments = append(index.Subsegments, sub)
+		}
+		indexes = append(indexes, index)
+	}
+	if len(indexes) == 0 {
+		return nil, ErrNoSubsegmentIndex
+	}
+	return indexes, nil
+}
+
+// LevelByteRanges returns the byte ranges of the level in all "ssix" of the file.
+// See SubsegmentIndex.LevelByteRanges.
+func (p *Parser) LevelByteRanges(level uint8) ([]LevelRange, error) {
+	indexes, err := p.SubsegmentIndexes()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []LevelRange
+	for _, index := range indexes {
+		ranges = append(ranges, index.LevelByteRanges(level)...)
+	}
+	return ranges, nil
+}
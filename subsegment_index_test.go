@@ -0,0 +1,121 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParser_LevelByteRanges(t *testing.T) {
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	leva := mkFullBox("leva", 0, 0, u8(2),
+		u32(1), u8(0), []byte("sap "), // level 1: sample group "sap "
+		u32(1), u8(2)) // level 2: the whole track
+	moov := mkBox("moov", mkBox("mvex", leva))
+	mdat1 := mkBox("mdat", make([]byte, 92))
+	mdat2 := mkBox("mdat", make([]byte, 42))
+	ssix := mkFullBox("ssix", 0, 0, u32(2),
+		u32(2), u32(1<<24|40), u32(2<<24|60),
+		u32(2), u32(1<<24|20), u32(2<<24|30))
+	// the first_offset of "sidx" skips the "ssix"
+	sidx := mkSidx(0, uint32(len(ssix)),
+		testSidxRef{size: uint32(len(mdat1)), duration: 1000, sapType: 1},
+		testSidxRef{size: uint32(len(mdat2)), duration: 1000, sapType: 1})
+	file := bytes.Join([][]byte{ftyp, moov, sidx, ssix, mdat1, mdat2}, nil)
+
+	p := newTestParser(t, file)
+	indexes, err := p.SubsegmentIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 1 || len(indexes[0].Subsegments) != 2 {
+		t.Fatal("ssix is not resolved")
+	}
+	if l := indexes[0].LevelAssignment(1); l == nil || l.AssignmentType != 0 || int2String(l.GroupingType) != "sap " {
+		t.Fatalf("unexpected assignment of level 1: %+v", l)
+	}
+	if l := indexes[0].LevelAssignment(2); l == nil || l.AssignmentType != 2 || l.TrackID != 1 {
+		t.Fatalf("unexpected assignment of level 2: %+v", l)
+	}
+
+	mdat1Pos := uint64(len(ftyp) + len(moov) + len(sidx) + len(ssix))
+	mdat2Pos := mdat1Pos + uint64(len(mdat1))
+	ranges, err := p.LevelByteRanges(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LevelRange{{1, mdat1Pos, 40}, {1, mdat2Pos, 20}}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expect %d ranges, got %d", len(expected), len(ranges))
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("range %d: expect %+v, got %+v", i, expected[i], ranges[i])
+		}
+	}
+	// level 2 contains level 1, the adjacent ranges are merged
+	ranges, _ = p.LevelByteRanges(2)
+	if len(ranges) != 1 || ranges[0].Offset != mdat1Pos || ranges[0].Size != 150 {
+		t.Errorf("unexpected ranges of level 2: %+v", ranges)
+	}
+}
+
+func TestParser_LevelAssignments(t *testing.T) {
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(0), make([]byte, 52), u32(0), u32(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+	stbl := mkBox("stbl",
+		mkFullBox("stsd", 0, 0, u32(0)),
+		mkFullBox("stts", 0, 0, u32(0)),
+		mkFullBox("sgpd", 1, 0, []byte("tele"), u32(1), u32(2), u8(0x80), u8(0)),
+		mkFullBox("sgpd", 1, 0, []byte("sap "), u32(1), u32(1), u8(0x81)))
+	trak := mkBox("trak", tkhd, mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl)))
+	leva := mkFullBox("leva", 0, 0, u8(6),
+		u32(1), u8(0), []byte("tele"), // level 1: sample group "tele"
+		u32(1), u8(1), []byte("sap "), u32(7), // level 2: sample group "sap " of the parameter 7
+		u32(1), u8(0x80|2), // level 3: the whole track, padded
+		u32(1), u8(3), // level 4: the whole track
+		u32(1), u8(4), u32(5), // level 5: the sub-track 5
+		u32(2), u8(0), []byte("tele")) // level 6: a track not in the file
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(1), u32(40), u32(0), u32(0))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak, mkBox("mvex", trex, leva))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	ssix := mkFullBox("ssix", 0, 0, u32(1), u32(1), u32(1<<24|8))
+	sidx := mkSidx(0, uint32(len(ssix)), testSidxRef{size: 8, duration: 1000, sapType: 1})
+	file := bytes.Join([][]byte{ftyp, moov, sidx, ssix, mkBox("mdat")}, nil)
+
+	p := newTestParser(t, file)
+	indexes, err := p.SubsegmentIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := indexes[0].Levels
+	if len(levels) != 6 {
+		t.Fatalf("got %d levels, want 6", len(levels))
+	}
+	for i, l := range levels[:5] {
+		if l.Track == nil || l.Track.TrackID != 1 || l.Track.Type != VideoTrack {
+			t.Errorf("level %d: Track = %+v, want the video track 1", i+1, l.Track)
+		}
+	}
+	tele := []interface{}{&TemporalLevelEntry{LevelIndependentlyDecodable: true}, &TemporalLevelEntry{}}
+	if l := levels[0]; int2String(l.GroupingType) != "tele" || !reflect.DeepEqual(l.GroupDescriptions, tele) {
+		t.Errorf("level 1 = %+v, want the sample group tele", l)
+	}
+	sap := []interface{}{&SAPEntry{DependentFlag: true, SAPType: 1}}
+	if l := levels[1]; int2String(l.GroupingType) != "sap " || l.GroupingTypeParameter != 7 || !reflect.DeepEqual(l.GroupDescriptions, sap) {
+		t.Errorf("level 2 = %+v, want the sample group sap of the parameter 7", l)
+	}
+	if l := levels[2]; l.AssignmentType != 2 || !l.PaddingFlag || l.GroupDescriptions != nil {
+		t.Errorf("level 3 = %+v, want the padded track", l)
+	}
+	if l := levels[3]; l.AssignmentType != 3 || l.PaddingFlag || l.GroupDescriptions != nil {
+		t.Errorf("level 4 = %+v, want the track", l)
+	}
+	if l := levels[4]; l.AssignmentType != 4 || l.SubTrackID != 5 || l.GroupDescriptions != nil {
+		t.Errorf("level 5 = %+v, want the sub-track 5", l)
+	}
+	if l := levels[5]; l.TrackID != 2 || l.Track != nil || l.GroupDescriptions != nil {
+		t.Errorf("level 6 = %+v, want no track", l)
+	}
+}
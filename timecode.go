@@ -0,0 +1,215 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// the flags of TimecodeSampleEntry
+const (
+	TimecodeDropFrame       uint32 = 0x0001 // the timecode drops some frame numbers, e.g. 29.97 fps
+	TimecodeMax24Hour       uint32 = 0x0002 // the timecode wraps at 24 hours
+	TimecodeNegativeTimesOK uint32 = 0x0004 // the timecode may be negative
+	TimecodeCounter         uint32 = 0x0008 // the sample is a tape counter instead of a frame number
+)
+
+// TimecodeSampleEntry is the "tmcd" sample description of a QuickTime timecode track.
+// The only sample of the track is the frame number of the first frame of the referring tracks.
+type TimecodeSampleEntry struct {
+	Flags          uint32 // TimecodeDropFrame, TimecodeMax24Hour, TimecodeNegativeTimesOK and TimecodeCounter
+	TimeScale      uint32
+	FrameDuration  uint32 // in TimeScale, e.g. 1001 of 30000 for 29.97 fps
+	NumberOfFrames uint8  // frames per second, or frames per tick of the counter
+	SourceName     string // "name" of the sample description, e.g. the tape reel name
+}
+
+// DropFrame reports whether the timecode is drop-frame.
+func (p *TimecodeSampleEntry) DropFrame() bool {
+	return p.Flags&TimecodeDropFrame != 0
+}
+
+// Timecode is a SMPTE timecode.
+type Timecode struct {
+	Hours     int
+	Minutes   int
+	Seconds   int
+	Frames    int
+	DropFrame bool
+	Negative  bool
+}
+
+// String returns the timecode in HH:MM:SS:FF, the separator of the frames is ';' if the
+// timecode is drop-frame.
+func (p Timecode) String() string {
+	sign, separator := "", ':'
+	if p.Negative {
+		sign = "-"
+	}
+	if p.DropFrame {
+		separator = ';'
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d%c%02d", sign, p.Hours, p.Minutes, p.Seconds, separator, p.Frames)
+}
+
+// timecodeOf returns the timecode of the frame number.
+func (p *TimecodeSampleEntry) timecodeOf(frame int64) Timecode {
+	tc := Timecode{DropFrame: p.DropFrame()}
+	if frame < 0 {
+		tc.Negative = true
+		frame = -frame
+	}
+	fps := int64(p.NumberOfFrames)
+	if fps == 0 {
+		return tc
+	}
+	if tc.DropFrame && fps >= 30 {
+		// the frame numbers 0 and 1 (0 to 3 of 59.94 fps) are dropped at the start of each
+		// minute, except every tenth minute
+		drop := fps / 15
+		framesPerMinute := fps*60 - drop
+		framesPer10Minutes := fps*600 - drop*9
+		tens, rest := frame/framesPer10Minutes, frame%framesPer10Minutes
+		frame += drop * 9 * tens
+		if rest > drop {
+			frame += drop * ((rest - drop) / framesPerMinute)
+		}
+	}
+	tc.Frames = int(frame % fps)
+	tc.Seconds = int(frame / fps % 60)
+	tc.Minutes = int(frame / (fps * 60) % 60)
+	tc.Hours = int(frame / (fps * 3600))
+	if p.Flags&TimecodeMax24Hour != 0 {
+		tc.Hours %= 24
+	}
+	return tc
+}
+
+// frameNumberOf returns the frame number of the timecode sample.
+func (p *TimecodeSampleEntry) frameNumberOf(sample []byte) int64 {
+	value := binary.BigEndian.Uint32(sample)
+	frame := int64(value)
+	if p.Flags&TimecodeNegativeTimesOK != 0 {
+		frame = int64(int32(value))
+	}
+	if p.Flags&TimecodeCounter != 0 {
+		frame *= int64(p.NumberOfFrames)
+	}
+	return frame
+}
+
+// parseTimecodeSampleEntry parses the "tmcd" sample description
+func (p *boxTrak) parseTimecodeSampleEntry(r *atomReader) error {
+	if r.Size() < 26 {
+		return ErrInvalidSampleDescription
+	}
+	_ = r.Move(8) // 6-bytes reserved + 2-bytes data_reference_index
+	_ = r.Move(4) // reserved
+	entry := new(TimecodeSampleEntry)
+	entry.Flags = r.Read4()
+	entry.TimeScale = r.Read4()
+	entry.FrameDuration = r.Read4()
+	entry.NumberOfFrames = r.ReadUnsignedByte()
+	_ = r.Move(1) // reserved
+	// the source reference: the size of the string, the language code and the string
+	if name, err := r.FindSubAtom(fourCCname); err == nil && name != nil && name.Size() >= 4 {
+		size := int(name.Read2())
+		_ = name.Move(2)
+		if size > name.Len() {
+			size = name.Len()
+		}
+		sourceName := make([]byte, size)
+		_, _ = name.ReadBytes(sourceName)
+		entry.SourceName = string(sourceName)
+	}
+	if p.timecodeEntry == nil {
+		p.timecodeEntry = entry
+	}
+	p.sampleEntries = append(p.sampleEntries, &sampleEntry{format: r.TypeCC()})
+	return nil
+}
+
+// timecodeTrakOf returns the timecode track of the track: the track itself or the track
+// referred by "tmcd" of "tref".
+func (p *MovieInfo) timecodeTrakOf(trak *boxTrak) *boxTrak {
+	if trak.timecodeEntry != nil {
+		return trak
+	}
+	for _, ref := range trak.tref {
+		if ref.Type != TrackReferenceTimecode {
+			continue
+		}
+		for _, id := range ref.TrackIDs {
+			if tmcd := p.trakOf(id); tmcd != nil && tmcd.timecodeEntry != nil {
+				return tmcd
+			}
+		}
+	}
+	return nil
+}
+
+// startFrameOf reads the timecode sample of the timecode track, it returns the frame number
+// and the presentation time of the sample in seconds.
+func (p *Parser) startFrameOf(tmcd *boxTrak) (int64, float64, error) {
+	packets := p.m.packetsOf(tmcd)
+	if len(packets) == 0 || packets[0].Size < 4 {
+		return 0, 0, ErrNoTimecode
+	}
+	if err := p.ReadPacket(&packets[0]); err != nil {
+		return 0, 0, err
+	}
+	start := 0.0
+	if tmcd.timeScale != 0 {
+		start = float64(packets[0].PTS) / float64(tmcd.timeScale)
+	}
+	return tmcd.timecodeEntry.frameNumberOf(packets[0].Data), start, nil
+}
+
+// StartTimecode returns the timecode of the first frame of the track. The track is either a
+// timecode track or the track which refers to a timecode track by "tmcd" of "tref". It must
+// be called after Parse.
+func (p *Parser) StartTimecode(trackID uint32) (Timecode, error) {
+	if p.m.movie == nil {
+		return Timecode{}, ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return Timecode{}, ErrNotFoundTrack
+	}
+	tmcd := p.m.movie.timecodeTrakOf(trak)
+	if tmcd == nil {
+		return Timecode{}, ErrNoTimecode
+	}
+	frame, _, err := p.startFrameOf(tmcd)
+	if err != nil {
+		return Timecode{}, err
+	}
+	return tmcd.timecodeEntry.timecodeOf(frame), nil
+}
+
+// PacketTimecode returns the timecode of the packet of the track by the presentation time
+// of the packet, i.e. Packet.PTS of Packets. It must be called after Parse.
+func (p *Parser) PacketTimecode(trackID uint32, packet *Packet) (Timecode, error) {
+	if p.m.movie == nil {
+		return Timecode{}, ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil || trak.timeScale == 0 {
+		return Timecode{}, ErrNotFoundTrack
+	}
+	tmcd := p.m.movie.timecodeTrakOf(trak)
+	if tmcd == nil {
+		return Timecode{}, ErrNoTimecode
+	}
+	frame, start, err := p.startFrameOf(tmcd)
+	if err != nil {
+		return Timecode{}, err
+	}
+	entry := tmcd.timecodeEntry
+	if entry.FrameDuration != 0 {
+		elapsed := float64(packet.PTS)/float64(trak.timeScale) - start
+		// a tiny tolerance, so that the frames exactly on the boundary aren't rounded down
+		frame += int64(math.Floor(elapsed*float64(entry.TimeScale)/float64(entry.FrameDuration) + 1e-6))
+	}
+	return entry.timecodeOf(frame), nil
+}
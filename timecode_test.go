@@ -0,0 +1,74 @@
+package fmp4parser
+
+import (
+	"testing"
+)
+
+func TestTimecodeSampleEntry_timecodeOf(t *testing.T) {
+	dropFrame := &TimecodeSampleEntry{Flags: TimecodeDropFrame, TimeScale: 30000, FrameDuration: 1001, NumberOfFrames: 30}
+	nonDropFrame := &TimecodeSampleEntry{Flags: TimecodeMax24Hour, TimeScale: 25, FrameDuration: 1, NumberOfFrames: 25}
+	tests := []struct {
+		entry *TimecodeSampleEntry
+		frame int64
+		want  string
+	}{
+		{dropFrame, 0, "00:00:00;00"},
+		{dropFrame, 1799, "00:00:59;29"},
+		{dropFrame, 1800, "00:01:00;02"},
+		{dropFrame, 17982, "00:10:00;00"},
+		{dropFrame, 107892, "01:00:00;00"},
+		{nonDropFrame, 90000 + 25*61 + 3, "01:01:01:03"},
+		{nonDropFrame, 25 * 3600 * 25, "01:00:00:00"},
+		{nonDropFrame, -26, "-00:00:01:01"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.timecodeOf(tt.frame).String(); got != tt.want {
+			t.Errorf("timecodeOf(%d) = %s, want %s", tt.frame, got, tt.want)
+		}
+	}
+}
+
+func TestParser_StartTimecode(t *testing.T) {
+	tmcdEntry := mkBox("tmcd", make([]byte, 6), u16(1), u32(0), u32(TimecodeDropFrame), u32(30000), u32(1001), u8(30), u8(0),
+		mkBox("name", u16(6), u16(0), []byte("REEL01")))
+	moov := func(offset uint32) []byte {
+		video := mkQuickTimeTrakOf(1, 30000, "vide", [][]byte{mkBox("tref", mkBox("tmcd", u32(2)))}, mkBox("stbl",
+			mkFullBox("stsd", 0, 0, u32(1), mkVideoEntry("apch", 1920, 1080)),
+			mkFullBox("stts", 0, 0, u32(1), u32(3), u32(1001)),
+			mkFullBox("stsc", 0, 0, u32(1), u32(1), u32(3), u32(1)),
+			mkFullBox("stsz", 0, 0, u32(4), u32(3)),
+			mkFullBox("stco", 0, 0, u32(1), u32(offset))))
+		tmcd := mkQuickTimeTrakOf(2, 30000, "tmcd", nil, mkBox("stbl",
+			mkFullBox("stsd", 0, 0, u32(1), tmcdEntry),
+			mkFullBox("stts", 0, 0, u32(1), u32(1), u32(3003)),
+			mkFullBox("stsc", 0, 0, u32(1), u32(1), u32(1), u32(1)),
+			mkFullBox("stsz", 0, 0, u32(4), u32(1)),
+			mkFullBox("stco", 0, 0, u32(1), u32(offset+12))))
+		return mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(1000), u32(100), make([]byte, 80)), video, tmcd)
+	}
+	ftyp := mkBox("ftyp", []byte("qt  "), u32(0), []byte("qt  "))
+	offset := uint32(len(ftyp) + len(moov(0)) + 8)
+	// 01:00:00;00 of 29.97 fps drop-frame
+	file := append(append(ftyp, moov(offset)...), mkBox("mdat", make([]byte, 12), u32(107892))...)
+
+	p := newTestParser(t, file)
+	track := p.GetTracks()[1]
+	if track.Type != TimecodeTrack || track.Timecode == nil || !track.Timecode.DropFrame() || track.Timecode.SourceName != "REEL01" {
+		t.Fatalf("timecode track = %v %+v", track.Type, track.Timecode)
+	}
+	for _, id := range []uint32{1, 2} {
+		if tc, err := p.StartTimecode(id); err != nil || tc.String() != "01:00:00;00" {
+			t.Errorf("StartTimecode(%d) = %v, %v, want 01:00:00;00", id, tc, err)
+		}
+	}
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil || len(packets) != 3 {
+		t.Fatalf("Packets() = %d packets, %v", len(packets), err)
+	}
+	if tc, err := p.PacketTimecode(1, &packets[2]); err != nil || tc.String() != "01:00:00;02" {
+		t.Errorf("PacketTimecode() = %v, %v, want 01:00:00;02", tc, err)
+	}
+	if _, err := p.StartTimecode(3); err != ErrNotFoundTrack {
+		t.Errorf("StartTimecode() of unknown track = %v, want ErrNotFoundTrack", err)
+	}
+}
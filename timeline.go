@@ -0,0 +1,298 @@
+package fmp4parser
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Edit is an entry of the edit list "elst". The times are in the time scale of the track,
+// the duration of "elst" is converted from the time scale of the movie.
+type Edit struct {
+	PresentationTime uint64  // the start of the edit on the presentation timeline
+	Duration         uint64  // 0 of the last edit means that the edit lasts to the end of the media
+	MediaTime        int64   // the start of the edit in the media (composition time), -1 for an empty edit
+	MediaRate        float64 // 1 for the normal playback, 0 for a dwell edit which holds MediaTime
+}
+
+// IsEmpty returns whether nothing of the media is presented during the edit.
+func (p *Edit) IsEmpty() bool {
+	return p.MediaTime == -1
+}
+
+// IsDwell returns whether the media at MediaTime is held during the edit.
+func (p *Edit) IsDwell() bool {
+	return p.MediaTime != -1 && p.MediaRate == 0
+}
+
+// mediaEnd returns the end of the media presented by the edit, exclusive. The media of the
+// unbounded edit ends at math.MaxInt64.
+func (p *Edit) mediaEnd(unbounded bool) int64 {
+	switch {
+	case unbounded:
+		return math.MaxInt64
+	case p.MediaRate == 1:
+		return p.MediaTime + int64(p.Duration)
+	default:
+		return p.MediaTime + int64(float64(p.Duration)*p.MediaRate)
+	}
+}
+
+// Timeline is the presentation timeline of a track. The edits are in the presentation order.
+// Without edit list, the presentation time is the composition time of the media.
+type Timeline struct {
+	TimeScale uint32 // the time scale of the track
+	Edits     []Edit
+}
+
+// unbounded returns whether the i-th edit lasts to the end of the media.
+func (p *Timeline) unbounded(i int) bool {
+	return i == len(p.Edits)-1 && p.Edits[i].Duration == 0
+}
+
+// Duration returns the duration of the presentation. 0 if there is no edit list or the
+// last edit is unbounded.
+func (p *Timeline) Duration() uint64 {
+	if len(p.Edits) == 0 || p.unbounded(len(p.Edits)-1) {
+		return 0
+	}
+	last := p.Edits[len(p.Edits)-1]
+	return last.PresentationTime + last.Duration
+}
+
+// MediaToPresentation maps the composition time of the media to the presentation time. If the
+// media is presented more than once, the first one is returned. false if it's not presented.
+func (p *Timeline) MediaToPresentation(mediaTime int64) (uint64, bool) {
+	if len(p.Edits) == 0 {
+		return uint64(mediaTime), mediaTime >= 0
+	}
+	for i := range p.Edits {
+		e := &p.Edits[i]
+		switch {
+		case e.IsEmpty():
+			continue
+		case e.IsDwell():
+			if mediaTime == e.MediaTime {
+				return e.PresentationTime, true
+			}
+		case mediaTime >= e.MediaTime && mediaTime < e.mediaEnd(p.unbounded(i)):
+			offset := uint64(mediaTime - e.MediaTime)
+			if e.MediaRate != 1 {
+				offset = uint64(float64(offset) / e.MediaRate)
+			}
+			return e.PresentationTime + offset, true
+		}
+	}
+	return 0, false
+}
+
+// presentationTimeOf returns the presentation time of the composition time of the media.
+// The media which isn't presented, e.g. the priming samples before the first edit, is
+// extrapolated from the nearest edit, so the result is negative before the presentation.
+func (p *Timeline) presentationTimeOf(mediaTime int64) int64 {
+	if pts, ok := p.MediaToPresentation(mediaTime); ok {
+		return int64(pts)
+	}
+	var nearest *Edit
+	distance := int64(math.MaxInt64)
+	for i := range p.Edits {
+		e := &p.Edits[i]
+		if e.IsEmpty() {
+			continue
+		}
+		d := e.MediaTime - mediaTime
+		if d < 0 {
+			d = mediaTime - e.mediaEnd(p.unbounded(i))
+		}
+		if d < distance {
+			nearest, distance = e, d
+		}
+	}
+	if nearest == nil {
+		return mediaTime
+	}
+	offset := mediaTime - nearest.MediaTime
+	if nearest.MediaRate != 0 && nearest.MediaRate != 1 {
+		offset = int64(float64(offset) / nearest.MediaRate)
+	}
+	return int64(nearest.PresentationTime) + offset
+}
+
+// PresentationToMedia maps the presentation time to the composition time of the media.
+// false if the time is in an empty edit or beyond the presentation.
+func (p *Timeline) PresentationToMedia(presentationTime uint64) (int64, bool) {
+	if len(p.Edits) == 0 {
+		return int64(presentationTime), true
+	}
+	for i := range p.Edits {
+		e := &p.Edits[i]
+		if presentationTime < e.PresentationTime ||
+			(!p.unbounded(i) && presentationTime-e.PresentationTime >= e.Duration) {
+			continue
+		}
+		switch {
+		case e.IsEmpty():
+			return 0, false
+		case e.IsDwell():
+			return e.MediaTime, true
+		case e.MediaRate == 1:
+			return e.MediaTime + int64(presentationTime-e.PresentationTime), true
+		default:
+			return e.MediaTime + int64(float64(presentationTime-e.PresentationTime)*e.MediaRate), true
+		}
+	}
+	return 0, false
+}
+
+// IsPresented returns whether any part of the sample [compositionTime, compositionTime+duration)
+// is presented.
+func (p *Timeline) IsPresented(compositionTime int64, duration uint32) bool {
+	if len(p.Edits) == 0 {
+		return true
+	}
+	end := compositionTime + int64(duration)
+	if duration == 0 {
+		end++
+	}
+	for i := range p.Edits {
+		e := &p.Edits[i]
+		switch {
+		case e.IsEmpty():
+			continue
+		case e.IsDwell():
+			if e.MediaTime >= compositionTime && e.MediaTime < end {
+				return true
+			}
+		case compositionTime < e.mediaEnd(p.unbounded(i)) && end > e.MediaTime:
+			return true
+		}
+	}
+	return false
+}
+
+// rescaleTime converts the time from the time scale "from" to the time scale "to" without overflow.
+func rescaleTime(t uint64, to, from uint32) uint64 {
+	if to == from || from == 0 {
+		return t
+	}
+	hi, lo := bits.Mul64(t, uint64(to))
+	if hi >= uint64(from) {
+		return math.MaxUint64
+	}
+	q, _ := bits.Div64(hi, lo, uint64(from))
+	return q
+}
+
+// timeline builds the presentation timeline of the track from "elst".
+func (p *boxTrak) timeline() *Timeline {
+	t := &Timeline{TimeScale: p.timeScale}
+	if p.edts == nil {
+		return t
+	}
+	movieTimeScale := p.timeScale
+	if p.movie != nil && p.movie.timeScale != 0 {
+		movieTimeScale = p.movie.timeScale
+	}
+	presentationTime := uint64(0)
+	for i := uint32(0); i < p.edts.entryCount; i++ {
+		e := Edit{
+			PresentationTime: presentationTime,
+			Duration:         rescaleTime(p.edts.editDuration[i], p.timeScale, movieTimeScale),
+			MediaTime:        p.edts.mediaTime[i],
+			MediaRate:        p.edts.mediaRate[i],
+		}
+		if e.MediaTime < -1 {
+			// invalid, take it as an empty edit
+			e.MediaTime = -1
+		}
+		t.Edits = append(t.Edits, e)
+		presentationTime += e.Duration
+	}
+	return t
+}
+
+// sampleTime is the timing of a sample on the media timeline.
+type sampleTime struct {
+	decodeTime      int64
+	compositionTime int64
+	duration        uint32
+}
+
+// sampleTimes returns the timing of all the samples of the track: the samples of "stbl"
+// first, then the samples of the movie fragments in file order.
+func (p *mediaInfo) sampleTimes(trak *boxTrak) []sampleTime {
+	var times []sampleTime
+	decodeTime := int64(0)
+	if trak.stts != nil {
+		for i := uint32(0); i < trak.stts.entryCount; i++ {
+			for j := uint32(0); j < trak.stts.sampleCount[i]; j++ {
+				times = append(times, sampleTime{decodeTime: decodeTime, compositionTime: decodeTime, duration: trak.stts.sampleDelta[i]})
+				decodeTime += int64(trak.stts.sampleDelta[i])
+			}
+		}
+	}
+	if trak.ctts != nil {
+		n := 0
+		for i := uint32(0); i < trak.ctts.entryCount; i++ {
+			for j := uint32(0); j < trak.ctts.sampleCount[i] && n < len(times); j++ {
+				times[n].compositionTime += int64(trak.ctts.sampleOffset[i])
+				n++
+			}
+		}
+	}
+	for _, moof := range p.fragments {
+		traf := moof.trackFragment(trak.id)
+		if traf == nil {
+			continue
+		}
+		if traf.baseMediaDecodeTime != nil {
+			decodeTime = int64(*traf.baseMediaDecodeTime)
+		}
+		defaultDuration := traf.defaultSampleDurationOf()
+		for _, trun := range traf.trun {
+			for _, sample := range trun.samples {
+				s := sampleTime{decodeTime: decodeTime, compositionTime: decodeTime, duration: defaultDuration}
+				if sample.sampleDuration != nil {
+					s.duration = *sample.sampleDuration
+				}
+				if sample.sampleCompositionTimeOffset != nil {
+					s.compositionTime += int64(*sample.sampleCompositionTimeOffset)
+				}
+				times = append(times, s)
+				decodeTime += int64(s.duration)
+			}
+		}
+	}
+	return times
+}
+
+// trakOf returns the "trak" of the track. nil if not found.
+func (p *MovieInfo) trakOf(trackID uint32) *boxTrak {
+	for _, trak := range p.trak {
+		if trak.id == trackID {
+			return trak
+		}
+	}
+	return nil
+}
+
+// SamplesOutsidePresentation returns the 1-based numbers of the samples of the track which
+// aren't presented because of the edit list, e.g. the priming samples of audio or the
+// trimmed frames of video. The samples of the movie fragments follow the samples of "stbl".
+// It must be called after Parse.
+func (p *Parser) SamplesOutsidePresentation(trackID uint32) ([]uint64, error) {
+	if p.m.movie == nil {
+		return nil, ErrMoovNotParsed
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return nil, ErrNotFoundTrack
+	}
+	timeline := trak.timeline()
+	var numbers []uint64
+	for i, s := range p.m.sampleTimes(trak) {
+		if !timeline.IsPresented(s.compositionTime, s.duration) {
+			numbers = append(numbers, uint64(i+1))
+		}
+	}
+	return numbers, nil
+}
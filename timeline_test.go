@@ -0,0 +1,155 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTimeline_Map(t *testing.T) {
+	// 100 empty, media [200, 300), hold media 500 for 50, media [0, 100) at double speed
+	timeline := &Timeline{TimeScale: 1000, Edits: []Edit{
+		{PresentationTime: 0, Duration: 100, MediaTime: -1, MediaRate: 1},
+		{PresentationTime: 100, Duration: 100, MediaTime: 200, MediaRate: 1},
+		{PresentationTime: 200, Duration: 50, MediaTime: 500, MediaRate: 0},
+		{PresentationTime: 250, Duration: 50, MediaTime: 0, MediaRate: 2},
+	}}
+	if d := timeline.Duration(); d != 300 {
+		t.Errorf("Duration() = %d, want 300", d)
+	}
+	mediaTests := []struct {
+		media int64
+		pt    uint64
+		ok    bool
+	}{
+		{200, 100, true},
+		{299, 199, true},
+		{300, 0, false},
+		{500, 200, true},
+		{40, 270, true},
+		{100, 0, false},
+		{-10, 0, false},
+	}
+	for _, tt := range mediaTests {
+		if pt, ok := timeline.MediaToPresentation(tt.media); pt != tt.pt || ok != tt.ok {
+			t.Errorf("MediaToPresentation(%d) = %d, %v, want %d, %v", tt.media, pt, ok, tt.pt, tt.ok)
+		}
+	}
+	presentationTests := []struct {
+		pt    uint64
+		media int64
+		ok    bool
+	}{
+		{50, 0, false},
+		{100, 200, true},
+		{220, 500, true},
+		{260, 20, true},
+		{300, 0, false},
+	}
+	for _, tt := range presentationTests {
+		if media, ok := timeline.PresentationToMedia(tt.pt); media != tt.media || ok != tt.ok {
+			t.Errorf("PresentationToMedia(%d) = %d, %v, want %d, %v", tt.pt, media, ok, tt.media, tt.ok)
+		}
+	}
+	presentedTests := []struct {
+		cts      int64
+		duration uint32
+		want     bool
+	}{
+		{150, 40, false},
+		{180, 40, true},
+		{300, 40, false},
+		{480, 40, true}, // dwell
+		{520, 40, false},
+		{80, 40, true},
+		{100, 40, false},
+	}
+	for _, tt := range presentedTests {
+		if got := timeline.IsPresented(tt.cts, tt.duration); got != tt.want {
+			t.Errorf("IsPresented(%d, %d) = %v, want %v", tt.cts, tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestRescaleTime(t *testing.T) {
+	if got := rescaleTime(1<<50, 48000, 600); got != (1<<50)*80 {
+		t.Errorf("rescaleTime() = %d", got)
+	}
+	if got := rescaleTime(300, 1000, 600); got != 500 {
+		t.Errorf("rescaleTime() = %d, want 500", got)
+	}
+}
+
+func TestParser_SamplesOutsidePresentation(t *testing.T) {
+	// the movie time scale is 600, the track time scale is 1000
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(4000), make([]byte, 52), u32(0), u32(0))
+	elst := mkFullBox("elst", 0, 0, u32(2),
+		u32(300), u32(0xFFFFFFFF), u16(1), u16(0), // empty edit, 500 in the track time scale
+		u32(0), u32(50), u16(1), u16(0)) // to the end of the media
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("soun"), make([]byte, 12), []byte("audio\x00"))
+	trak := mkBox("trak", tkhd, mkBox("edts", elst), mkBox("mdia", mdhd, hdlr))
+	trex := mkFullBox("trex", 0, 0, u32(1), u32(1), u32(40), u32(0), u32(0))
+	mvhd := mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(600), u32(0), make([]byte, 80))
+	moov := mkBox("moov", mvhd, trak, mkBox("mvex", trex))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	tfhd := mkFullBox("tfhd", 0, 0x020000, u32(1))
+	tfdt := mkFullBox("tfdt", 0, 0, u32(0))
+	trun := mkFullBox("trun", 0, 0x000200, u32(4), u32(100), u32(100), u32(100), u32(100))
+	moof := mkBox("moof", mkFullBox("mfhd", 0, 0, u32(1)), mkBox("traf", tfhd, tfdt, trun))
+	file := append(append(append(ftyp, moov...), moof...), mkBox("mdat", make([]byte, 400))...)
+
+	p := newTestParser(t, file)
+	tracks := p.GetTracks()
+	if len(tracks) != 1 || tracks[0].Timeline == nil {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+	want := []Edit{
+		{PresentationTime: 0, Duration: 500, MediaTime: -1, MediaRate: 1},
+		{PresentationTime: 500, Duration: 0, MediaTime: 50, MediaRate: 1},
+	}
+	if !reflect.DeepEqual(tracks[0].Timeline.Edits, want) {
+		t.Errorf("Edits = %+v, want %+v", tracks[0].Timeline.Edits, want)
+	}
+	if pt, ok := tracks[0].Timeline.MediaToPresentation(80); !ok || pt != 530 {
+		t.Errorf("MediaToPresentation(80) = %d, %v, want 530, true", pt, ok)
+	}
+	numbers, err := p.SamplesOutsidePresentation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(numbers, []uint64{1}) {
+		t.Errorf("SamplesOutsidePresentation() = %v, want [1]", numbers)
+	}
+	if _, err := p.SamplesOutsidePresentation(2); err != ErrNotFoundTrack {
+		t.Errorf("got error %v, want ErrNotFoundTrack", err)
+	}
+}
+
+func TestParser_PacketsBeforePresentation(t *testing.T) {
+	// 4 AAC frames, the presentation starts at 1500 of the media, in the middle of the second frame
+	tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(1), u32(0), u32(2596), make([]byte, 52), u32(0), u32(0))
+	elst := mkFullBox("elst", 0, 0, u32(1), u32(2596), u32(1500), u16(1), u16(0))
+	mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(48000), u32(4096), u16(0x15C7), u16(0))
+	hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("soun"), make([]byte, 12), []byte("audio\x00"))
+	stbl := mkBox("stbl", mkFullBox("stsd", 0, 0, u32(1), mkAudioEntry("mp4a", 2, 48000)),
+		mkFullBox("stts", 0, 0, u32(1), u32(4), u32(1024)),
+		mkFullBox("stsc", 0, 0, u32(1), u32(1), u32(4), u32(1)),
+		mkFullBox("stsz", 0, 0, u32(10), u32(4)),
+		mkFullBox("stco", 0, 0, u32(1), u32(0)))
+	trak := mkBox("trak", tkhd, mkBox("edts", elst), mkBox("mdia", mdhd, hdlr, mkBox("minf", stbl)))
+	mvhd := mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(48000), u32(2596), make([]byte, 80))
+	ftyp := mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6"))
+	p := newTestParser(t, append(ftyp, mkBox("moov", mvhd, trak)...))
+
+	packets, err := p.Packets(1, PrimingKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pts []int64
+	for _, packet := range packets {
+		pts = append(pts, packet.PTS)
+	}
+	if want := []int64{-1500, -476, 548, 1572}; !reflect.DeepEqual(pts, want) {
+		t.Errorf("PTS = %v, want %v", pts, want)
+	}
+}
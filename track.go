@@ -1,90 +1,131 @@
-package main
+package fmp4parser
 
-func (track *boxTrak) constructPacketList() {
-	movie := track.movie
-	if track.edts != nil && track.edts.entryCount > 0 {
-		elst := track.edts
-		emptyDuration := uint64(0)
-		editStartIndex := uint32(0)
-		startTime := int64(0)
-		for i := uint32(0); i < elst.entryCount; i++ {
-			if i == 0 && elst.mediaTime[0] == -1 {
-				// the first edit list is empty
-				emptyDuration = elst.editDuration[0]
-				editStartIndex = 1
-			} else if i == editStartIndex && elst.mediaTime[i] >= 0 {
-				startTime = elst.mediaTime[i]
-			} else {
-				// do nothing
-			}
-		}
-		if movie.timeScale > 0 && (emptyDuration != 0 || startTime != 0) {
-			if emptyDuration != 0 {
-				emptyDuration = emptyDuration * uint64(track.timeScale) / uint64(movie.timeScale)
-			}
-			track.timeOffset = startTime - int64(emptyDuration)
-		}
+// newTrack converts the parsed "trak" into Track.
+func (track *boxTrak) newTrack() Track {
+	t := Track{
+		Type:       track.trackType,
+		TrackID:    track.id,
+		Duration:   track.duration,
+		TimeScale:  track.timeScale,
+		Timeline:   track.timeline(),
+		codingName: track.format,
+		audioEntry: track.audioEntry,
+		videoEntry: track.videoEntry,
+	}
+	if track.stsz != nil {
+		t.SampleSize = track.stsz.sampleSize
 	}
+	if track.audioEntry != nil {
+		t.Codec = track.audioEntry.codec
+		t.Format = int2String(track.audioEntry.format)
+		t.ChannelCount = track.audioEntry.channelCount
+		t.SampleRate = track.audioEntry.sampleRate
+		t.ExtraRawData = track.audioEntry.descriptorsRawData
+		t.ChannelLayout = track.audioEntry.channelLayout
+	}
+	if track.videoEntry != nil {
+		t.Codec = track.videoEntry.codec
+		t.Format = int2String(track.videoEntry.format)
+		t.Width = track.videoEntry.width
+		t.Height = track.videoEntry.height
+		t.SequenceParameterSet = track.videoEntry.sequenceParameterSet()
+		t.HDRInfo = track.videoEntry.hdrInfo()
+		t.Spatial = track.spatialOf(track.videoEntry)
+		t.ExtraRawData = track.videoEntry.configurationRecordsRawData
+	}
+	t.Timecode = track.timecodeEntry
+	t.References = track.tref
+	t.Groups = track.trgr
+	t.DataReferences = track.dref
+	t.QuickTime = track.quickTime
+	if len(track.protection) > 0 {
+		t.EncryptedInformation = track.protection[0]
+	}
+	for i, entry := range track.sampleEntries {
+		t.SampleEntries = append(t.SampleEntries, newSampleEntry(i+1, entry))
+	}
+	return t
+}
 
+// constructPacketList builds the packets of the samples in "stbl" without reading the data.
+func (track *boxTrak) constructPacketList() {
 	track.packets = make([]Packet, track.sampleNumber)
+	if track.stts == nil {
+		return
+	}
 
-	// set DTS and PTS
+	// set DTS and PTS. PTS is the presentation time mapped by the edit list, the samples
+	// which aren't presented are extrapolated onto the presentation timeline.
+	timeline := track.timeline()
 	accuSample := 0
 	accuDur := uint64(0)
 	for i := 0; i < int(track.stts.entryCount); i++ {
-		for j := 0; j < int(track.stts.sampleCount[i]); j++ {
+		for j := 0; j < int(track.stts.sampleCount[i]) && accuSample < len(track.packets); j++ {
 			track.packets[accuSample].DTS = accuDur
+			track.packets[accuSample].PTS = int64(accuDur)
 			track.packets[accuSample].Duration = track.stts.sampleDelta[i]
 			accuDur += uint64(track.stts.sampleDelta[i])
 			accuSample++
 		}
 	}
-	accuSample = 0
-	for i := 0; i < int(track.ctts.entryCount); i++ {
-		for j := 0; j < int(track.ctts.sampleCount[i]); j++ {
-			track.packets[accuSample].PTS = uint64(int32(track.packets[accuSample].DTS) + int32(track.timeOffset) + track.ctts.sampleOffset[i])
-			accuSample++
+	if track.ctts != nil {
+		accuSample = 0
+		for i := 0; i < int(track.ctts.entryCount); i++ {
+			for j := 0; j < int(track.ctts.sampleCount[i]) && accuSample < len(track.packets); j++ {
+				track.packets[accuSample].PTS += int64(track.ctts.sampleOffset[i])
+				accuSample++
+			}
 		}
 	}
+	for i := range track.packets {
+		track.packets[i].PTS = timeline.presentationTimeOf(track.packets[i].PTS)
+	}
+
+	// set sample flags, the sync samples of AV1 without "stss" are detected by ReadPacket
+	var av1c *Av1cConfig
+	if track.stss == nil {
+		av1c = track.av1Config()
+	}
+	for i := range track.packets {
+		track.packets[i].SampleFlags = track.sampleFlagsOf(i)
+		track.packets[i].av1c = av1c
+	}
+
+	// set sub-samples
+	setSubSamples(track.packets, track.subs, track.codec())
 
 	// set sample size
-	for i := uint32(0); i < track.stsz.sampleCount; i++ {
-		if track.stsz.sampleSize == 0 {
-			track.packets[i].Size = track.stsz.entrySize[i]
-		} else {
-			track.packets[i].Size = track.stsz.sampleSize
+	if track.stsz != nil {
+		for i := uint32(0); i < track.stsz.sampleCount && int(i) < len(track.packets); i++ {
+			if track.stsz.sampleSize == 0 {
+				track.packets[i].Size = track.stsz.entrySize[i]
+			} else {
+				track.packets[i].Size = track.stsz.sampleSize
+			}
 		}
 	}
 
-	// set sample offset
+	// set sample offset and description index. The entry of "stsc" covers the chunks up to
+	// the first chunk of the next entry, the last one covers the rest chunks.
+	if track.stsc == nil || track.stco == nil {
+		return
+	}
 	chunkOffset := track.stco.chunkOffset
 	accuSampleCount := 0
-	lastChunkCount := 0
-	lastFirstChunk := 0
-	lastSamplePerCount := 0
-	lastSampleDescriptionIndex := 0
-
-	// get samples' offset and description index
-	for i := 0; i <= int(track.stsc.entryCount); i++ {
-		if lastChunkCount != 0 {
-			totalSampleCount := lastChunkCount * lastSamplePerCount
-			lastOffset := chunkOffset[lastFirstChunk]
-			for j := 0; j < totalSampleCount; j++ {
-				nextPacket := track.packets[accuSampleCount]
-				nextPacket.offset = lastOffset
-				nextPacket.DescriptorIndex = lastSampleDescriptionIndex
-				lastOffset += uint64(nextPacket.Size) // move offset
-				accuSampleCount++                     // increase 1
-			}
-		} else {
-			if i == int(track.stsc.entryCount) {
-				lastChunkCount = len(chunkOffset) - lastFirstChunk
-			} else {
-				lastChunkCount = int(track.stsc.firstChunk[i]-1) - lastFirstChunk
+	for i := 0; i < int(track.stsc.entryCount); i++ {
+		lastChunk := len(chunkOffset)
+		if i+1 < int(track.stsc.entryCount) && int(track.stsc.firstChunk[i+1]-1) < lastChunk {
+			lastChunk = int(track.stsc.firstChunk[i+1] - 1)
+		}
+		for chunk := int(track.stsc.firstChunk[i] - 1); chunk < lastChunk; chunk++ {
+			offset := chunkOffset[chunk]
+			for j := 0; j < int(track.stsc.samplePerChunk[i]) && accuSampleCount < len(track.packets); j++ {
+				nextPacket := &track.packets[accuSampleCount]
+				nextPacket.offset = offset
+				nextPacket.DescriptorIndex = int(track.stsc.sampleDescriptionIndex[i])
+				offset += uint64(nextPacket.Size) // move offset
+				accuSampleCount++
 			}
-			lastFirstChunk = int(track.stsc.firstChunk[i] - 1)
-			lastSamplePerCount = int(track.stsc.samplePerChunk[i])
-			lastSampleDescriptionIndex = int(track.stsc.sampleDescriptionIndex[i])
 		}
 	}
 }
@@ -0,0 +1,112 @@
+package fmp4parser
+
+// TrackReferenceType is the reference_type of a TrackReferenceTypeBox, the four character code.
+type TrackReferenceType string
+
+// the reference types of "tref", refer to ISO/IEC 14496-12 8.3.3, ISO/IEC 14496-15 and
+// QuickTime File Format. The comments describe the track which has the reference.
+const (
+	TrackReferenceChapter    TrackReferenceType = "chap" // the referenced tracks are the chapter lists of the track, QuickTime
+	TrackReferenceHint       TrackReferenceType = "hint" // the track is a hint track of the referenced media tracks
+	TrackReferenceTimecode   TrackReferenceType = "tmcd" // the referenced track is the timecode track of the track
+	TrackReferenceDepth      TrackReferenceType = "vdep" // the track is the auxiliary depth video of the referenced video track, or the Dolby Vision enhancement layer of the referenced base layer track
+	TrackReferenceParallax   TrackReferenceType = "vplx" // the track is the auxiliary parallax video of the referenced video track
+	TrackReferenceBase       TrackReferenceType = "sbas" // the track is an L-HEVC or SVC enhancement layer of the referenced base layer track
+	TrackReferenceScalable   TrackReferenceType = "scal" // the track extracts the NAL units of the referenced layer tracks
+	TrackReferenceSubtitle   TrackReferenceType = "subt" // the track is the subtitle or timed text of the referenced track
+	TrackReferenceForced     TrackReferenceType = "forc" // the referenced track contains the forced subtitles of the track only
+	TrackReferenceDescribes  TrackReferenceType = "cdsc" // the track is the timed metadata which describes the referenced track
+	TrackReferenceFont       TrackReferenceType = "font" // the track uses the fonts of the referenced track
+	TrackReferenceSubpicture TrackReferenceType = "subp" // the referenced tracks are the VVC subpicture tracks of the VVC base track
+	TrackReferenceVvcNonVCL  TrackReferenceType = "vvcN" // the referenced track is the VVC non-VCL track
+)
+
+// the track group types of "trgr", refer to ISO/IEC 14496-12 8.3.4
+const (
+	TrackGroupMultiSource = "msrc" // the tracks are captured from the same source, e.g. the audio and video of a speaker
+	TrackGroupStereo      = "ster" // the tracks are the left and the right views of a stereo pair
+	TrackGroupAlternate   = "alte" // the tracks are alternatives of each other
+)
+
+// TrackReference is a TrackReferenceTypeBox of "tref", the track refers to the tracks of
+// TrackIDs by the reference type, e.g. "subp" or "vvcN".
+type TrackReference struct {
+	Type     TrackReferenceType // reference_type
+	TrackIDs []uint32           // track_IDs, 0 is allowed and refers to no track
+}
+
+// TrackGroup is a TrackGroupTypeBox of "trgr". The tracks which have the same Type and ID
+// belong to the same group.
+type TrackGroup struct {
+	Type     string // track_group_type, the four character code
+	ID       uint32 // track_group_id
+	LeftView bool   // left_view_flag of "ster", the track is the left view
+}
+
+// TrackRelationKind is the way in which two tracks are related.
+type TrackRelationKind int
+
+const (
+	RelationReference  TrackRelationKind = iota // the track refers to the other track by "tref"
+	RelationReferredBy                          // the other track refers to the track by "tref"
+	RelationGroup                               // the tracks belong to the same group of "trgr"
+)
+
+// TrackRelation is a relation between a track and another track of the movie.
+type TrackRelation struct {
+	Kind    TrackRelationKind
+	Type    string // the reference type of "tref" or the track group type of "trgr"
+	TrackID uint32 // the other track
+}
+
+// ReferencedTracks returns the IDs of the tracks the track refers to by the reference type.
+func (p *Track) ReferencedTracks(referenceType TrackReferenceType) []uint32 {
+	var ids []uint32
+	for _, ref := range p.References {
+		if ref.Type == referenceType {
+			ids = append(ids, ref.TrackIDs...)
+		}
+	}
+	return ids
+}
+
+// TrackRelations returns all the relations of the track to the other tracks of the movie:
+// the references of the track first, then the references to the track and the track groups
+// in the order of the other tracks. It must be called after Parse.
+func (p *Parser) TrackRelations(trackID uint32) []TrackRelation {
+	if p.m.movie == nil {
+		return nil
+	}
+	trak := p.m.movie.trakOf(trackID)
+	if trak == nil {
+		return nil
+	}
+	var relations []TrackRelation
+	for _, ref := range trak.tref {
+		for _, id := range ref.TrackIDs {
+			if id != 0 {
+				relations = append(relations, TrackRelation{Kind: RelationReference, Type: string(ref.Type), TrackID: id})
+			}
+		}
+	}
+	for _, other := range p.m.movie.trak {
+		if other == trak {
+			continue
+		}
+		for _, ref := range other.tref {
+			for _, id := range ref.TrackIDs {
+				if id == trackID {
+					relations = append(relations, TrackRelation{Kind: RelationReferredBy, Type: string(ref.Type), TrackID: other.id})
+				}
+			}
+		}
+		for _, group := range trak.trgr {
+			for _, otherGroup := range other.trgr {
+				if otherGroup.Type == group.Type && otherGroup.ID == group.ID {
+					relations = append(relations, TrackRelation{Kind: RelationGroup, Type: group.Type, TrackID: other.id})
+				}
+			}
+		}
+	}
+	return relations
+}
@@ -0,0 +1,41 @@
+package fmp4parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParser_TrackRelations(t *testing.T) {
+	tref := mkBox("tref", mkBox("tmcd", u32(3)), mkBox("chap", u32(4)))
+	trgr := mkBox("trgr", mkFullBox("msrc", 0, 0, u32(7)), mkFullBox("ster", 0, 0, u32(9), u32(1)))
+	video := mkQuickTimeTrak(1, "vide", [][]byte{tref, trgr})
+	// the Dolby Vision enhancement layer is the right view of the stereo pair
+	enhancement := mkQuickTimeTrak(2, "vide", [][]byte{mkBox("tref", mkBox("sbas", u32(1))),
+		mkBox("trgr", mkFullBox("msrc", 0, 0, u32(8)), mkFullBox("ster", 0, 0, u32(9), u32(0)))})
+	chapters := mkQuickTimeTrak(4, "text", [][]byte{mkBox("tref", mkBox("font", u32(0)))})
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, u32(0), u32(0), u32(1000), u32(1000), make([]byte, 80)),
+		video, enhancement, mkQuickTimeTrak(3, "tmcd", nil), chapters)
+	p := newTestParser(t, moov)
+
+	wantGroups := []TrackGroup{{TrackGroupMultiSource, 7, false}, {TrackGroupStereo, 9, true}}
+	if groups := p.GetTracks()[0].Groups; !reflect.DeepEqual(groups, wantGroups) {
+		t.Errorf("Groups = %+v, want %+v", groups, wantGroups)
+	}
+	want := []TrackRelation{
+		{RelationReference, string(TrackReferenceTimecode), 3},
+		{RelationReference, string(TrackReferenceChapter), 4},
+		{RelationReferredBy, string(TrackReferenceBase), 2},
+		{RelationGroup, TrackGroupStereo, 2},
+	}
+	if got := p.TrackRelations(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("TrackRelations(1) = %+v, want %+v", got, want)
+	}
+	// the reference of the track ID 0 refers to no track
+	want = []TrackRelation{{RelationReferredBy, string(TrackReferenceChapter), 1}}
+	if got := p.TrackRelations(4); !reflect.DeepEqual(got, want) {
+		t.Errorf("TrackRelations(4) = %+v, want %+v", got, want)
+	}
+	if got := p.TrackRelations(5); got != nil {
+		t.Errorf("TrackRelations() of unknown track = %+v, want nil", got)
+	}
+}
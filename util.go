@@ -1,4 +1,4 @@
-package main
+package fmp4parser
 
 import (
 	"io"
@@ -19,6 +19,12 @@ func newLog(out io.Writer) {
 	logD = log.New(out, "[D] [fmp4parser] ", log.Ldate|log.Ltime|log.Lmicroseconds|log.LstdFlags|log.Lshortfile)
 }
 
+// SetLogOutput sets the destination of the logs of the parser, e.g. io.Discard to drop them.
+// NewFmp4Parser resets it to os.Stdout.
+func SetLogOutput(out io.Writer) {
+	newLog(out)
+}
+
 func min(a uint64, b uint64) uint64 {
 	if a < b {
 		return a
@@ -0,0 +1,209 @@
+package fmp4parser
+
+import (
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// nal_unit_type of VVC, refer to ITU-T H.266 Table 5
+const (
+	vvcNalIDRWRADL  = 7
+	vvcNalIDRNLP    = 8
+	vvcNalCRA       = 9
+	vvcNalGDR       = 10
+	vvcNalOPI       = 12
+	vvcNalDCI       = 13
+	vvcNalVPS       = 14
+	vvcNalSPS       = 15
+	vvcNalPPS       = 16
+	vvcNalPrefixAPS = 17
+	vvcNalAUD       = 20
+)
+
+/*
+parseConfig parses vvcC, refer to ISO/IEC 14496-15:2022 11.2.4.2
+
+	aligned(8) class VvcDecoderConfigurationRecord {
+	   bit(5) reserved = '11111'b;
+	   unsigned int(2) LengthSizeMinusOne;
+	   unsigned int(1) ptl_present_flag;
+	   if (ptl_present_flag) {
+	      unsigned int(9) ols_idx;
+	      unsigned int(3) num_sublayers;
+	      unsigned int(2) constant_frame_rate;
+	      unsigned int(2) chroma_format_idc;
+	      unsigned int(3) bit_depth_minus8;
+	      bit(5) reserved = '11111'b;
+	      VvcPTLRecord(num_sublayers) native_ptl;
+	      unsigned_int(16) max_picture_width;
+	      unsigned_int(16) max_picture_height;
+	      unsigned int(16) avg_frame_rate;
+	   }
+	   unsigned int(8) num_of_arrays;
+	   for (j=0; j < num_of_arrays; j++) {
+	      unsigned int(1) array_completeness;
+	      bit(2) reserved = 0;
+	      unsigned int(5) NAL_unit_type;
+	      if (NAL_unit_type != DCI_NUT && NAL_unit_type != OPI_NUT)
+	         unsigned int(16) num_nalus;
+	      for (i=0; i< num_nalus; i++) {
+	         unsigned int(16) nal_unit_length;
+	         bit(8*nal_unit_length) nal_unit;
+	      }
+	   }
+	}
+
+VvcConfigurationBox is a FullBox of version 0.
+*/
+func (p *VvcConfig) parseConfig(r *atomReader) error {
+	if r.Size() < 5 {
+		return fmt.Errorf("%w : VvcConfig", ErrInvalidAtomSize)
+	}
+	p.DecoderSpecificInfo = make([]byte, r.Size())
+	if err := r.Peek(p.DecoderSpecificInfo); err != nil {
+		return fmt.Errorf("%w : VvcConfig.DecoderSpecificInfo", err)
+	}
+	if version, _ := r.ReadVersionFlags(); version != 0 {
+		return fmt.Errorf("%w : unknown vvcC version %d", ErrInvalidAtom, version)
+	}
+	buf := make([]byte, r.Len())
+	_, _ = r.ReadBytes(buf)
+	br := newBitReaderFromSlice(buf)
+	_ = br.ReadBitsLE8(5)
+	p.LengthSizeMinusOne = br.ReadBitsLE8(2)
+	p.PtlPresentFlag = br.ReadBitsLE8(1)
+	if p.PtlPresentFlag == 1 {
+		p.OlsIdx = br.ReadBitsLE16(9)
+		p.NumSublayers = br.ReadBitsLE8(3)
+		p.ConstantFrameRate = br.ReadBitsLE8(2)
+		p.ChromaFormatIdc = br.ReadBitsLE8(2)
+		p.BitDepthMinus8 = br.ReadBitsLE8(3)
+		_ = br.ReadBitsLE8(5)
+		p.NativePTL.parse(&br, p.NumSublayers)
+		p.MaxPictureWidth = br.ReadBitsLE16(16)
+		p.MaxPictureHeight = br.ReadBitsLE16(16)
+		p.AvgFrameRate = br.ReadBitsLE16(16)
+	}
+	p.NumOfArrays = br.ReadBitsLE8(8)
+	for i := uint8(0); i < p.NumOfArrays && br.Err() == nil; i++ {
+		array := NalUnitInfo{ArrayCompleteness: br.ReadBitsLE8(1)}
+		_ = br.ReadBitsLE8(2)
+		array.NALUnitType = br.ReadBitsLE8(5)
+		array.NumNalus = 1
+		if array.NALUnitType != vvcNalDCI && array.NALUnitType != vvcNalOPI {
+			array.NumNalus = br.ReadBitsLE16(16)
+		}
+		for j := uint16(0); j < array.NumNalus && br.Err() == nil; j++ {
+			length := br.ReadBitsLE16(16)
+			nal := make([]byte, length)
+			for k := range nal {
+				nal[k] = br.ReadBitsLE8(8)
+			}
+			array.NalUnitLength = append(array.NalUnitLength, length)
+			array.NalUnit = append(array.NalUnit, nal)
+		}
+		p.NalUnitArrays = append(p.NalUnitArrays, array)
+	}
+	if br.Err() != nil {
+		return fmt.Errorf("%w : VvcConfig: %v", ErrIncompleteBox, br.Err())
+	}
+	return nil
+}
+
+/*
+parse parses VvcPTLRecord
+
+	aligned(8) class VvcPTLRecord(num_sublayers) {
+	   bit(2) reserved = 0;
+	   unsigned int(6) num_bytes_constraint_info;
+	   unsigned int(7) general_profile_idc;
+	   unsigned int(1) general_tier_flag;
+	   unsigned int(8) general_level_idc;
+	   unsigned int(1) ptl_frame_only_constraint_flag;
+	   unsigned int(1) ptl_multi_layer_enabled_flag;
+	   unsigned int(8*num_bytes_constraint_info - 2) general_constraint_info;
+	   for (i=num_sublayers - 2; i >= 0; i--)
+	      unsigned int(1) ptl_sublayer_level_present_flag[i];
+	   for (j=num_sublayers; j<=8 && num_sublayers > 1; j++)
+	      bit(1) ptl_reserved_zero_bit = 0;
+	   for (i=num_sublayers-2; i >= 0; i--)
+	      if (ptl_sublayer_level_present_flag[i])
+	         unsigned int(8) sublayer_level_idc[i];
+	   unsigned int(8) ptl_num_sub_profiles;
+	   for (j=0; j < ptl_num_sub_profiles; j++)
+	      unsigned int(32) general_sub_profile_idc[j];
+	}
+*/
+func (p *VvcPTLRecord) parse(br *bitReader, numSublayers uint8) {
+	_ = br.ReadBitsLE8(2)
+	p.NumBytesConstraintInfo = br.ReadBitsLE8(6)
+	p.GeneralProfileIdc = br.ReadBitsLE8(7)
+	p.GeneralTierFlag = br.ReadBitsLE8(1)
+	p.GeneralLevelIdc = br.ReadBitsLE8(8)
+	p.GeneralConstraintInfo = make([]byte, p.NumBytesConstraintInfo)
+	for i := range p.GeneralConstraintInfo {
+		p.GeneralConstraintInfo[i] = br.ReadBitsLE8(8)
+	}
+	if numSublayers > 1 {
+		present := make([]bool, numSublayers-1)
+		for i := int(numSublayers) - 2; i >= 0; i-- {
+			present[i] = br.ReadBool()
+		}
+		for j := numSublayers; j <= 8; j++ {
+			_ = br.ReadBool()
+		}
+		p.SublayerLevelIdc = make([]uint8, numSublayers-1)
+		for i := int(numSublayers) - 2; i >= 0; i-- {
+			if present[i] {
+				p.SublayerLevelIdc[i] = br.ReadBitsLE8(8)
+			}
+		}
+	}
+	numSubProfiles := br.ReadBitsLE8(8)
+	for j := uint8(0); j < numSubProfiles; j++ {
+		p.SubProfileIdc = append(p.SubProfileIdc, br.ReadBitsLE32(32))
+	}
+}
+
+// codecString returns the codecs parameter of VVC, refer to ISO/IEC 14496-15 Annex E.6:
+// the profile, the tier and level, "C" and the constraint information in base32 without
+// the trailing zero bytes, "S" and the sub-profiles, "O" and the output layer set index.
+// Only the profile, the tier and level are present if there is no PTL in vvcC.
+func (p *VvcConfig) codecString(name string) string {
+	if p.PtlPresentFlag == 0 {
+		return name
+	}
+	ptl := &p.NativePTL
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s.%d.", name, ptl.GeneralProfileIdc)
+	if ptl.GeneralTierFlag == 1 {
+		b.WriteByte('H')
+	} else {
+		b.WriteByte('L')
+	}
+	fmt.Fprintf(&b, "%d", ptl.GeneralLevelIdc)
+	constraints := ptl.GeneralConstraintInfo
+	for len(constraints) > 0 && constraints[len(constraints)-1] == 0 {
+		constraints = constraints[:len(constraints)-1]
+	}
+	if len(constraints) > 0 {
+		b.WriteString(".C")
+		b.WriteString(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(constraints))
+	}
+	for i, subProfile := range ptl.SubProfileIdc {
+		if i == 0 {
+			b.WriteString(".S")
+		} else {
+			b.WriteByte('+')
+		}
+		fmt.Fprintf(&b, "%08X", subProfile)
+	}
+	if p.OlsIdx != 0 {
+		fmt.Fprintf(&b, ".O%d", p.OlsIdx)
+		if p.NumSublayers > 1 {
+			fmt.Fprintf(&b, "+%d", p.NumSublayers-1)
+		}
+	}
+	return b.String()
+}
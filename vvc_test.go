@@ -0,0 +1,124 @@
+package fmp4parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// mkVvcC builds the VvcDecoderConfigurationRecord of 3 sub-layers with the SPS and the PPS.
+func mkVvcC(olsIdx uint64, sps, pps []byte) []byte {
+	w := &bitWriter{}
+	w.write(0x1F, 5).write(3, 2).write(1, 1)               // LengthSizeMinusOne 3, ptl_present_flag
+	w.write(olsIdx, 9).write(3, 3).write(0, 2).write(1, 2) // num_sublayers 3, 4:2:0
+	w.write(2, 3).write(0x1F, 5)                           // 10 bits
+	// VvcPTLRecord: 2 bytes of constraint info, Main 10, Main tier, level 5.1
+	w.write(0, 2).write(2, 6).write(1, 7).write(0, 1).write(83, 8).write(0x8000, 16)
+	w.write(1, 1).write(0, 1).write(0, 6) // sub-layer 1 has the level, sub-layer 0 doesn't
+	w.write(80, 8)
+	w.write(1, 8).write(0x12345678, 32) // a sub-profile
+	w.write(1920, 16).write(1080, 16).write(0, 16)
+	w.write(2, 8)
+	for _, nal := range [][]byte{sps, pps} {
+		w.write(1, 1).write(0, 2).write(uint64(nal[1]>>3), 5).write(1, 16).write(uint64(len(nal)), 16)
+		for _, b := range nal {
+			w.write(uint64(b), 8)
+		}
+	}
+	return mkFullBox("vvcC", 0, 0, w.b)
+}
+
+func TestVvcConfig(t *testing.T) {
+	sps, pps := []byte{0x00, 0x79, 0x01, 0x02}, []byte{0x00, 0x81, 0x03}
+	p := newTestParser(t, mkVideoInit(mkVideoEntry("vvc1", 1920, 1080, mkVvcC(1, sps, pps))))
+	track := p.GetTracks()[0]
+	if track.Codec != VideoCodecVVC {
+		t.Fatalf("Codec = %v, want VideoCodecVVC", track.Codec)
+	}
+	vvc, ok := track.videoEntry.decoderConfigurationRecords[VideoCodecVVC].(*VvcConfig)
+	if !ok {
+		t.Fatal("no VvcConfig")
+	}
+	if vvc.LengthSizeMinusOne != 3 || vvc.NumSublayers != 3 || vvc.ChromaFormatIdc != 1 || vvc.BitDepthMinus8 != 2 ||
+		vvc.MaxPictureWidth != 1920 || vvc.MaxPictureHeight != 1080 {
+		t.Errorf("VvcConfig = %+v", vvc)
+	}
+	ptl := vvc.NativePTL
+	if ptl.GeneralProfileIdc != 1 || ptl.GeneralLevelIdc != 83 || !ptl.PtlFrameOnlyConstraintFlag() || ptl.PtlMultiLayerEnabledFlag() ||
+		!reflect.DeepEqual(ptl.SublayerLevelIdc, []uint8{0, 80}) || !reflect.DeepEqual(ptl.SubProfileIdc, []uint32{0x12345678}) {
+		t.Errorf("VvcPTLRecord = %+v", ptl)
+	}
+	if len(vvc.NalUnitArrays) != 2 || !bytes.Equal(vvc.NalUnitArrays[0].NalUnit[0], sps) || !bytes.Equal(vvc.NalUnitArrays[1].NalUnit[0], pps) {
+		t.Errorf("NalUnitArrays = %+v", vvc.NalUnitArrays)
+	}
+	if got, want := track.CodecString(), "vvc1.1.L83.CQA.S12345678.O1+2"; got != want {
+		t.Errorf("CodecString() = %v, want %v", got, want)
+	}
+
+	c, err := track.SampleEntries[0].NalConverter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aud, idr := []byte{0x00, 0xA1, 0x10}, []byte{0x00, 0x41, 0x84}
+	got, err := c.ToAnnexB(append(append(u32(3), aud...), append(u32(3), idr...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Join([][]byte{nil, aud, sps, pps, idr}, startCode); !bytes.Equal(got, want) {
+		t.Errorf("ToAnnexB() = % X, want % X", got, want)
+	}
+}
+
+func TestVvcConfig_CodecString(t *testing.T) {
+	tests := []struct {
+		name   string
+		config VvcConfig
+		want   string
+	}{
+		{"no constraints", VvcConfig{PtlPresentFlag: 1, NativePTL: VvcPTLRecord{GeneralProfileIdc: 1, GeneralLevelIdc: 51,
+			GeneralConstraintInfo: []byte{0, 0}}}, "vvc1.1.L51"},
+		{"high tier", VvcConfig{PtlPresentFlag: 1, NumSublayers: 1, OlsIdx: 2, NativePTL: VvcPTLRecord{GeneralProfileIdc: 17,
+			GeneralTierFlag: 1, GeneralLevelIdc: 102, GeneralConstraintInfo: []byte{0xFF, 0x01, 0}}}, "vvc1.17.H102.C74AQ.O2"},
+		{"no PTL", VvcConfig{}, "vvc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.codecString("vvc1"); got != tt.want {
+				t.Errorf("codecString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrack_References(t *testing.T) {
+	trak := func(id uint32, boxes ...[]byte) []byte {
+		tkhd := mkFullBox("tkhd", 0, 1, u32(0), u32(0), u32(id), u32(0), u32(4000), make([]byte, 52), u32(0), u32(0))
+		mdhd := mkFullBox("mdhd", 0, 0, u32(0), u32(0), u32(1000), u32(4000), u16(0x15C7), u16(0))
+		hdlr := mkFullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("video\x00"))
+		stsd := mkFullBox("stsd", 0, 0, u32(1), mkVideoEntry("vvc1", 1920, 1080, mkVvcC(0, []byte{0, 0x79}, []byte{0, 0x81})))
+		minf := mkBox("minf", mkBox("stbl", stsd))
+		return mkBox("trak", append([][]byte{tkhd, mkBox("mdia", mdhd, hdlr, minf)}, boxes...)...)
+	}
+	tref := mkBox("tref", mkBox("subp", u32(2), u32(3)), mkBox("vvcN", u32(4)))
+	moov := mkBox("moov", mkFullBox("mvhd", 0, 0, make([]byte, 96)), trak(1, tref), trak(2), trak(3), trak(4))
+	p := newTestParser(t, append(mkBox("ftyp", []byte("iso6"), u32(0), []byte("iso6")), moov...))
+
+	var base Track
+	for _, track := range p.GetTracks() {
+		if track.TrackID == 1 {
+			base = track
+		} else if len(track.References) != 0 {
+			t.Errorf("track %d References = %v, want none", track.TrackID, track.References)
+		}
+	}
+	want := []TrackReference{{TrackReferenceSubpicture, []uint32{2, 3}}, {TrackReferenceVvcNonVCL, []uint32{4}}}
+	if !reflect.DeepEqual(base.References, want) {
+		t.Errorf("References = %v, want %v", base.References, want)
+	}
+	if got := base.ReferencedTracks(TrackReferenceSubpicture); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("ReferencedTracks(subp) = %v", got)
+	}
+	if got := base.ReferencedTracks("hint"); got != nil {
+		t.Errorf("ReferencedTracks(hint) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,130 @@
+package fmp4parser
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// wFormatTag of WAVE
+const (
+	wavFormatPCM       = 1
+	wavFormatIEEEFloat = 3
+	wavFormatALaw      = 6
+	wavFormatMULaw     = 7
+)
+
+// wavSampleFormat is how a PCM format is stored in WAVE. The samples of WAVE are little
+// endian, and they are unsigned if they are 8 bits, otherwise signed.
+type wavSampleFormat struct {
+	formatTag uint16
+	bits      uint16
+	bigEndian bool // the bytes of a sample are swapped
+	flipSign  bool // the most significant bit of a sample is flipped
+}
+
+// the PCM formats of lpcmCodecId which WAVE supports
+var wavSampleFormats = map[lpcmCodecId]wavSampleFormat{
+	pcmU8:    {wavFormatPCM, 8, false, false},
+	pcmS8:    {wavFormatPCM, 8, false, true},
+	pcmS16LE: {wavFormatPCM, 16, false, false},
+	pcmS16BE: {wavFormatPCM, 16, true, false},
+	pcmU16LE: {wavFormatPCM, 16, false, true},
+	pcmU16BE: {wavFormatPCM, 16, true, true},
+	pcmS24LE: {wavFormatPCM, 24, false, false},
+	pcmS24BE: {wavFormatPCM, 24, true, false},
+	pcmU24LE: {wavFormatPCM, 24, false, true},
+	pcmU24BE: {wavFormatPCM, 24, true, true},
+	pcmS32LE: {wavFormatPCM, 32, false, false},
+	pcmS32BE: {wavFormatPCM, 32, true, false},
+	pcmU32LE: {wavFormatPCM, 32, false, true},
+	pcmU32BE: {wavFormatPCM, 32, true, true},
+	pcmS64LE: {wavFormatPCM, 64, false, false},
+	pcmS64BE: {wavFormatPCM, 64, true, false},
+	pcmF32LE: {wavFormatIEEEFloat, 32, false, false},
+	pcmF32BE: {wavFormatIEEEFloat, 32, true, false},
+	pcmF64LE: {wavFormatIEEEFloat, 64, false, false},
+	pcmF64BE: {wavFormatIEEEFloat, 64, true, false},
+	pcmALaw:  {wavFormatALaw, 8, false, false},
+	pcmMULaw: {wavFormatMULaw, 8, false, false},
+}
+
+// wavWriter writes the PCM samples in WAVE. The size of the data chunk is the sum of the
+// sizes of the packets, so the header is written before the samples.
+type wavWriter struct {
+	w      io.Writer
+	format wavSampleFormat
+	size   uint64 // bytes written of the data chunk
+}
+
+func newWavWriter(w io.Writer, track *Track, packets []Packet) (esWriter, error) {
+	if track.audioEntry == nil {
+		return nil, ErrUnsupportedExtraction
+	}
+	format, ok := wavSampleFormats[track.audioEntry.lpcmCodec]
+	if !ok || track.ChannelCount == 0 {
+		return nil, ErrUnsupportedExtraction
+	}
+	dataSize := uint64(0)
+	for i := range packets {
+		dataSize += uint64(packets[i].Size)
+	}
+	fmtSize := uint32(16)
+	if format.formatTag != wavFormatPCM {
+		fmtSize = 18 // with cbSize
+	}
+	riffSize := 4 + 8 + uint64(fmtSize) + 8 + dataSize + dataSize&1
+	if riffSize > 0xFFFFFFFF {
+		return nil, ErrTooLarge
+	}
+	blockAlign := track.ChannelCount * format.bits / 8
+
+	header := make([]byte, 20+fmtSize+8)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(riffSize))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], fmtSize)
+	binary.LittleEndian.PutUint16(header[20:], format.formatTag)
+	binary.LittleEndian.PutUint16(header[22:], track.ChannelCount)
+	binary.LittleEndian.PutUint32(header[24:], track.SampleRate)
+	binary.LittleEndian.PutUint32(header[28:], track.SampleRate*uint32(blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], blockAlign)
+	binary.LittleEndian.PutUint16(header[34:], format.bits) // cbSize is 0 if present
+	copy(header[20+fmtSize:], "data")
+	binary.LittleEndian.PutUint32(header[24+fmtSize:], uint32(dataSize))
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &wavWriter{w: w, format: format}, nil
+}
+
+func (p *wavWriter) writePacket(packet *Packet) error {
+	data := packet.Data
+	if p.format.bigEndian || p.format.flipSign {
+		data = make([]byte, len(packet.Data))
+		copy(data, packet.Data)
+		width := int(p.format.bits / 8)
+		for i := 0; i+width <= len(data); i += width {
+			sample := data[i : i+width]
+			if p.format.bigEndian {
+				for l, r := 0, width-1; l < r; l, r = l+1, r-1 {
+					sample[l], sample[r] = sample[r], sample[l]
+				}
+			}
+			if p.format.flipSign {
+				sample[width-1] ^= 0x80
+			}
+		}
+	}
+	p.size += uint64(len(data))
+	_, err := p.w.Write(data)
+	return err
+}
+
+// close pads the data chunk to an even size.
+func (p *wavWriter) close() error {
+	if p.size&1 == 0 {
+		return nil
+	}
+	_, err := p.w.Write([]byte{0})
+	return err
+}
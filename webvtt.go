@@ -0,0 +1,102 @@
+package fmp4parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// boxes of the "wvtt" samples, refer to ISO/IEC 14496-30
+const (
+	fourCCvttc uint32 = 0x76747463 // "vttc"
+	fourCCiden uint32 = 0x6964656e // "iden"
+	fourCCsttg uint32 = 0x73747467 // "sttg"
+	fourCCpayl uint32 = 0x7061796c // "payl"
+)
+
+// webVTTWriter writes the subtitles of "wvtt" and "tx3g" in WebVTT. A cue is written for
+// each cue of a "wvtt" sample or each non-empty "tx3g" sample, its time is the presentation
+// time of the sample.
+type webVTTWriter struct {
+	w         io.Writer
+	timeScale uint32
+	tx3g      bool
+}
+
+func newWebVTTWriter(w io.Writer, track *Track) (esWriter, error) {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return nil, err
+	}
+	return &webVTTWriter{w: w, timeScale: track.TimeScale, tx3g: track.SampleEntries[0].Format == "tx3g"}, nil
+}
+
+// webVTTTimestamp returns the timestamp of WebVTT, hh:mm:ss.ttt, of the time in the time scale.
+func webVTTTimestamp(t uint64, timeScale uint32) string {
+	ms := rescaleTime(t, 1000, timeScale)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func (p *webVTTWriter) writePacket(packet *Packet) error {
+	// the cues before the presentation are cut at the start of it
+	start, end := packet.PTS, packet.PTS+int64(packet.Duration)
+	if end <= 0 {
+		return nil
+	}
+	if start < 0 {
+		start = 0
+	}
+	timing := webVTTTimestamp(uint64(start), p.timeScale) + " --> " + webVTTTimestamp(uint64(end), p.timeScale)
+	var cues []string
+	if p.tx3g {
+		// TextSample: unsigned int(16) text-length, unsigned int(8) text[text-length], the modifier boxes
+		data := packet.Data
+		if len(data) >= 2 {
+			length := int(data[0])<<8 | int(data[1])
+			if length > 0 && 2+length <= len(data) {
+				cues = append(cues, timing+"\n"+string(data[2:2+length]))
+			}
+		}
+	} else {
+		r := newAtomReader(packet.Data, &atom{atomType: wvttSampleEntry, bodySize: int64(len(packet.Data))})
+		for {
+			ar, err := r.GetSubAtom()
+			if err != nil {
+				break
+			}
+			// "vtte" is an empty sample and "vtta" is a comment
+			if ar.TypeCC() != fourCCvttc {
+				continue
+			}
+			var id, settings, payload string
+			for {
+				box, err := ar.GetSubAtom()
+				if err != nil {
+					break
+				}
+				switch box.TypeCC() {
+				case fourCCiden:
+					id = string(box.b)
+				case fourCCsttg:
+					settings = " " + string(box.b)
+				case fourCCpayl:
+					payload = string(box.b)
+				}
+			}
+			cue := timing + settings + "\n" + strings.TrimRight(payload, "\n")
+			if id != "" {
+				cue = id + "\n" + cue
+			}
+			cues = append(cues, cue)
+		}
+	}
+	for _, cue := range cues {
+		if _, err := io.WriteString(p.w, cue+"\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *webVTTWriter) close() error {
+	return nil
+}